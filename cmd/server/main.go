@@ -2,7 +2,9 @@ package main
 
 import (
 	"log"
+	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -14,6 +16,7 @@ import (
 	"ffmeditor/internal/jobs"
 	"ffmeditor/internal/metrics"
 	"ffmeditor/internal/storage"
+	"ffmeditor/internal/validator"
 )
 
 func main() {
@@ -22,15 +25,40 @@ func main() {
 	// Initialize components
 	metrics.Start()
 
-	// Detect hardware encoder once at startup.
-	if cfg.HWAccel != "none" {
-		hw := ffmpeg.DetectHardwareEncoder(cfg.FFmpegPath)
-		cfg.ResolvedHWEncoder = ffmpeg.HWEncoderCodec(hw)
-		if cfg.ResolvedHWEncoder != "" {
-			log.Printf("Hardware encoder detected: %s (%s)", hw, cfg.ResolvedHWEncoder)
-		} else {
-			log.Printf("No hardware encoder detected, using libx264")
+	// Probe ffmpeg once at startup; requests are validated against what it has.
+	caps, err := ffmpeg.ProbeCapabilities(cfg.FFmpegPath)
+	if err != nil {
+		log.Printf("Could not probe ffmpeg capabilities: %v", err)
+	} else {
+		cfg.Capabilities = caps
+		validator.Restrict(caps.HasEncoder, caps.HasEncoder, caps.CanWrite)
+		log.Printf("FFmpeg %s: %d encoders, %d filters, %d muxers", caps.Version, len(caps.Encoders), len(caps.Filters), len(caps.Muxers))
+	}
+
+	// Verify hardware encoders once at startup with a test encode each.
+	hw := ffmpeg.DetectHWEncoders(cfg.FFmpegPath, caps, cfg.HWAccel)
+	cfg.HWEncoders = hw.Encoders
+	cfg.ResolvedHWEncoder = hw.Encoders["h264"]
+	for enc, reason := range hw.Failed {
+		log.Printf("Hardware encoder %s unusable: %s", enc, reason)
+	}
+	if len(hw.Encoders) > 0 {
+		codecs := make([]string, 0, len(hw.Encoders))
+		for _, enc := range hw.Encoders {
+			codecs = append(codecs, enc)
 		}
+		validator.AllowVideoCodecs(codecs...)
+		log.Printf("Hardware encoders verified: %s", strings.Join(codecs, ", "))
+	} else {
+		log.Printf("No hardware encoder verified, using software encoders")
+	}
+
+	cfg.VMAFAvailable = caps != nil && caps.HasFilter("libvmaf")
+	log.Printf("VMAF available: %v", cfg.VMAFAvailable)
+
+	if _, err := os.Stat(cfg.RNNoiseModel); err != nil {
+		log.Printf("RNNoise model %s not found, RNNoise denoising disabled", cfg.RNNoiseModel)
+		cfg.RNNoiseModel = ""
 	}
 
 	store := storage.NewStorage(cfg.UploadDir)
@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+
+	"ffmeditor/internal/ffmpeg"
 )
 
 // loadDotEnv reads key=value pairs from .env and sets them as env vars
@@ -45,10 +47,20 @@ type Config struct {
 	UploadDir         string
 	OutputDir         string
 	LogRingBufferSize int
-	// HWAccel: "auto" (detect), "none", "cuda", "qsv", "videotoolbox"
+	// HWAccel: "auto" (detect), "none", "cuda"/"nvenc", "qsv", "vaapi", "amf", "videotoolbox"
 	HWAccel string
 	// ResolvedHWEncoder is set at startup after probing ffmpeg (e.g. "h264_nvenc", "h264_qsv", "").
 	ResolvedHWEncoder string
+	// HWEncoders maps "h264", "hevc" and "av1" to the hardware encoder verified
+	// for each at startup; codecs without one are absent.
+	HWEncoders map[string]string
+	// VMAFAvailable is set at startup when ffmpeg has the libvmaf filter.
+	VMAFAvailable bool
+	// Capabilities is probed from ffmpeg at startup; nil if ffmpeg could not be run.
+	Capabilities *ffmpeg.Capabilities
+	// RNNoiseModel is the arnndn model file for RNNoise denoising; cleared at
+	// startup if the file is missing.
+	RNNoiseModel string
 	// Auth
 	AuthUsername string
 	AuthPassword string
@@ -66,7 +78,8 @@ func Load() *Config {
 	ffmpegPath := getEnv("FFMPEG_PATH", "ffmpeg")
 	ffprobePath := getEnv("FFPROBE_PATH", "ffprobe")
 	logRingBufferSize := getEnvInt("LOG_RING_BUFFER_SIZE", 200)
-	hwAccel := getEnv("HWACCEL", "auto") // auto | none | cuda | qsv | videotoolbox
+	hwAccel := getEnv("HWACCEL", "auto") // auto | none | cuda | qsv | vaapi | amf | videotoolbox
+	rnnoiseModel := getEnv("RNNOISE_MODEL", filepath.Join("models", "rnnoise", "cb.rnnn"))
 
 	// Create directories
 	uploadDir := filepath.Join(".", "uploads")
@@ -90,6 +103,7 @@ func Load() *Config {
 		OutputDir:         outputDir,
 		LogRingBufferSize: logRingBufferSize,
 		HWAccel:           hwAccel,
+		RNNoiseModel:      rnnoiseModel,
 		AuthUsername:      authUsername,
 		AuthPassword:      authPassword,
 		AuthSecret:        authSecret,
@@ -0,0 +1,375 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ─── Audio Analysis ───────────────────────────────────────────────────────────
+
+// TimeRange is a [Start, End) span of the source timeline in seconds.
+type TimeRange struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// Duration returns End-Start.
+func (r TimeRange) Duration() float64 { return r.End - r.Start }
+
+// RMSPoint is the RMS level of one analysis window.
+type RMSPoint struct {
+	Time float64 `json:"time"`
+	DB   float64 `json:"db"`
+}
+
+// AudioAnalysis is the result of AnalyzeAudio. dB values are floored at
+// silenceFloorDB so the struct is always JSON-encodable.
+type AudioAnalysis struct {
+	Duration           float64     `json:"duration"`
+	Channels           int         `json:"channels"`
+	SampleRate         int         `json:"sample_rate"`
+	IntegratedLUFS     float64     `json:"integrated_lufs"`
+	LoudnessRangeLU    float64     `json:"loudness_range_lu"`
+	TruePeakDBFS       float64     `json:"true_peak_dbfs"`
+	SamplePeakDBFS     float64     `json:"sample_peak_dbfs"`
+	RMSDB              float64     `json:"rms_db"`
+	RMSWindowSecs      float64     `json:"rms_window_secs"`
+	RMS                []RMSPoint  `json:"rms"`
+	Clipping           bool        `json:"clipping"`
+	ClippedSamples     int64       `json:"clipped_samples"`
+	ClippingRanges     []TimeRange `json:"clipping_ranges"`
+	Silences           []TimeRange `json:"silences"`
+	ChannelCorrelation *float64    `json:"channel_correlation,omitempty"` // L/R, -1..1; nil for mono
+}
+
+// AudioAnalysisOptions tunes AnalyzeAudio. Zero values use the defaults.
+type AudioAnalysisOptions struct {
+	Channels      int     // source channel count from the probe; 0 = treat as stereo
+	SampleRate    int     // source sample rate from the probe; 0 = 48000
+	Duration      float64 // source duration, used to size RMS windows
+	SilenceDB     float64 // silencedetect noise floor, default -50 dB
+	SilenceMinSec float64 // minimum silence length, default 0.5 s
+}
+
+const (
+	silenceFloorDB = -120.0
+	// clipThreshold is the linear sample level treated as clipped (≈ -0.01 dBFS).
+	clipThreshold = 0.999
+	// maxRMSPoints bounds the RMS series so long files stay cheap to return.
+	maxRMSPoints = 2000
+	// maxClipRanges bounds the reported clipping ranges.
+	maxClipRanges = 500
+)
+
+// AnalyzeAudio decodes the first audio stream of inputPath once and reports
+// EBU R128 loudness (ebur128), silence ranges (silencedetect), RMS over time,
+// clipping and L/R correlation (computed from the decoded PCM). ph (may be
+// nil) follows the decode when opts.Duration is known.
+func AnalyzeAudio(ctx context.Context, ffmpegPath, inputPath string, opts AudioAnalysisOptions, ph ProgressHandler) (*AudioAnalysis, error) {
+	channels := 2
+	if opts.Channels == 1 {
+		channels = 1
+	}
+	rate := opts.SampleRate
+	if rate <= 0 {
+		rate = 48000
+	}
+	silenceDB := opts.SilenceDB
+	if silenceDB == 0 {
+		silenceDB = -50
+	}
+	silenceMin := opts.SilenceMinSec
+	if silenceMin <= 0 {
+		silenceMin = 0.5
+	}
+	window := 0.5
+	if opts.Duration/maxRMSPoints > window {
+		window = opts.Duration / maxRMSPoints
+	}
+
+	cmd := exec.CommandContext(ctx, ffmpegPath,
+		"-hide_banner", "-nostats", "-v", "info",
+		"-i", inputPath,
+		"-vn",
+		"-af", fmt.Sprintf("ebur128=peak=true:framelog=verbose,silencedetect=n=%.1fdB:d=%.3f", silenceDB, silenceMin),
+		"-ac", strconv.Itoa(channels),
+		"-ar", strconv.Itoa(rate),
+		"-f", "f32le",
+		"-",
+	)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("audio analysis stdout pipe: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("audio analysis stderr pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("audio analysis ffmpeg start: %w", err)
+	}
+
+	summary := &ebur128Summary{}
+	silence := &silenceParser{}
+	errTail := &tailBuffer{max: 8 * 1024}
+	errDone := make(chan struct{})
+	go func() {
+		defer close(errDone)
+		sc := bufio.NewScanner(stderr)
+		for sc.Scan() {
+			line := sc.Text()
+			summary.parseLine(line)
+			silence.parseLine(line)
+			errTail.Write([]byte(line + "\n"))
+		}
+		io.Copy(io.Discard, stderr)
+	}()
+
+	pcm := newPCMStats(channels, rate, window)
+	var onRead func(frames int64)
+	if ph != nil && opts.Duration > 0 {
+		reported := int64(0)
+		onRead = func(frames int64) {
+			// About once per second of audio.
+			if frames-reported < int64(rate) {
+				return
+			}
+			reported = frames
+			secs := float64(frames) / float64(rate)
+			ph(ProgressEvent{Progress: math.Min(secs/opts.Duration, 1), OutTimeMs: secs * 1e6})
+		}
+	}
+	readErr := pcm.consume(bufio.NewReaderSize(stdout, 64*1024), onRead)
+	<-errDone
+	waitErr := cmd.Wait()
+	if readErr != nil {
+		return nil, fmt.Errorf("audio analysis read failed: %w", readErr)
+	}
+	if waitErr != nil {
+		if text := strings.TrimSpace(errTail.String()); text != "" {
+			return nil, fmt.Errorf("audio analysis ffmpeg: %w: %s", waitErr, text)
+		}
+		return nil, fmt.Errorf("audio analysis ffmpeg: %w", waitErr)
+	}
+
+	duration := float64(pcm.frames) / float64(rate)
+	a := &AudioAnalysis{
+		Duration:        duration,
+		Channels:        channels,
+		SampleRate:      rate,
+		IntegratedLUFS:  floorDB(summary.integrated),
+		LoudnessRangeLU: finiteOr(summary.lra, 0),
+		TruePeakDBFS:    floorDB(summary.truePeak),
+		SamplePeakDBFS:  linearToDB(pcm.peak),
+		RMSDB:           pcm.totalRMSDB(),
+		RMSWindowSecs:   window,
+		RMS:             pcm.rms,
+		Clipping:        pcm.clipped > 0,
+		ClippedSamples:  pcm.clipped,
+		ClippingRanges:  pcm.clipRanges,
+		Silences:        silence.finish(duration),
+	}
+	if channels == 2 {
+		a.ChannelCorrelation = pcm.correlation()
+	}
+	return a, nil
+}
+
+// ebur128Summary picks the integrated loudness, LRA and true peak out of the
+// summary ebur128 prints when the stream ends.
+type ebur128Summary struct {
+	inSummary  bool
+	section    string
+	integrated float64
+	lra        float64
+	truePeak   float64
+}
+
+var ebur128ValueRe = regexp.MustCompile(`^(I|LRA|Peak):\s+(\S+)\s`)
+
+func (s *ebur128Summary) parseLine(line string) {
+	if strings.Contains(line, "Summary:") {
+		s.inSummary = true
+		s.integrated, s.lra, s.truePeak = math.Inf(-1), 0, math.Inf(-1)
+		return
+	}
+	if !s.inSummary {
+		return
+	}
+	trimmed := strings.TrimSpace(line)
+	if strings.HasSuffix(trimmed, ":") {
+		s.section = trimmed
+		return
+	}
+	m := ebur128ValueRe.FindStringSubmatch(trimmed + " ")
+	if m == nil {
+		return
+	}
+	v, err := strconv.ParseFloat(m[2], 64)
+	if err != nil {
+		return
+	}
+	switch {
+	case m[1] == "I" && s.section == "Integrated loudness:":
+		s.integrated = v
+	case m[1] == "LRA" && s.section == "Loudness range:":
+		s.lra = v
+	case m[1] == "Peak" && s.section == "True peak:":
+		s.truePeak = v
+	}
+}
+
+// pcmStats accumulates per-window RMS, peaks, clipping and L/R correlation over
+// interleaved f32le samples.
+type pcmStats struct {
+	channels     int
+	rate         int
+	windowFrames int64
+
+	frames      int64
+	peak        float64
+	sumSq       float64
+	winSumSq    float64
+	winFrames   int64
+	rms         []RMSPoint
+	clipped     int64
+	clipRanges  []TimeRange
+	inClip      bool
+	clipTracked bool
+	sumLR       float64
+	sumLL       float64
+	sumRR       float64
+}
+
+func newPCMStats(channels, rate int, window float64) *pcmStats {
+	wf := int64(window * float64(rate))
+	if wf < 1 {
+		wf = 1
+	}
+	return &pcmStats{channels: channels, rate: rate, windowFrames: wf, rms: []RMSPoint{}, clipRanges: []TimeRange{}}
+}
+
+func (p *pcmStats) consume(r io.Reader, onRead func(frames int64)) error {
+	frameBytes := 4 * p.channels
+	buf := make([]byte, frameBytes*4096)
+	for {
+		n, err := io.ReadFull(r, buf)
+		for off := 0; off+frameBytes <= n; off += frameBytes {
+			p.addFrame(buf[off : off+frameBytes])
+		}
+		if onRead != nil {
+			onRead(p.frames)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			p.flushWindow()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (p *pcmStats) addFrame(frame []byte) {
+	var l, r float64
+	clipped := false
+	for ch := 0; ch < p.channels; ch++ {
+		v := float64(math.Float32frombits(binary.LittleEndian.Uint32(frame[ch*4:])))
+		av := math.Abs(v)
+		if av > p.peak {
+			p.peak = av
+		}
+		if av >= clipThreshold {
+			p.clipped++
+			clipped = true
+		}
+		p.sumSq += v * v
+		p.winSumSq += v * v
+		if ch == 0 {
+			l = v
+		} else if ch == 1 {
+			r = v
+		}
+	}
+	if p.channels == 2 {
+		p.sumLR += l * r
+		p.sumLL += l * l
+		p.sumRR += r * r
+	}
+	if clipped {
+		t := float64(p.frames) / float64(p.rate)
+		if !p.inClip {
+			p.inClip = true
+			p.clipTracked = len(p.clipRanges) < maxClipRanges
+			if p.clipTracked {
+				p.clipRanges = append(p.clipRanges, TimeRange{Start: t})
+			}
+		}
+		if p.clipTracked {
+			p.clipRanges[len(p.clipRanges)-1].End = t + 1/float64(p.rate)
+		}
+	} else {
+		p.inClip = false
+	}
+	p.frames++
+	p.winFrames++
+	if p.winFrames >= p.windowFrames {
+		p.flushWindow()
+	}
+}
+
+func (p *pcmStats) flushWindow() {
+	if p.winFrames == 0 {
+		return
+	}
+	mean := p.winSumSq / float64(p.winFrames*int64(p.channels))
+	start := float64(p.frames-p.winFrames) / float64(p.rate)
+	p.rms = append(p.rms, RMSPoint{Time: start, DB: linearToDB(math.Sqrt(mean))})
+	p.winSumSq, p.winFrames = 0, 0
+}
+
+func (p *pcmStats) totalRMSDB() float64 {
+	if p.frames == 0 {
+		return silenceFloorDB
+	}
+	return linearToDB(math.Sqrt(p.sumSq / float64(p.frames*int64(p.channels))))
+}
+
+// correlation is the zero-lag L/R correlation: +1 mono-compatible, 0
+// unrelated, -1 out of phase. nil when either channel is silent.
+func (p *pcmStats) correlation() *float64 {
+	if p.sumLL == 0 || p.sumRR == 0 {
+		return nil
+	}
+	c := p.sumLR / math.Sqrt(p.sumLL*p.sumRR)
+	return &c
+}
+
+func linearToDB(v float64) float64 {
+	if v <= 0 {
+		return silenceFloorDB
+	}
+	return floorDB(20 * math.Log10(v))
+}
+
+func floorDB(v float64) float64 {
+	if math.IsNaN(v) || v < silenceFloorDB {
+		return silenceFloorDB
+	}
+	return v
+}
+
+func finiteOr(v, fallback float64) float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return fallback
+	}
+	return v
+}
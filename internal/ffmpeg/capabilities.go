@@ -0,0 +1,209 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ─── Capability discovery ─────────────────────────────────────────────────────
+
+// Capabilities is what an ffmpeg build supports, from -version, -encoders,
+// -decoders, -filters and -muxers. A nil *Capabilities (ffmpeg could not be
+// probed) reports everything as available, leaving failures to the run.
+type Capabilities struct {
+	Version  string
+	Encoders map[string]bool
+	Decoders map[string]bool
+	Filters  map[string]bool
+	Muxers   map[string]bool
+}
+
+// FeatureFilters lists the filters each optional feature needs.
+var FeatureFilters = map[string][]string{
+	"loudness_normalization": {"loudnorm"},
+	"audio_analysis":         {"ebur128", "silencedetect"},
+	"jump_cut":               {"silencedetect"},
+	"scene_detection":        {"select", "metadata"},
+	"qc":                     {"blackdetect", "freezedetect", "ebur128", "silencedetect"},
+	"quality_metrics":        {"psnr", "ssim"},
+	"vmaf":                   {"libvmaf"},
+	"upmix":                  {"surround"},
+	"audio_cleanup":          {"highpass", "afftdn", "agate", "deesser", "acompressor", "alimiter"},
+	"rnnoise":                {"arnndn"},
+	"music_ducking":          {"asplit", "sidechaincompress", "amix"},
+	"stabilization":          {"vidstabdetect", "vidstabtransform"},
+	"deshake":                {"deshake"},
+	"lut":                    {"lut3d"},
+	"color_temperature":      {"colortemperature"},
+	"tonemap":                {"zscale", "tonemap"},
+	"interlace_detection":    {"idet"},
+	"bwdif":                  {"bwdif"},
+	"yadif":                  {"yadif"},
+	"frame_blending":         {"framerate"},
+	"motion_interpolation":   {"minterpolate"},
+	"reverse":                {"reverse", "areverse"},
+	"freeze_frame":           {"tpad", "adelay", "apad"},
+	"composition":            {"overlay", "xstack", "tpad", "amix", "adelay", "apad"},
+	"keyframed_zoom":         {"sendcmd", "crop"},
+}
+
+// formatMuxers maps output formats (file extensions) to ffmpeg muxers.
+var formatMuxers = map[string]string{
+	"mp4": "mp4", "mkv": "matroska", "mov": "mov", "webm": "webm", "avi": "avi", "mxf": "mxf",
+	"mp3": "mp3", "aac": "adts", "m4a": "ipod", "wav": "wav", "flac": "flac", "ogg": "ogg",
+}
+
+// ProbeCapabilities runs ffmpeg's listing commands once. It fails only when
+// ffmpeg itself cannot be run; a listing that fails is left empty.
+func ProbeCapabilities(ffmpegPath string) (*Capabilities, error) {
+	version, err := ffmpegListing(ffmpegPath, "-version")
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg -version: %w", err)
+	}
+	list := func(flag string) string {
+		out, _ := ffmpegListing(ffmpegPath, flag)
+		return out
+	}
+	return &Capabilities{
+		Version:  parseVersion(version),
+		Encoders: parseCodecList(list("-encoders")),
+		Decoders: parseCodecList(list("-decoders")),
+		Filters:  parseFilterList(list("-filters")),
+		Muxers:   parseMuxerList(list("-muxers")),
+	}, nil
+}
+
+func ffmpegListing(ffmpegPath, flag string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, ffmpegPath, "-hide_banner", flag).Output()
+	return string(out), err
+}
+
+// parseVersion returns "6.1.1" from "ffmpeg version 6.1.1 Copyright ...".
+func parseVersion(out string) string {
+	fields := strings.Fields(strings.SplitN(out, "\n", 2)[0])
+	if len(fields) >= 3 && fields[1] == "version" {
+		return fields[2]
+	}
+	return ""
+}
+
+// parseCodecList reads -encoders / -decoders: a legend, a " ------" line, then
+// one " V....D name  description" line per codec.
+func parseCodecList(out string) map[string]bool {
+	set := map[string]bool{}
+	listing := false
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if !listing {
+			listing = len(fields) == 1 && strings.Trim(fields[0], "-") == ""
+			continue
+		}
+		if len(fields) >= 2 {
+			set[fields[1]] = true
+		}
+	}
+	return set
+}
+
+// parseFilterList reads -filters lines such as " TSC loudnorm  A->A  ...".
+// The legend lines have no "->" column.
+func parseFilterList(out string) map[string]bool {
+	set := map[string]bool{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && strings.Contains(fields[2], "->") {
+			set[fields[1]] = true
+		}
+	}
+	return set
+}
+
+// parseMuxerList reads -muxers: a legend, a " --" line, then " E  name  ..."
+// lines, where name may list several comma-separated aliases.
+func parseMuxerList(out string) map[string]bool {
+	set := map[string]bool{}
+	listing := false
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if !listing {
+			listing = len(fields) == 1 && strings.Trim(fields[0], "-") == ""
+			continue
+		}
+		if len(fields) >= 2 {
+			for _, name := range strings.Split(fields[1], ",") {
+				set[name] = true
+			}
+		}
+	}
+	return set
+}
+
+// HasEncoder reports whether the build has the named encoder. "copy" is always
+// available.
+func (c *Capabilities) HasEncoder(name string) bool {
+	return c == nil || name == "copy" || c.Encoders[name]
+}
+
+// HasFilter reports whether the build has the named filter.
+func (c *Capabilities) HasFilter(name string) bool {
+	return c == nil || c.Filters[name]
+}
+
+// CanWrite reports whether the build can write the output format: it has the
+// muxer and, for audio-only formats, the encoder the format defaults to.
+func (c *Capabilities) CanWrite(format string) bool {
+	if c == nil {
+		return true
+	}
+	format = strings.ToLower(format)
+	muxer, ok := formatMuxers[format]
+	if !ok || !c.Muxers[muxer] {
+		return false
+	}
+	return !isAudioOnlyFormat(format) || c.HasEncoder(defaultAudioCodecForFormat(format))
+}
+
+// MissingFilters returns the filters of names the build lacks.
+func (c *Capabilities) MissingFilters(names ...string) []string {
+	var missing []string
+	for _, n := range names {
+		if !c.HasFilter(n) {
+			missing = append(missing, n)
+		}
+	}
+	return missing
+}
+
+// RequireFeature returns an error naming the filters feature (a FeatureFilters
+// key) needs that the build lacks.
+func (c *Capabilities) RequireFeature(feature string) error {
+	if missing := c.MissingFilters(FeatureFilters[feature]...); len(missing) > 0 {
+		return fmt.Errorf("%s is not available: this server's ffmpeg lacks the %s filter(s)", strings.ReplaceAll(feature, "_", " "), strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Features reports which FeatureFilters features the build supports.
+func (c *Capabilities) Features() map[string]bool {
+	out := make(map[string]bool, len(FeatureFilters))
+	for feature, filters := range FeatureFilters {
+		out[feature] = len(c.MissingFilters(filters...)) == 0
+	}
+	return out
+}
+
+// Names returns the members of a capability set, sorted.
+func Names(set map[string]bool) []string {
+	out := make([]string, 0, len(set))
+	for name := range set {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
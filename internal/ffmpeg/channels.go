@@ -0,0 +1,59 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"math"
+)
+
+// ─── Channel operations ───────────────────────────────────────────────────────
+
+// ChannelModes are the channel operations, applied before the other audio
+// effects:
+//
+//	mono    downmix to one channel
+//	stereo  downmix 5.1/7.1 (ITU-R BS.775: centre and surrounds at -3 dB, LFE
+//	        dropped), or upmix mono to both sides
+//	5.1     upmix stereo with the surround filter
+//	left    the left channel on both sides, e.g. a lav mic on one channel
+//	right   the right channel on both sides
+//	swap    exchange left and right
+var ChannelModes = []string{"mono", "stereo", "5.1", "left", "right", "swap"}
+
+// ChannelOptions rearranges the channels of an audio stream.
+type ChannelOptions struct {
+	Mode string // one of ChannelModes, or "" to keep the layout
+	// Pan is the stereo balance from -1 (left only) through 0 to 1 (right
+	// only); nil leaves it.
+	Pan *float64
+}
+
+// downmixResample sets the swresample mix levels used by downmixes.
+const downmixResample = "aresample=clev=0.707:slev=0.707:lfe_mix_level=0"
+
+// filters returns the audio filters for c, or nil.
+func (c ChannelOptions) filters() []string {
+	var filters []string
+	switch c.Mode {
+	case "mono", "stereo":
+		filters = append(filters, downmixResample, "aformat=channel_layouts="+c.Mode)
+	case "5.1":
+		filters = append(filters, "aformat=channel_layouts=stereo", "surround=chl_out=5.1")
+	case "left":
+		filters = append(filters, "pan=stereo|c0=c0|c1=c0")
+	case "right":
+		filters = append(filters, "pan=stereo|c0=c1|c1=c1")
+	case "swap":
+		filters = append(filters, "pan=stereo|c0=c1|c1=c0")
+	}
+	if c.Pan != nil && math.Abs(*c.Pan) > 0.001 {
+		// Linear balance: the far side is attenuated, the near side kept.
+		l, r := math.Min(1, 1-*c.Pan), math.Min(1, 1+*c.Pan)
+		filters = append(filters, fmt.Sprintf("pan=stereo|c0=%.3f*c0|c1=%.3f*c1", l, r))
+	}
+	return filters
+}
+
+// IsSet reports whether c changes anything.
+func (c ChannelOptions) IsSet() bool {
+	return c.Mode != "" || (c.Pan != nil && math.Abs(*c.Pan) > 0.001)
+}
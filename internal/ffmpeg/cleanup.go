@@ -0,0 +1,171 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ─── Audio cleanup ────────────────────────────────────────────────────────────
+
+// AudioCleanup is a voice cleanup chain. Its stages run in this order, after
+// the channel operations and before volume, speed and loudness normalization:
+//
+//	high-pass   rumble and handling noise below HighPass Hz (highpass)
+//	denoise     broadband noise: FFT (afftdn) or RNNoise (arnndn)
+//	gate        room tone between phrases (agate)
+//	de-ess      sibilance (deesser)
+//	compressor  evens out the level (acompressor)
+//	limiter     peak ceiling at Limiter dBFS (alimiter)
+//
+// String stages take one of the levels in CleanupLevels; "" and "off" skip
+// the stage, as does a zero HighPass or Limiter.
+type AudioCleanup struct {
+	HighPass     float64
+	Denoise      string
+	DenoiseModel string // "fft" (default) or "rnnoise"
+	// RNNoiseModel is the arnndn model file; DenoiseModel "rnnoise" needs it.
+	RNNoiseModel string
+	Gate         string
+	DeEss        string
+	Compressor   string
+	Limiter      float64
+}
+
+// CleanupLevels are the levels each string stage accepts.
+var CleanupLevels = map[string][]string{
+	"denoise":    {"light", "medium", "strong"},
+	"gate":       {"light", "medium", "strong"},
+	"deess":      {"light", "medium", "strong"},
+	"compressor": {"gentle", "voice", "heavy"},
+}
+
+// DenoiseModels are the noise reduction filters.
+var DenoiseModels = []string{"fft", "rnnoise"}
+
+// CleanupPresets are the named chains accepted by the API. Explicit stages
+// override the preset's.
+var CleanupPresets = map[string]AudioCleanup{
+	// voice: a typical voiceover or screen recording.
+	"voice": {HighPass: 80, Denoise: "medium", Gate: "light", DeEss: "medium", Compressor: "voice", Limiter: -1},
+	// podcast: a treated room; keeps the room tone between phrases.
+	"podcast": {HighPass: 80, Denoise: "light", DeEss: "light", Compressor: "voice", Limiter: -1},
+	// noisy: fans, traffic or air conditioning behind the voice.
+	"noisy": {HighPass: 100, Denoise: "strong", Gate: "medium", DeEss: "medium", Compressor: "heavy", Limiter: -1},
+}
+
+// ResolveAudioCleanup starts from the named preset (or an empty chain) and
+// applies any explicit stages.
+func ResolveAudioCleanup(preset string, highPass *float64, denoise, denoiseModel, gate, deEss, compressor *string, limiter *float64) AudioCleanup {
+	c := CleanupPresets[preset]
+	if highPass != nil {
+		c.HighPass = *highPass
+	}
+	if denoise != nil {
+		c.Denoise = *denoise
+	}
+	if denoiseModel != nil {
+		c.DenoiseModel = *denoiseModel
+	}
+	if gate != nil {
+		c.Gate = *gate
+	}
+	if deEss != nil {
+		c.DeEss = *deEss
+	}
+	if compressor != nil {
+		c.Compressor = *compressor
+	}
+	if limiter != nil {
+		c.Limiter = *limiter
+	}
+	return c
+}
+
+// afftdn noise reduction (dB) and arnndn mix per denoise level.
+var (
+	fftNoiseReduction = map[string]float64{"light": 6, "medium": 12, "strong": 20}
+	rnnoiseMix        = map[string]float64{"light": 0.5, "medium": 0.8, "strong": 1}
+)
+
+// gateLevel is an agate threshold and the attenuation below it, in dB.
+type gateLevel struct{ threshold, reduction float64 }
+
+var gateLevels = map[string]gateLevel{
+	"light":  {threshold: -45, reduction: -12},
+	"medium": {threshold: -40, reduction: -24},
+	"strong": {threshold: -35, reduction: -40},
+}
+
+// deessIntensity is the deesser intensity (0-1) per level.
+var deessIntensity = map[string]float64{"light": 0.3, "medium": 0.5, "strong": 0.8}
+
+// compressorLevel is an acompressor setting; times are in milliseconds,
+// threshold and makeup gain in dB.
+type compressorLevel struct{ threshold, ratio, attack, release, makeup float64 }
+
+var compressorLevels = map[string]compressorLevel{
+	"gentle": {threshold: -18, ratio: 2, attack: 20, release: 250, makeup: 2},
+	"voice":  {threshold: -20, ratio: 3, attack: 10, release: 200, makeup: 4},
+	"heavy":  {threshold: -24, ratio: 6, attack: 5, release: 150, makeup: 6},
+}
+
+// dbToLinear converts a level in dB to the linear gain most audio filters take.
+func dbToLinear(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+// filters returns the cleanup filters, or nil for a nil or empty chain.
+func (c *AudioCleanup) filters() []string {
+	if c == nil {
+		return nil
+	}
+	var filters []string
+	if c.HighPass > 0 {
+		filters = append(filters, fmt.Sprintf("highpass=f=%.0f", c.HighPass))
+	}
+	if stageOn(c.Denoise) {
+		if c.DenoiseModel == "rnnoise" {
+			filters = append(filters, fmt.Sprintf("arnndn=m=%s:mix=%.2f", filterPath(c.RNNoiseModel), rnnoiseMix[c.Denoise]))
+		} else {
+			// tn tracks the noise floor, so it adapts when the room changes.
+			filters = append(filters, fmt.Sprintf("afftdn=nr=%.0f:nf=-50:tn=1", fftNoiseReduction[c.Denoise]))
+		}
+	}
+	if g, ok := gateLevels[c.Gate]; ok {
+		filters = append(filters, fmt.Sprintf("agate=threshold=%.5f:range=%.5f:attack=10:release=250",
+			dbToLinear(g.threshold), dbToLinear(g.reduction)))
+	}
+	if i, ok := deessIntensity[c.DeEss]; ok {
+		filters = append(filters, fmt.Sprintf("deesser=i=%.2f", i))
+	}
+	if k, ok := compressorLevels[c.Compressor]; ok {
+		filters = append(filters, fmt.Sprintf("acompressor=threshold=%.5f:ratio=%.0f:attack=%.0f:release=%.0f:makeup=%.3f",
+			dbToLinear(k.threshold), k.ratio, k.attack, k.release, dbToLinear(k.makeup)))
+	}
+	if c.Limiter < 0 {
+		// level=0 stops alimiter from raising the output to the ceiling.
+		filters = append(filters, fmt.Sprintf("alimiter=limit=%.5f:attack=5:release=50:level=0", dbToLinear(c.Limiter)))
+	}
+	return filters
+}
+
+// IsSet reports whether c (which may be nil) runs any stage.
+func (c *AudioCleanup) IsSet() bool {
+	return len(c.filters()) > 0
+}
+
+// UsesRNNoise reports whether c denoises with arnndn.
+func (c *AudioCleanup) UsesRNNoise() bool {
+	return c != nil && stageOn(c.Denoise) && c.DenoiseModel == "rnnoise"
+}
+
+// stageOn reports whether a string stage is enabled.
+func stageOn(level string) bool {
+	return level != "" && level != "off"
+}
+
+// filterPath escapes a file path for use as a filter option value.
+func filterPath(path string) string {
+	return strings.NewReplacer(`\`, "/", ":", `\\:`, "'", `\\\'`, ",", `\,`, ";", `\;`, "[", `\[`, "]", `\]`).Replace(path)
+}
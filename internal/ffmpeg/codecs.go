@@ -0,0 +1,204 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ─── Software encoders ────────────────────────────────────────────────────────
+
+// Requests name presets the x264 way. The VP9 and AV1 encoders use a numeric
+// speed instead, so each name maps onto the encoder's own scale.
+var (
+	// svtAV1Presets maps onto SVT-AV1's -preset 0 (slowest) … 13.
+	svtAV1Presets = map[string]int{
+		"ultrafast": 12, "superfast": 11, "veryfast": 10, "faster": 9, "fast": 8,
+		"medium": 6, "slow": 5, "slower": 4, "veryslow": 2,
+	}
+	// aomCPUUsed maps onto libaom's -cpu-used 8 (fastest) … 0.
+	aomCPUUsed = map[string]int{
+		"ultrafast": 8, "superfast": 7, "veryfast": 6, "faster": 5, "fast": 4,
+		"medium": 3, "slow": 2, "slower": 1, "veryslow": 0,
+	}
+	// vp9CPUUsed maps onto libvpx's -cpu-used 5 (fastest) … 0 at -deadline good.
+	vp9CPUUsed = map[string]int{
+		"ultrafast": 5, "superfast": 5, "veryfast": 4, "faster": 4, "fast": 3,
+		"medium": 2, "slow": 1, "slower": 1, "veryslow": 0,
+	}
+)
+
+// AV1Decoders are the software AV1 decoders, in ffmpeg's order of preference.
+// The native "av1" decoder only drives hardware decoding.
+var AV1Decoders = []string{"libdav1d", "libaom-av1"}
+
+// softwareQualityArgs returns the preset and rate-control flags for a software
+// encoder. With crf and no bitrate, libaom and libvpx need -b:v 0 for
+// constant-quality mode.
+func softwareQualityArgs(vCodec string, crf *int, preset string, hasBitrate bool) []string {
+	var args []string
+	switch vCodec {
+	case "libsvtav1":
+		args = append(args, "-preset", fmt.Sprintf("%d", speedLevel(svtAV1Presets, preset)))
+		if crf != nil {
+			args = append(args, "-crf", fmt.Sprintf("%d", *crf))
+		}
+	case "libaom-av1", "libvpx-vp9":
+		if vCodec == "libaom-av1" {
+			args = append(args, "-cpu-used", fmt.Sprintf("%d", speedLevel(aomCPUUsed, preset)))
+		} else {
+			args = append(args, "-deadline", "good", "-cpu-used", fmt.Sprintf("%d", speedLevel(vp9CPUUsed, preset)))
+		}
+		args = append(args, "-row-mt", "1")
+		if crf != nil {
+			args = append(args, "-crf", fmt.Sprintf("%d", *crf))
+			if !hasBitrate {
+				args = append(args, "-b:v", "0")
+			}
+		}
+	case "prores_ks", "dnxhd", "ffv1":
+		// Quality is set by the profile (intermediateArgs).
+	default: // libx264, libx265
+		args = append(args, "-preset", preset)
+		if crf != nil {
+			args = append(args, "-crf", fmt.Sprintf("%d", *crf))
+		}
+	}
+	return args
+}
+
+// speedLevel looks preset up in levels, falling back to "medium".
+func speedLevel(levels map[string]int, preset string) int {
+	if n, ok := levels[preset]; ok {
+		return n
+	}
+	return levels["medium"]
+}
+
+// defaultVideoCodecForFormat is the encoder used when a re-encode names none.
+func defaultVideoCodecForFormat(format string) string {
+	if strings.EqualFold(format, "webm") {
+		return "libvpx-vp9"
+	}
+	return "libx264"
+}
+
+// VideoEncoderFor is the video encoder a re-encode to format uses: codec when
+// one is named, else hwEncoder when the container can store it, else the
+// format's default.
+func VideoEncoderFor(format string, codec *string, hwEncoder string) string {
+	if codec != nil && *codec != "" && *codec != "copy" {
+		return *codec
+	}
+	if hwEncoder != "" && containerAccepts(format, hwEncoder) {
+		return hwEncoder
+	}
+	return defaultVideoCodecForFormat(format)
+}
+
+// ConvertVideoEncoder is the video encoder Convert uses: codec when one is
+// named, else the muxer's own default, which Convert leaves to ffmpeg.
+func ConvertVideoEncoder(format string, codec *string) string {
+	if codec == nil || *codec == "" || *codec == "copy" {
+		switch strings.ToLower(format) {
+		case "avi":
+			return "mpeg4"
+		case "mxf":
+			return "mpeg2video"
+		}
+	}
+	return VideoEncoderFor(format, codec, "")
+}
+
+// containerAccepts reports whether format can store encoder's output. Only
+// WebM is restrictive: VP9 or AV1.
+func containerAccepts(format, encoder string) bool {
+	if !strings.EqualFold(format, "webm") {
+		return true
+	}
+	return encoder == "libvpx-vp9" || strings.Contains(encoder, "av1")
+}
+
+// CanDecode reports whether the build can decode codec (an ffprobe codec
+// name). Only AV1 is checked, since its decoders are optional libraries.
+func (c *Capabilities) CanDecode(codec string) bool {
+	if c == nil || codec != "av1" {
+		return true
+	}
+	for _, d := range AV1Decoders {
+		if c.Decoders[d] {
+			return true
+		}
+	}
+	return false
+}
+
+// ─── Intermediate codecs ──────────────────────────────────────────────────────
+
+// IntermediateProfiles lists the profiles of the editing and mastering codecs,
+// each with the pixel format it is encoded in. FFV1 has no profiles and keeps
+// the source's pixel format.
+var IntermediateProfiles = map[string]map[string]string{
+	"prores_ks": {
+		"proxy": "yuv422p10le", "lt": "yuv422p10le", "standard": "yuv422p10le", "hq": "yuv422p10le",
+		"4444": "yuv444p10le", "4444xq": "yuv444p10le",
+	},
+	// DNxHR through the dnxhd encoder; profiles are dnxhr_<name>.
+	"dnxhd": {
+		"lb": "yuv422p", "sq": "yuv422p", "hq": "yuv422p", "hqx": "yuv422p10le", "444": "yuv444p10le",
+	},
+	"ffv1": {},
+}
+
+// DefaultIntermediateProfile is used when a request names no profile.
+var DefaultIntermediateProfile = map[string]string{"prores_ks": "hq", "dnxhd": "hq"}
+
+// IsIntermediateCodec reports whether encoder is ProRes, DNxHR or FFV1.
+func IsIntermediateCodec(encoder string) bool {
+	_, ok := IntermediateProfiles[encoder]
+	return ok
+}
+
+// intermediateArgs returns the profile, pixel format and encoder flags for an
+// intermediate codec, or nil for other encoders. profile may be nil.
+func intermediateArgs(vCodec string, profile *string) []string {
+	profiles, ok := IntermediateProfiles[vCodec]
+	if !ok {
+		return nil
+	}
+	if vCodec == "ffv1" {
+		// Version 3, all intra, with per-slice CRCs for archival.
+		return []string{"-level", "3", "-g", "1", "-slicecrc", "1"}
+	}
+	p := DefaultIntermediateProfile[vCodec]
+	if profile != nil && profiles[*profile] != "" {
+		p = *profile
+	}
+	args := []string{"-pix_fmt", profiles[p]}
+	if vCodec == "dnxhd" {
+		return append(args, "-profile:v", "dnxhr_"+p)
+	}
+	// The apl0 vendor tag is what Apple's and Resolve's decoders expect.
+	return append(args, "-profile:v", p, "-vendor", "apl0")
+}
+
+// intermediateAudioCodec is the audio codec that goes with an intermediate
+// video codec, and the only kind MXF takes: uncompressed 24-bit PCM.
+const intermediateAudioCodec = "pcm_s24le"
+
+// resolveAudioCodecFor is resolveAudioCodec for an output whose video is
+// vCodec: intermediate codecs and MXF default to PCM, which MXF requires at
+// 48 kHz.
+func resolveAudioCodecFor(format, vCodec string, codec *string) []string {
+	if format != "mxf" && !IsIntermediateCodec(vCodec) {
+		return []string{"-c:a", resolveAudioCodec(format, codec)}
+	}
+	aCodec := intermediateAudioCodec
+	if codec != nil && *codec != "" && *codec != "copy" {
+		aCodec = *codec
+	}
+	args := []string{"-c:a", aCodec}
+	if format == "mxf" {
+		args = append(args, "-ar", "48000")
+	}
+	return args
+}
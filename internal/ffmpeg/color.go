@@ -0,0 +1,143 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ─── Colour ───────────────────────────────────────────────────────────────────
+
+// ColorGrade is a colour correction on top of brightness and contrast.
+type ColorGrade struct {
+	Saturation  *float64 // 0 (grey) … 3, 1 = unchanged
+	Gamma       *float64 // 0.1 … 10, 1 = unchanged
+	Hue         *float64 // rotation, degrees
+	Temperature *int     // light temperature, K; below 6500 is warmer
+	Curves      string   // one of CurvesPresets, or ""
+	// LUTPath is a .cube 3D LUT, applied first since technical LUTs (log to
+	// Rec.709) expect the camera's own colours.
+	LUTPath string
+}
+
+// CurvesPresets are the curves filter presets a grade may use.
+var CurvesPresets = []string{
+	"color_negative", "cross_process", "darker", "increase_contrast", "lighter",
+	"linear_contrast", "medium_contrast", "negative", "strong_contrast", "vintage",
+}
+
+// IsSet reports whether g (which may be nil) changes anything.
+func (g *ColorGrade) IsSet() bool {
+	return len(colorFilters(nil, nil, g)) > 0
+}
+
+// colorFilters returns the colour filters for brightness, contrast and g (any
+// may be nil): the LUT, white balance, one eq, hue, then curves.
+func colorFilters(brightness, contrast *float64, g *ColorGrade) []string {
+	var filters, eq []string
+	if brightness != nil {
+		eq = append(eq, fmt.Sprintf("brightness=%f", *brightness))
+	}
+	if contrast != nil {
+		eq = append(eq, fmt.Sprintf("contrast=%f", *contrast))
+	}
+	if g != nil {
+		if g.LUTPath != "" {
+			filters = append(filters, fmt.Sprintf("lut3d=file=%s:interp=tetrahedral", filterPath(g.LUTPath)))
+		}
+		if g.Temperature != nil {
+			filters = append(filters, fmt.Sprintf("colortemperature=temperature=%d", *g.Temperature))
+		}
+		if g.Saturation != nil {
+			eq = append(eq, fmt.Sprintf("saturation=%f", *g.Saturation))
+		}
+		if g.Gamma != nil {
+			eq = append(eq, fmt.Sprintf("gamma=%f", *g.Gamma))
+		}
+	}
+	if len(eq) > 0 {
+		filters = append(filters, "eq="+strings.Join(eq, ":"))
+	}
+	if g != nil {
+		if g.Hue != nil {
+			filters = append(filters, fmt.Sprintf("hue=h=%f", *g.Hue))
+		}
+		if g.Curves != "" {
+			filters = append(filters, "curves=preset="+g.Curves)
+		}
+	}
+	return filters
+}
+
+// ─── .cube LUTs ───────────────────────────────────────────────────────────────
+
+// MaxCubeSize is the largest LUT_3D_SIZE lut3d loads.
+const MaxCubeSize = 256
+
+// CubeLUT describes a 3D LUT in the .cube format.
+type CubeLUT struct {
+	Title string `json:"title,omitempty"`
+	Size  int    `json:"size"` // points per axis
+}
+
+// ParseCubeLUT checks that r is a 3D .cube LUT lut3d can load: a LUT_3D_SIZE
+// of 2 to MaxCubeSize followed by size³ rows of three numbers.
+func ParseCubeLUT(r io.Reader) (*CubeLUT, error) {
+	lut := &CubeLUT{}
+	rows := 0
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "TITLE":
+			lut.Title = strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "TITLE")), `"`)
+			continue
+		case "LUT_1D_SIZE":
+			return nil, fmt.Errorf("1D LUTs are not supported, only 3D")
+		case "LUT_3D_SIZE":
+			size, err := strconv.Atoi(fieldAt(fields, 1))
+			if err != nil || size < 2 || size > MaxCubeSize {
+				return nil, fmt.Errorf("line %d: LUT_3D_SIZE must be between 2 and %d", n, MaxCubeSize)
+			}
+			lut.Size = size
+			continue
+		case "DOMAIN_MIN", "DOMAIN_MAX", "LUT_3D_INPUT_RANGE":
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected three values, got %q", n, line)
+		}
+		for _, f := range fields {
+			if _, err := strconv.ParseFloat(f, 64); err != nil {
+				return nil, fmt.Errorf("line %d: %q is not a number", n, f)
+			}
+		}
+		if lut.Size == 0 {
+			return nil, fmt.Errorf("line %d: LUT data before LUT_3D_SIZE", n)
+		}
+		rows++
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if lut.Size == 0 {
+		return nil, fmt.Errorf("no LUT_3D_SIZE: not a 3D .cube LUT")
+	}
+	if want := lut.Size * lut.Size * lut.Size; rows != want {
+		return nil, fmt.Errorf("LUT_3D_SIZE %d needs %d rows, found %d", lut.Size, want, rows)
+	}
+	return lut, nil
+}
+
+func fieldAt(fields []string, i int) string {
+	if i < len(fields) {
+		return fields[i]
+	}
+	return ""
+}
@@ -0,0 +1,250 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ─── Composition ──────────────────────────────────────────────────────────────
+
+// ComposeLayouts are the composition layouts: "pip" insets the second source
+// over the first; the others tile the sources with xstack, the grid two by
+// two.
+var ComposeLayouts = []string{"pip", "side_by_side", "top_bottom", "grid"}
+
+// PiPCorners are the corners a picture-in-picture inset can sit in.
+var PiPCorners = []string{"top_left", "top_right", "bottom_left", "bottom_right"}
+
+// composeXStack is the xstack layout of each tiled layout; w0 / h0 are the
+// first cell's size, and every cell is the same size.
+var composeXStack = map[string]string{
+	"side_by_side": "0_0|w0_0",
+	"top_bottom":   "0_0|0_h0",
+	"grid":         "0_0|w0_0|0_h0|w0_h0",
+}
+
+// ComposeSource is one input of a composition.
+type ComposeSource struct {
+	FilePath string
+	Start    float64 // in-point in the file
+	Duration float64 // seconds played from Start
+	// Offset is when the source enters the composition. Its cell is black
+	// before then and after it ends.
+	Offset float64
+	// Volume (may be nil, unity gain) scales the source's audio when it is
+	// used; 0 mutes it.
+	Volume   *float64
+	HasVideo bool
+	HasAudio bool
+}
+
+// PiP places the inset of a "pip" composition.
+type PiP struct {
+	Corner string  // one of PiPCorners; "" is bottom_right
+	Scale  float64 // inset width as a fraction of the canvas width
+	Margin int     // pixels between the inset and the canvas edges
+	Border int     // border width in pixels, 0 for none
+	// BorderColor is an ffmpeg colour name or #RRGGBB; "" is white.
+	BorderColor string
+}
+
+// ComposeOptions lays several sources out on one canvas.
+type ComposeOptions struct {
+	Sources []ComposeSource
+	Layout  string // one of ComposeLayouts
+	PiP     PiP
+	// Width and Height are the canvas size; each tile is an equal share.
+	Width  int
+	Height int
+	// FPS is the output frame rate as a rational ("30000/1001"); "" is 30.
+	FPS string
+	// Duration is the length of the output.
+	Duration float64
+	// Audio lists the sources (indexes into Sources) whose audio is used;
+	// more than one are mixed. Empty writes no audio.
+	Audio      []int
+	OutputPath string
+	FFmpegPath string
+	VideoCodec *string
+	AudioCodec *string
+	CRF        *int
+	Preset     *string
+	PresetMode string
+	FastStart  bool
+	HWEncoder  string
+}
+
+// composeCells returns the layout's tile count and tile size.
+func (opts ComposeOptions) composeCells() (n, w, h int) {
+	even := func(v int) int { return v &^ 1 }
+	switch opts.Layout {
+	case "side_by_side":
+		return 2, even(opts.Width / 2), opts.Height
+	case "top_bottom":
+		return 2, opts.Width, even(opts.Height / 2)
+	case "grid":
+		return 4, even(opts.Width / 2), even(opts.Height / 2)
+	}
+	return 1, opts.Width, opts.Height
+}
+
+// composeCell is the chain fitting source i into a w×h tile that lasts the
+// whole composition: letterboxed, black until its offset and after its end.
+func (opts ComposeOptions) composeCell(fc *strings.Builder, i, w, h int, fps, out string) {
+	src := opts.Sources[i]
+	if !src.HasVideo {
+		fmt.Fprintf(fc, "color=c=black:s=%dx%d:r=%s:d=%.6f,format=yuv420p%s;", w, h, fps, opts.Duration, out)
+		return
+	}
+	pad := fmt.Sprintf("tpad=stop_mode=add:stop_duration=%.6f", opts.Duration)
+	if src.Offset > 0 {
+		pad = fmt.Sprintf("tpad=start_duration=%.6f:stop_mode=add:stop_duration=%.6f", src.Offset, opts.Duration)
+	}
+	fmt.Fprintf(fc, "[%d:v]scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=%s,format=yuv420p,%s%s;",
+		i, w, h, w, h, fps, pad, out)
+}
+
+// pipInset is the chain scaling the inset, source 1, and framing it with the
+// border. Its timestamps start at its offset, and overlay shows nothing
+// before its first frame or (with eof_action=pass) after its last.
+func (opts ComposeOptions) pipInset(fc *strings.Builder, fps, out string) {
+	p := opts.PiP
+	w := int(float64(opts.Width)*p.Scale) &^ 1
+	chain := []string{fmt.Sprintf("scale=%d:-2", w), "setsar=1"}
+	if p.Border > 0 {
+		color := p.BorderColor
+		if color == "" {
+			color = "white"
+		}
+		chain = append(chain, fmt.Sprintf("pad=iw+%d:ih+%d:%d:%d:color=%s", 2*p.Border, 2*p.Border, p.Border, p.Border, color))
+	}
+	chain = append(chain, "fps="+fps, "format=yuv420p", fmt.Sprintf("setpts=PTS-STARTPTS+%.6f/TB", opts.Sources[1].Offset))
+	fmt.Fprintf(fc, "[1:v]%s%s;", strings.Join(chain, ","), out)
+}
+
+// pipPosition returns overlay's x and y for the inset's corner.
+func (p PiP) pipPosition() (string, string) {
+	x, y := fmt.Sprintf("main_w-overlay_w-%d", p.Margin), fmt.Sprintf("main_h-overlay_h-%d", p.Margin)
+	if strings.HasSuffix(p.Corner, "_left") {
+		x = fmt.Sprintf("%d", p.Margin)
+	}
+	if strings.HasPrefix(p.Corner, "top_") {
+		y = fmt.Sprintf("%d", p.Margin)
+	}
+	return x, y
+}
+
+// buildComposeVideo writes the video half of the graph, ending in [outv].
+func (opts ComposeOptions) buildComposeVideo(fc *strings.Builder, fps string) {
+	n, w, h := opts.composeCells()
+	if opts.Layout == "pip" {
+		opts.composeCell(fc, 0, w, h, fps, "[main]")
+		opts.pipInset(fc, fps, "[inset]")
+		x, y := opts.PiP.pipPosition()
+		fmt.Fprintf(fc, "[main][inset]overlay=x=%s:y=%s:eof_action=pass[outv]", x, y)
+		return
+	}
+	var cells strings.Builder
+	for i := 0; i < n; i++ {
+		label := fmt.Sprintf("[c%d]", i)
+		if i < len(opts.Sources) {
+			opts.composeCell(fc, i, w, h, fps, label)
+		} else {
+			// A grid with fewer than four sources leaves its last tiles black.
+			fmt.Fprintf(fc, "color=c=black:s=%dx%d:r=%s:d=%.6f,format=yuv420p%s;", w, h, fps, opts.Duration, label)
+		}
+		cells.WriteString(label)
+	}
+	fmt.Fprintf(fc, "%sxstack=inputs=%d:layout=%s[outv]", cells.String(), n, composeXStack[opts.Layout])
+}
+
+// buildComposeAudio writes the audio half of the graph, ending in [outa]:
+// each chosen source delayed to its offset, mixed when there are several,
+// and padded with silence to the full length.
+func (opts ComposeOptions) buildComposeAudio(fc *strings.Builder) {
+	var mix strings.Builder
+	for k, i := range opts.Audio {
+		chain := []string{"aresample=48000", "aformat=channel_layouts=stereo", "asetpts=PTS-STARTPTS"}
+		src := opts.Sources[i]
+		if src.Volume != nil && *src.Volume != 1 {
+			chain = append(chain, fmt.Sprintf("volume=%.3f", *src.Volume))
+		}
+		if src.Offset > 0 {
+			chain = append(chain, fmt.Sprintf("adelay=delays=%.0f:all=1", src.Offset*1000))
+		}
+		fmt.Fprintf(fc, ";[%d:a]%s[a%d]", i, strings.Join(chain, ","), k)
+		fmt.Fprintf(&mix, "[a%d]", k)
+	}
+	if len(opts.Audio) == 1 {
+		fc.WriteString(";[a0]apad[outa]")
+		return
+	}
+	// normalize=0 keeps each source at its own level instead of dividing
+	// the sum by the input count.
+	fmt.Fprintf(fc, ";%samix=inputs=%d:duration=longest:normalize=0,apad[outa]", mix.String(), len(opts.Audio))
+}
+
+// Compose renders the sources in opts.Layout to opts.OutputPath.
+func Compose(ctx context.Context, opts ComposeOptions, ph ProgressHandler) error {
+	n, _, _ := opts.composeCells()
+	switch {
+	case composeXStack[opts.Layout] == "" && opts.Layout != "pip":
+		return fmt.Errorf("unknown layout %q", opts.Layout)
+	case opts.Layout == "pip" && len(opts.Sources) != 2:
+		return fmt.Errorf("pip needs 2 sources, got %d", len(opts.Sources))
+	case opts.Layout != "pip" && (len(opts.Sources) < 2 || len(opts.Sources) > n):
+		return fmt.Errorf("%s needs 2 to %d sources, got %d", opts.Layout, n, len(opts.Sources))
+	case opts.Duration <= 0:
+		return fmt.Errorf("composition duration must be positive")
+	}
+	fps := opts.FPS
+	if fps == "" {
+		fps = "30"
+	}
+
+	var args []string
+	for _, src := range opts.Sources {
+		args = append(args,
+			"-ss", fmt.Sprintf("%.6f", src.Start),
+			"-t", fmt.Sprintf("%.6f", src.Duration),
+			"-i", src.FilePath,
+		)
+	}
+	args = append(args, "-progress", "pipe:1", "-v", "warning")
+
+	var fc strings.Builder
+	opts.buildComposeVideo(&fc, fps)
+	if len(opts.Audio) > 0 {
+		opts.buildComposeAudio(&fc)
+	}
+
+	outputFormat := strings.TrimPrefix(strings.ToLower(filepath.Ext(opts.OutputPath)), ".")
+	vCodec := VideoEncoderFor(outputFormat, opts.VideoCodec, opts.HWEncoder)
+	outV := "[outv]"
+	if up := hwUploadFilter(vCodec); up != "" {
+		fc.WriteString(";[outv]" + up + "[outvhw]")
+		outV = "[outvhw]"
+	}
+	args = append(hwDeviceArgs(vCodec), args...)
+
+	args = append(args, "-filter_complex", fc.String(), "-map", outV)
+	if len(opts.Audio) > 0 {
+		args = append(args, "-map", "[outa]")
+	}
+	args = append(args, "-c:v", vCodec)
+	args = append(args, videoQualityArgs(vCodec, opts.CRF, getPresetFromMode(opts.Preset, opts.PresetMode), false)...)
+	args = append(args, intermediateArgs(vCodec, nil)...)
+	if len(opts.Audio) > 0 {
+		args = append(args, resolveAudioCodecFor(outputFormat, vCodec, opts.AudioCodec)...)
+	} else {
+		args = append(args, "-an")
+	}
+	if opts.FastStart && outputFormat == "mp4" {
+		args = append(args, "-movflags", "+faststart")
+	}
+	args = append(args, "-t", fmt.Sprintf("%.6f", opts.Duration), "-y", opts.OutputPath)
+
+	return runFFmpeg(ctx, opts.FFmpegPath, args, &opts.Duration, ph)
+}
@@ -0,0 +1,168 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ─── Error classification ─────────────────────────────────────────────────────
+
+// ErrorCode is a stable identifier for a class of ffmpeg failure.
+type ErrorCode string
+
+const (
+	CodeUnknown          ErrorCode = "ffmpeg_failed"
+	CodeCanceled         ErrorCode = "canceled"
+	CodeTimeout          ErrorCode = "timeout"
+	CodeHWEncoderInit    ErrorCode = "hw_encoder_init"
+	CodeEncoderNotFound  ErrorCode = "encoder_not_found"
+	CodeDecoderNotFound  ErrorCode = "decoder_not_found"
+	CodeUnsupportedCodec ErrorCode = "unsupported_codec"
+	CodeInvalidFilter    ErrorCode = "invalid_filter"
+	CodeCorruptInput     ErrorCode = "corrupt_input"
+	CodeMissingInput     ErrorCode = "missing_input"
+	CodeNoSpace          ErrorCode = "no_space"
+	CodePermissionDenied ErrorCode = "permission_denied"
+	CodeOutOfMemory      ErrorCode = "out_of_memory"
+)
+
+// Error is a failed ffmpeg run. Code and Hint come from the stderr signature;
+// Detail is the stderr line that matched it.
+type Error struct {
+	Code   ErrorCode
+	Hint   string
+	Detail string
+	Stderr string // tail of stderr
+	err    error  // from cmd.Wait
+}
+
+func (e *Error) Error() string {
+	if e.Stderr != "" {
+		return fmt.Sprintf("ffmpeg: %v: %s", e.err, e.Stderr)
+	}
+	return fmt.Sprintf("ffmpeg: %v", e.err)
+}
+
+func (e *Error) Unwrap() error { return e.err }
+
+// errorSignature maps stderr substrings to a code. Signatures are tried in
+// order, so specific ones come before generic ones: NVENC, for one, reports a
+// busy device as "out of memory".
+type errorSignature struct {
+	code     ErrorCode
+	patterns []string
+	hint     string
+}
+
+var errorSignatures = []errorSignature{
+	{CodeHWEncoderInit, []string{
+		"No NVENC capable devices found", "OpenEncodeSessionEx failed", "Cannot load libcuda", "Cannot load nvcuda",
+		"CUDA_ERROR", "Failed to initialise VAAPI", "No VA display found", "Error creating a MFX session",
+		"Error initializing an internal MFX session", "cannot create compression session", "Device creation failed",
+		"Failed to create Direct3D device", "No capable devices found",
+	}, "The hardware encoder could not be initialised (driver, device or session limit). The job can be re-run with the software encoder."},
+	{CodeNoSpace, []string{"No space left on device"},
+		"The disk holding the outputs directory is full. Free some space or delete old outputs."},
+	{CodePermissionDenied, []string{"Permission denied", "Operation not permitted"},
+		"ffmpeg could not read the input or write the output. Check the permissions of the upload and output directories."},
+	{CodeOutOfMemory, []string{"Cannot allocate memory", "Out of memory"},
+		"ffmpeg ran out of memory. Lower the resolution or run fewer jobs at once (WORKERS)."},
+	{CodeEncoderNotFound, []string{"Unknown encoder", "Encoder not found", "Unrecognized encoder"},
+		"This ffmpeg build does not include the requested encoder. Pick another codec or install a full ffmpeg build."},
+	{CodeDecoderNotFound, []string{"Decoder (codec", "Failed to open decoder", "No decoder for"},
+		"This ffmpeg build cannot decode the input's codec (AV1 needs libdav1d or libaom). Install a full ffmpeg build or convert the source elsewhere."},
+	{CodeUnsupportedCodec, []string{
+		"Could not find tag for codec", "codec not currently supported in container",
+		"not supported by the muxer", "Could not write header for output file",
+	}, "The codec cannot be stored in this container. Choose a matching codec (e.g. VP9/Opus for WebM, H.264/AAC for MP4) or another output format."},
+	{CodeInvalidFilter, []string{
+		"Error initializing complex filters", "Error reinitializing filters", "Error initializing filter",
+		"Error parsing filterchain", "Error parsing a filter description", "No such filter",
+		"Failed to configure output pad", "Failed to configure input pad", "has an unconnected output",
+		"Cannot find a matching stream for unlabeled input pad", "Invalid stream specifier",
+	}, "The filter graph is invalid for this input, often because a stream it needs (e.g. audio) is missing or a parameter is out of range."},
+	{CodeMissingInput, []string{"No such file or directory"},
+		"An input file no longer exists. Uploads are cleaned up after a while; upload the file again."},
+	{CodeCorruptInput, []string{
+		"Invalid data found when processing input", "moov atom not found", "Error while decoding stream",
+		"corrupt decoded frame", "invalid frame size", "could not find codec parameters",
+	}, "The input appears damaged or truncated. Try re-exporting or re-uploading the source file."},
+}
+
+// ClassifyStderr returns the code, hint and matching line for ffmpeg's
+// stderr. Unrecognised output yields CodeUnknown with the last error-looking
+// line as the detail.
+func ClassifyStderr(stderr string) (code ErrorCode, hint, detail string) {
+	lines := strings.Split(stderr, "\n")
+	for _, sig := range errorSignatures {
+		for _, p := range sig.patterns {
+			lp := strings.ToLower(p)
+			// Last match first: the fatal message is at the end of the log.
+			for i := len(lines) - 1; i >= 0; i-- {
+				if strings.Contains(strings.ToLower(lines[i]), lp) {
+					return sig.code, sig.hint, strings.TrimSpace(lines[i])
+				}
+			}
+		}
+	}
+	for i := len(lines) - 1; i >= 0; i-- {
+		if l := strings.TrimSpace(lines[i]); l != "" && !strings.HasPrefix(l, "Conversion failed") {
+			detail = l
+			break
+		}
+	}
+	return CodeUnknown, "ffmpeg failed; see the job logs for its output.", detail
+}
+
+// newError classifies a failed run. A done context takes precedence, since
+// ffmpeg's output after being killed says nothing about why.
+func newError(ctx context.Context, waitErr error, stderr string) *Error {
+	e := &Error{Stderr: stderr, err: waitErr}
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		e.Code, e.Hint = CodeTimeout, "The job took longer than its time limit."
+	case context.Canceled:
+		e.Code, e.Hint = CodeCanceled, "The job was canceled."
+	default:
+		e.Code, e.Hint, e.Detail = ClassifyStderr(stderr)
+	}
+	return e
+}
+
+// AsError returns the *Error in err's chain, or nil.
+func AsError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return nil
+}
+
+// CodeOf returns the code of the ffmpeg failure in err's chain; errors that
+// did not come from an ffmpeg run yield "".
+func CodeOf(err error) ErrorCode {
+	if e := AsError(err); e != nil {
+		return e.Code
+	}
+	return ""
+}
+
+// HWEncoderFailed reports whether err is a failure of hwEncoder that a run
+// with the software encoder would avoid: the device could not be initialised,
+// or this ffmpeg build lacks the hardware encoder altogether. Software
+// encoders never qualify, since the retry would run the same command.
+func HWEncoderFailed(err error, hwEncoder string) bool {
+	e := AsError(err)
+	if e == nil || HWAccelOf(hwEncoder) == "" {
+		return false
+	}
+	switch e.Code {
+	case CodeHWEncoderInit:
+		return true
+	case CodeEncoderNotFound:
+		return strings.Contains(e.Detail, hwEncoder)
+	}
+	return false
+}
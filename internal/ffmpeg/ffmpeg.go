@@ -14,32 +14,62 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
-	"time"
 )
 
-// ProgressHandler receives normalised progress [0,1] and raw out_time_ms.
-type ProgressHandler func(current, total float64, outTimeMs float64)
-
 // ─── Media Info ───────────────────────────────────────────────────────────────
 
 type MediaInfo struct {
-	Duration   *float64
-	HasVideo   bool
-	HasAudio   bool
-	VideoCodec string
-	AudioCodec string
-	Resolution string
+	Duration        *float64
+	HasVideo        bool
+	HasAudio        bool
+	VideoCodec      string
+	AudioCodec      string
+	Resolution      string
+	AudioChannels   int
+	AudioSampleRate int
+	// HDR is the first video stream's HDR format ("pq" or "hlg"), or "".
+	HDR     string
+	Streams []StreamInfo
+	// Tags are the container's global tags, keys lowercased.
+	Tags     map[string]string
+	Chapters []Chapter
 }
 
 type ffprobeOutput struct {
 	Format struct {
-		Duration string `json:"duration"`
+		Duration string            `json:"duration"`
+		Tags     map[string]string `json:"tags"`
 	} `json:"format"`
+	Chapters []struct {
+		StartTime string `json:"start_time"`
+		EndTime   string `json:"end_time"`
+		Tags      struct {
+			Title string `json:"title"`
+		} `json:"tags"`
+	} `json:"chapters"`
 	Streams []struct {
-		CodecType string `json:"codec_type"`
-		CodecName string `json:"codec_name"`
-		Width     int    `json:"width"`
-		Height    int    `json:"height"`
+		Index         int    `json:"index"`
+		CodecType     string `json:"codec_type"`
+		CodecName     string `json:"codec_name"`
+		Width         int    `json:"width"`
+		Height        int    `json:"height"`
+		Channels      int    `json:"channels"`
+		ChannelLayout string `json:"channel_layout"`
+		SampleRate    string `json:"sample_rate"`
+		PixFmt        string `json:"pix_fmt"`
+		ColorSpace    string `json:"color_space"`
+		ColorTransfer string `json:"color_transfer"`
+		ColorPrimary  string `json:"color_primaries"`
+		FieldOrder    string `json:"field_order"`
+		Tags          struct {
+			Language string `json:"language"`
+			Title    string `json:"title"`
+		} `json:"tags"`
+		Disposition struct {
+			Default     int `json:"default"`
+			Forced      int `json:"forced"`
+			AttachedPic int `json:"attached_pic"`
+		} `json:"disposition"`
 	} `json:"streams"`
 }
 
@@ -48,6 +78,7 @@ func GetMediaInfo(ctx context.Context, ffprobePath, inputPath string) (*MediaInf
 		"-v", "error",
 		"-show_format",
 		"-show_streams",
+		"-show_chapters",
 		"-of", "json",
 		inputPath,
 	)
@@ -69,7 +100,35 @@ func parseFFprobeOutput(data []byte) (*MediaInfo, error) {
 			info.Duration = &d
 		}
 	}
+	for k, v := range probe.Format.Tags {
+		if info.Tags == nil {
+			info.Tags = map[string]string{}
+		}
+		info.Tags[strings.ToLower(k)] = v
+	}
+	for _, ch := range probe.Chapters {
+		c := Chapter{Title: ch.Tags.Title}
+		c.Start, _ = strconv.ParseFloat(ch.StartTime, 64)
+		c.End, _ = strconv.ParseFloat(ch.EndTime, 64)
+		info.Chapters = append(info.Chapters, c)
+	}
 	for _, s := range probe.Streams {
+		st := StreamInfo{
+			Index: s.Index, Type: s.CodecType, Codec: s.CodecName,
+			Language: s.Tags.Language, Title: s.Tags.Title,
+			Width: s.Width, Height: s.Height,
+			Channels: s.Channels, ChannelLayout: s.ChannelLayout,
+			Default: s.Disposition.Default == 1, Forced: s.Disposition.Forced == 1,
+			AttachedPic: s.Disposition.AttachedPic == 1,
+			PixelFormat: s.PixFmt, ColorSpace: s.ColorSpace,
+			ColorTransfer: s.ColorTransfer, ColorPrimaries: s.ColorPrimary,
+			FieldOrder: s.FieldOrder,
+		}
+		st.SampleRate, _ = strconv.Atoi(s.SampleRate)
+		if st.Language == "und" {
+			st.Language = ""
+		}
+		info.Streams = append(info.Streams, st)
 		switch s.CodecType {
 		case "video":
 			info.HasVideo = true
@@ -79,10 +138,15 @@ func parseFFprobeOutput(data []byte) (*MediaInfo, error) {
 			if s.Width > 0 && s.Height > 0 && info.Resolution == "" {
 				info.Resolution = fmt.Sprintf("%dx%d", s.Width, s.Height)
 			}
+			if v := defaultStream(info.Streams, "video"); v != nil && v.Index == st.Index {
+				info.HDR = st.HDR()
+			}
 		case "audio":
 			info.HasAudio = true
 			if info.AudioCodec == "" {
 				info.AudioCodec = s.CodecName
+				info.AudioChannels = s.Channels
+				info.AudioSampleRate, _ = strconv.Atoi(s.SampleRate)
 			}
 		}
 	}
@@ -171,42 +235,30 @@ func GenerateWaveform(ctx context.Context, ffmpegPath, inputPath string, barCoun
 }
 
 func DetectHardwareEncoder(ffmpegPath string) string {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	out, err := exec.CommandContext(ctx, ffmpegPath, "-encoders", "-v", "quiet").Output()
+	caps, err := ProbeCapabilities(ffmpegPath)
 	if err != nil {
 		return ""
 	}
-	s := string(out)
-	switch {
-	case strings.Contains(s, "h264_nvenc"):
-		return "nvenc"
-	case strings.Contains(s, "h264_qsv"):
-		return "qsv"
-	case strings.Contains(s, "h264_videotoolbox"):
-		return "videotoolbox"
-	default:
-		return ""
-	}
+	return DetectHWEncoders(ffmpegPath, caps, "auto").Accel()
 }
 
 func hwCodecName(accel string) string {
 	return HWEncoderCodec(accel)
 }
 
-// HWEncoderCodec converts an accel name ("nvenc", "qsv", "videotoolbox") to the
-// ffmpeg codec string, or "" if accel is empty/unknown (caller uses libx264).
+// HWEncoderCodec converts an accel name ("nvenc", "qsv", "vaapi", "amf",
+// "videotoolbox") to its H.264 encoder, or "" if accel is empty/unknown
+// (caller uses libx264).
 func HWEncoderCodec(accel string) string {
-	switch accel {
-	case "nvenc":
-		return "h264_nvenc"
-	case "qsv":
-		return "h264_qsv"
-	case "videotoolbox":
-		return "h264_videotoolbox"
-	default:
-		return ""
+	if accel == "cuda" {
+		accel = "nvenc"
+	}
+	for _, a := range HWAccels {
+		if a == accel {
+			return HWEncoderFor(accel, "h264")
+		}
 	}
+	return ""
 }
 
 // ─── Single-file Convert ──────────────────────────────────────────────────────
@@ -217,12 +269,14 @@ type ConvertOptions struct {
 	FFmpegPath    string
 	FFprobePath   string
 	VideoCodec    *string
+	VideoProfile  *string // ProRes or DNxHR profile, see IntermediateProfiles
 	AudioCodec    *string
 	VideoBitrate  *string
 	AudioBitrate  *string
 	CRF           *int
 	Preset        *string
-	FPS           *int
+	FPS           *string // output frame rate, a rational such as "30000/1001"
+	FPSMode       string  // one of FrameRateModes; "" is "drop"
 	RemoveAudio   bool
 	RemoveVideo   bool
 	TrimStart     *float64
@@ -243,6 +297,26 @@ type ConvertOptions struct {
 	Normalize     bool
 	Bass          *float64
 	Treble        *float64
+	// Loudness is the two-pass normalization target used when Normalize is
+	// set; nil means DefaultLoudnessTarget.
+	Loudness *LoudnessTarget
+	// OnLoudness (may be nil) receives the first-pass measurement.
+	OnLoudness func(LoudnessTarget, LoudnessMeasurement)
+	Channels   ChannelOptions
+	// Cleanup (may be nil) is the voice cleanup chain.
+	Cleanup *AudioCleanup
+	// Stabilize (may be nil) smooths camera shake; the video is re-encoded.
+	Stabilize *Stabilization
+	// Grade (may be nil) is the colour correction besides Brightness and
+	// Contrast.
+	Grade *ColorGrade
+	// Deinterlace (may be nil) makes interlaced video progressive.
+	Deinterlace *Deinterlace
+	// HDR (may be nil) tone maps or preserves an HDR source; nil leaves its
+	// colours as they are.
+	HDR *HDRHandling
+	// Streams (may be nil) replaces ffmpeg's default stream selection.
+	Streams *StreamSelection
 }
 
 func Convert(ctx context.Context, opts ConvertOptions, ph ProgressHandler) error {
@@ -256,22 +330,79 @@ func Convert(ctx context.Context, opts ConvertOptions, ph ProgressHandler) error
 	}
 
 	var totalDuration *float64
-	if info, err := GetMediaInfo(ctx, opts.FFprobePath, opts.InputPath); err == nil && info.Duration != nil {
+	info, probeErr := GetMediaInfo(ctx, opts.FFprobePath, opts.InputPath)
+	if probeErr == nil && info.Duration != nil {
 		totalDuration = info.Duration
 	}
 
 	// -ss BEFORE -i = keyframe-based input seeking (fast).
 	// -ss after -i would decode from the start (accurate but slow for large files).
-	args := []string{}
+	inputArgs := []string{}
 	if opts.TrimStart != nil {
-		args = append(args, "-ss", fmt.Sprintf("%.6f", *opts.TrimStart))
+		inputArgs = append(inputArgs, "-ss", fmt.Sprintf("%.6f", *opts.TrimStart))
 	}
-	args = append(args, "-i", opts.InputPath, "-progress", "pipe:1", "-v", "warning")
+	inputArgs = append(inputArgs, "-i", opts.InputPath)
 	if opts.TrimDuration != nil {
-		args = append(args, "-t", fmt.Sprintf("%.6f", *opts.TrimDuration))
+		inputArgs = append(inputArgs, "-t", fmt.Sprintf("%.6f", *opts.TrimDuration))
+	}
+	args := append([]string{}, inputArgs...)
+	args = append(args, "-progress", "pipe:1", "-v", "warning")
+	var measureMap []string
+	if opts.Streams != nil {
+		if probeErr != nil {
+			return fmt.Errorf("stream selection needs the input's streams: %w", probeErr)
+		}
+		mapArgs, kept, err := convertStreamArgs(opts, outputFormat, info.Streams)
+		if err != nil {
+			return err
+		}
+		args = append(args, mapArgs...)
+		if opts.Normalize && !opts.RemoveAudio {
+			st, err := LoudnessStream(kept)
+			if err != nil {
+				return err
+			}
+			if st != nil {
+				// Measure the selected track, not ffmpeg's default one.
+				measureMap = []string{"-map", fmt.Sprintf("0:%d", st.Index)}
+			}
+		}
 	}
 
 	// Video
+	var deinterlace []string
+	if opts.Deinterlace != nil && !opts.RemoveVideo {
+		interlaced := true
+		if opts.Deinterlace.Auto {
+			r, err := DetectInterlace(ctx, opts.FFmpegPath, inputArgs)
+			if err != nil {
+				return err
+			}
+			if opts.Deinterlace.OnDetect != nil {
+				opts.Deinterlace.OnDetect(*r)
+			}
+			interlaced = r.Interlaced()
+		}
+		if interlaced {
+			deinterlace = []string{opts.Deinterlace.filter()}
+		}
+	}
+	var stabilize []string
+	if opts.Stabilize != nil && !opts.RemoveVideo {
+		srcDur := totalDuration
+		if opts.TrimDuration != nil && *opts.TrimDuration > 0 {
+			srcDur = opts.TrimDuration
+		}
+		trf, err := opts.Stabilize.detect(ctx, opts.FFmpegPath, inputArgs, srcDur, progressRange(ph, 0, stabilizePassWeight))
+		if err != nil {
+			return err
+		}
+		if trf != "" {
+			defer os.Remove(trf)
+			ph = progressRange(ph, stabilizePassWeight, 1)
+		}
+		stabilize = opts.Stabilize.filters(trf)
+	}
 	if opts.RemoveVideo {
 		args = append(args, "-vn")
 	} else {
@@ -279,36 +410,47 @@ func Convert(ctx context.Context, opts ConvertOptions, ph ProgressHandler) error
 			args = append(args, "-c:v", *opts.VideoCodec)
 		}
 		preset := getPreset(opts)
-		if opts.VideoCodec != nil && (*opts.VideoCodec == "libx264" || *opts.VideoCodec == "libx265") {
-			args = append(args, "-preset", preset)
-		}
-		if opts.CRF != nil {
+		hwCodec := ""
+		if opts.VideoCodec != nil && HWAccelOf(*opts.VideoCodec) != "" {
+			hwCodec = *opts.VideoCodec
+			args = append(hwDeviceArgs(hwCodec), args...)
+			args = append(args, videoQualityArgs(hwCodec, opts.CRF, preset, opts.VideoBitrate != nil)...)
+		} else if opts.VideoCodec != nil && *opts.VideoCodec != "copy" {
+			args = append(args, softwareQualityArgs(*opts.VideoCodec, opts.CRF, preset, opts.VideoBitrate != nil)...)
+			args = append(args, intermediateArgs(*opts.VideoCodec, opts.VideoProfile)...)
+		} else if opts.CRF != nil {
 			args = append(args, "-crf", fmt.Sprintf("%d", *opts.CRF))
 		}
 		if opts.VideoBitrate != nil {
 			args = append(args, "-b:v", *opts.VideoBitrate)
 		}
-		if opts.FPS != nil {
-			args = append(args, "-r", fmt.Sprintf("%d", *opts.FPS))
+		// Deinterlacing and stabilization work on the source frames, so they
+		// come first.
+		vf := append(deinterlace, stabilize...)
+		if opts.HDR != nil && probeErr == nil {
+			if src := defaultStream(info.Streams, "video"); src != nil && src.HDR() != "" {
+				vf = append(vf, opts.HDR.filters(*src)...)
+				vCodec := ""
+				if opts.VideoCodec != nil {
+					vCodec = *opts.VideoCodec
+				}
+				args = append(args, opts.HDR.encoderArgs(*src, vCodec)...)
+			}
 		}
-		var vf []string
 		if opts.Speed != nil && *opts.Speed != 1.0 && *opts.Speed > 0 {
 			vf = append(vf, fmt.Sprintf("setpts=%.6f*PTS", 1.0/(*opts.Speed)))
 		}
+		if opts.FPS != nil {
+			vf = append(vf, frameRateFilter(*opts.FPS, opts.FPSMode))
+		}
 		if opts.ResizeWidth != nil || opts.ResizeHeight != nil {
 			if f := buildScaleFilter(opts); f != "" {
 				vf = append(vf, f)
 			}
 		}
-		var eq []string
-		if opts.Brightness != nil {
-			eq = append(eq, fmt.Sprintf("brightness=%f", *opts.Brightness))
-		}
-		if opts.Contrast != nil {
-			eq = append(eq, fmt.Sprintf("contrast=%f", *opts.Contrast))
-		}
-		if len(eq) > 0 {
-			vf = append(vf, "eq="+strings.Join(eq, ":"))
+		vf = append(vf, colorFilters(opts.Brightness, opts.Contrast, opts.Grade)...)
+		if up := hwUploadFilter(hwCodec); up != "" {
+			vf = append(vf, up)
 		}
 		if len(vf) > 0 {
 			args = append(args, "-vf", strings.Join(vf, ","))
@@ -319,10 +461,18 @@ func Convert(ctx context.Context, opts ConvertOptions, ph ProgressHandler) error
 	if opts.RemoveAudio {
 		args = append(args, "-an")
 	} else {
-		args = append(args, "-c:a", resolveAudioCodec(outputFormat, opts.AudioCodec))
-		// FLAC requires integer samples at supported rates; normalize to 44100 stereo
+		vCodec := ""
+		if opts.VideoCodec != nil && !opts.RemoveVideo {
+			vCodec = *opts.VideoCodec
+		}
+		args = append(args, resolveAudioCodecFor(outputFormat, vCodec, opts.AudioCodec)...)
+		// FLAC requires integer samples at supported rates; normalize to 44100
+		// stereo unless a channel operation sets the layout.
 		if outputFormat == "flac" {
-			args = append(args, "-ar", "44100", "-ac", "2")
+			args = append(args, "-ar", "44100")
+			if opts.Channels.Mode == "" {
+				args = append(args, "-ac", "2")
+			}
 		}
 		if opts.AudioBitrate != nil {
 			args = append(args, "-b:a", *opts.AudioBitrate)
@@ -337,7 +487,26 @@ func Convert(ctx context.Context, opts ConvertOptions, ph ProgressHandler) error
 		if opts.Speed != nil && *opts.Speed > 0 {
 			outDur /= *opts.Speed
 		}
-		afFilters := buildAudioFilterChain(opts.Volume, opts.Speed, opts.Normalize, opts.FadeIn, opts.FadeOut, opts.Bass, opts.Treble, outDur)
+		loudnorm := ""
+		if opts.Normalize {
+			target := DefaultLoudnessTarget
+			if opts.Loudness != nil {
+				target = *opts.Loudness
+			}
+			pre := buildAudioFilterChain(opts.Channels, opts.Cleanup, opts.Volume, opts.Speed, "", nil, nil, nil, nil, 0)
+			measureArgs := append(append(append([]string{}, inputArgs...), measureMap...), "-vn",
+				"-af", strings.Join(append(pre, target.measureFilter()), ","))
+			m, err := runLoudnessAnalysis(ctx, opts.FFmpegPath, measureArgs, &outDur, progressRange(ph, 0, loudnessPassWeight))
+			if err != nil {
+				return fmt.Errorf("loudness analysis: %w", err)
+			}
+			if opts.OnLoudness != nil {
+				opts.OnLoudness(target, *m)
+			}
+			loudnorm = target.normalizeFilter(m)
+			ph = progressRange(ph, loudnessPassWeight, 1)
+		}
+		afFilters := buildAudioFilterChain(opts.Channels, opts.Cleanup, opts.Volume, opts.Speed, loudnorm, opts.FadeIn, opts.FadeOut, opts.Bass, opts.Treble, outDur)
 		if len(afFilters) > 0 {
 			args = append(args, "-af", strings.Join(afFilters, ","))
 		}
@@ -364,6 +533,20 @@ type TimelineExportClip struct {
 	Duration    float64
 	HasVideo    bool
 	HasAudio    bool
+	Channels    ChannelOptions
+	Stabilize   *Stabilization // may be nil
+	Grade       *ColorGrade    // may be nil; before the timeline's own
+	// Reverse plays the clip backwards; see MaxReverseDuration.
+	Reverse bool
+	// Ramp (may be nil) varies the clip's speed; keyframes are sorted by Time.
+	Ramp []SpeedKeyframe
+	// Freeze (may be nil) holds one frame of the clip.
+	Freeze *FreezeFrame
+	// Animate (may be nil) keyframes the clip's properties.
+	Animate *ClipAnimation
+	// Width and Height are the source's frame size, which a zoom needs.
+	Width  int
+	Height int
 }
 
 type TimelineExportOptions struct {
@@ -372,6 +555,7 @@ type TimelineExportOptions struct {
 	FFmpegPath   string
 	FFprobePath  string
 	VideoCodec   *string
+	VideoProfile *string // see ConvertOptions.VideoProfile
 	AudioCodec   *string
 	VideoBitrate *string
 	AudioBitrate *string
@@ -399,6 +583,18 @@ type TimelineExportOptions struct {
 	Mode string
 	// HWEncoder is the detected hardware codec (e.g. "h264_nvenc"). Empty = use libx264.
 	HWEncoder string
+	// Loudness is the two-pass normalization target used when Normalize is
+	// set; nil means DefaultLoudnessTarget.
+	Loudness *LoudnessTarget
+	// OnLoudness (may be nil) receives the first-pass measurement.
+	OnLoudness func(LoudnessTarget, LoudnessMeasurement)
+	// Cleanup (may be nil) is the voice cleanup chain, run on each clip.
+	Cleanup *AudioCleanup
+	// Music (may be nil) is mixed under the timeline audio and ducked.
+	Music *MusicBed
+	// Grade (may be nil) is the colour correction besides Brightness and
+	// Contrast.
+	Grade *ColorGrade
 }
 
 // CanStreamCopy reports whether stream-copy is safe for this export (no filters/re-encode needed).
@@ -406,10 +602,15 @@ func CanStreamCopy(opts TimelineExportOptions) bool {
 	if opts.Mode == "precise" {
 		return false
 	}
+	// Intermediate codecs are asked for to hand off to grading or editing
+	// tools, where a copied delivery codec will not do.
+	if opts.VideoCodec != nil && IsIntermediateCodec(*opts.VideoCodec) {
+		return false
+	}
 	if opts.ResizeWidth != nil || opts.ResizeHeight != nil {
 		return false
 	}
-	if opts.Brightness != nil || opts.Contrast != nil {
+	if opts.Brightness != nil || opts.Contrast != nil || opts.Grade.IsSet() {
 		return false
 	}
 	if opts.Volume != nil {
@@ -433,6 +634,14 @@ func CanStreamCopy(opts TimelineExportOptions) bool {
 	if opts.Treble != nil && *opts.Treble != 0 {
 		return false
 	}
+	if opts.Cleanup.IsSet() || opts.Music != nil {
+		return false
+	}
+	for _, c := range opts.Clips {
+		if c.Channels.IsSet() || c.Stabilize != nil || c.Grade.IsSet() || c.retimed() || c.Animate.IsSet() {
+			return false
+		}
+	}
 	return true
 }
 
@@ -465,12 +674,11 @@ func timelineExportAudioOnly(ctx context.Context, opts TimelineExportOptions, ou
 	}
 	stage("preparing")
 
-	if len(opts.Clips) == 1 {
+	if len(opts.Clips) == 1 && opts.Music == nil && !opts.Clips[0].retimed() && !opts.Clips[0].Animate.IsSet() {
 		clip := opts.Clips[0]
 		if !clip.HasAudio {
 			return fmt.Errorf("selected clip has no audio stream")
 		}
-		stage("extracting audio")
 		outDur := clip.Duration
 		if opts.Speed != nil && *opts.Speed > 0 {
 			outDur /= *opts.Speed
@@ -483,14 +691,41 @@ func timelineExportAudioOnly(ctx context.Context, opts TimelineExportOptions, ou
 			"-vn",
 			"-c:a", audioCodec,
 		}
-		// FLAC requires integer samples at supported rates; normalize to 44100 stereo
+		// FLAC requires integer samples at supported rates; normalize to 44100
+		// stereo unless a channel operation sets the layout.
 		if outputFormat == "flac" {
-			args = append(args, "-ar", "44100", "-ac", "2")
+			args = append(args, "-ar", "44100")
+			if clip.Channels.Mode == "" {
+				args = append(args, "-ac", "2")
+			}
 		}
 		if opts.AudioBitrate != nil {
 			args = append(args, "-b:a", *opts.AudioBitrate)
 		}
-		afFilters := buildAudioFilterChain(opts.Volume, opts.Speed, opts.Normalize, opts.FadeIn, opts.FadeOut, opts.Bass, opts.Treble, outDur)
+		loudnorm := ""
+		if opts.Normalize {
+			stage("analyzing loudness")
+			target := opts.loudnessTarget()
+			pre := buildAudioFilterChain(clip.Channels, opts.Cleanup, opts.Volume, opts.Speed, "", nil, nil, nil, nil, 0)
+			measureArgs := []string{
+				"-ss", fmt.Sprintf("%.6f", clip.SourceStart),
+				"-t", fmt.Sprintf("%.6f", clip.Duration),
+				"-i", clip.FilePath,
+				"-vn",
+				"-af", strings.Join(append(pre, target.measureFilter()), ","),
+			}
+			m, err := runLoudnessAnalysis(ctx, opts.FFmpegPath, measureArgs, &outDur, progressRange(ph, 0, loudnessPassWeight))
+			if err != nil {
+				return fmt.Errorf("loudness analysis: %w", err)
+			}
+			if opts.OnLoudness != nil {
+				opts.OnLoudness(target, *m)
+			}
+			loudnorm = target.normalizeFilter(m)
+			ph = progressRange(ph, loudnessPassWeight, 1)
+		}
+		stage("extracting audio")
+		afFilters := buildAudioFilterChain(clip.Channels, opts.Cleanup, opts.Volume, opts.Speed, loudnorm, opts.FadeIn, opts.FadeOut, opts.Bass, opts.Treble, outDur)
 		if len(afFilters) > 0 {
 			args = append(args, "-af", strings.Join(afFilters, ","))
 		}
@@ -498,54 +733,21 @@ func timelineExportAudioOnly(ctx context.Context, opts TimelineExportOptions, ou
 		return runFFmpeg(ctx, opts.FFmpegPath, args, &outDur, ph)
 	}
 
-	totalDuration := 0.0
-	for _, c := range opts.Clips {
-		totalDuration += c.Duration
-	}
-
-	args := []string{}
-	for _, clip := range opts.Clips {
-		args = append(args,
-			"-ss", fmt.Sprintf("%.6f", clip.SourceStart),
-			"-t", fmt.Sprintf("%.6f", clip.Duration),
-			"-i", clip.FilePath,
-		)
-	}
-
-	var fc, concatA strings.Builder
-	for i, clip := range opts.Clips {
-		if clip.HasAudio {
-			clipFilters := []string{"aresample=44100", "aformat=channel_layouts=stereo"}
-			if opts.Volume != nil && *opts.Volume != 1.0 {
-				clipFilters = append(clipFilters, fmt.Sprintf("volume=%f", *opts.Volume))
-			}
-			if opts.Speed != nil && *opts.Speed != 1.0 && *opts.Speed > 0 {
-				clipFilters = append(clipFilters, buildAtempoChain(*opts.Speed)...)
-			}
-			fmt.Fprintf(&fc, "[%d:a]%s[a%d];", i, strings.Join(clipFilters, ","), i)
-		} else {
-			silenceDur := clip.Duration
-			if opts.Speed != nil && *opts.Speed > 0 {
-				silenceDur /= *opts.Speed
-			}
-			fmt.Fprintf(&fc, "anullsrc=r=44100:cl=stereo:d=%.3f[a%d];", silenceDur, i)
-		}
-		fmt.Fprintf(&concatA, "[a%d]", i)
-	}
-
 	// Apply global post-concat audio effects (normalize, fade, bass, treble)
-	totalOutputDuration := totalDuration
-	if opts.Speed != nil && *opts.Speed > 0 {
-		totalOutputDuration /= *opts.Speed
+	totalOutputDuration := opts.outputDuration()
+	loudnorm, err := measureTimelineLoudness(ctx, opts, true, totalOutputDuration, ph, stage)
+	if err != nil {
+		return err
 	}
-	globalFilters := buildGlobalAudioFilters(opts.Normalize, opts.FadeIn, opts.FadeOut, opts.Bass, opts.Treble, totalOutputDuration)
-	if len(globalFilters) > 0 {
-		fmt.Fprintf(&fc, "%sconcat=n=%d:v=0:a=1[outa_pre];[outa_pre]%s[outa]", concatA.String(), len(opts.Clips), strings.Join(globalFilters, ","))
-	} else {
-		fmt.Fprintf(&fc, "%sconcat=n=%d:v=0:a=1[outa]", concatA.String(), len(opts.Clips))
+	if loudnorm != "" {
+		ph = progressRange(ph, loudnessPassWeight, 1)
 	}
+	globalFilters := buildGlobalAudioFilters(loudnorm, opts.FadeIn, opts.FadeOut, opts.Bass, opts.Treble, totalOutputDuration)
+
+	args := timelineInputArgs(opts)
+	fc := buildTimelineAudioGraph(opts, true, globalFilters)
 
-	args = append(args, "-filter_complex", fc.String(), "-map", "[outa]")
+	args = append(args, "-filter_complex", fc, "-map", "[outa]")
 	args = append(args, "-c:a", resolveAudioCodec(outputFormat, opts.AudioCodec))
 	if opts.AudioBitrate != nil {
 		args = append(args, "-b:a", *opts.AudioBitrate)
@@ -639,7 +841,7 @@ func timelineExportFast(ctx context.Context, opts TimelineExportOptions, ph Prog
 
 		done += clip.Duration
 		if ph != nil && totalDuration > 0 {
-			ph((done/totalDuration)*0.85, 1.0, done*1000)
+			ph(ProgressEvent{Progress: (done / totalDuration) * 0.85, OutTimeMs: done * 1e6})
 		}
 	}
 
@@ -674,9 +876,9 @@ func timelineExportFast(ctx context.Context, opts TimelineExportOptions, ph Prog
 
 	var concatPH ProgressHandler
 	if ph != nil && totalDuration > 0 {
-		concatPH = func(_, _ float64, outTimeMs float64) {
-			p := (outTimeMs / 1000.0) / totalDuration
-			ph(0.85+p*0.15, 1.0, outTimeMs)
+		concatPH = func(ev ProgressEvent) {
+			ev.Progress = 0.85 + clamp01(ev.OutTimeMs/1e6/totalDuration)*0.15
+			ph(ev)
 		}
 	}
 
@@ -689,20 +891,9 @@ func timelineExportFast(ctx context.Context, opts TimelineExportOptions, ph Prog
 func timelineExportReencode(ctx context.Context, opts TimelineExportOptions, ph ProgressHandler, onStage func(string)) error {
 	onStage("preparing")
 
-	totalDuration := 0.0
-	for _, c := range opts.Clips {
-		totalDuration += c.Duration
-	}
+	totalDuration := opts.outputDuration()
 
-	// -ss / -t before each -i for fast input seeking.
-	args := []string{"-progress", "pipe:1", "-v", "warning"}
-	for _, clip := range opts.Clips {
-		args = append(args,
-			"-ss", fmt.Sprintf("%.6f", clip.SourceStart),
-			"-t", fmt.Sprintf("%.6f", clip.Duration),
-			"-i", clip.FilePath,
-		)
-	}
+	args := append([]string{"-progress", "pipe:1", "-v", "warning"}, timelineInputArgs(opts)...)
 
 	n := len(opts.Clips)
 	hasAudio := !opts.RemoveAudio
@@ -734,116 +925,74 @@ func timelineExportReencode(ctx context.Context, opts TimelineExportOptions, ph
 			vfParts = append(vfParts, fmt.Sprintf("scale=%d:%d", w, h))
 		}
 	}
-	var eq []string
-	if opts.Brightness != nil {
-		eq = append(eq, fmt.Sprintf("brightness=%f", *opts.Brightness))
+	vfParts = append(vfParts, colorFilters(opts.Brightness, opts.Contrast, opts.Grade)...)
+
+	stabilize, trfs, err := stabilizeClips(ctx, opts, ph, onStage)
+	for _, trf := range trfs {
+		defer os.Remove(trf)
+	}
+	if err != nil {
+		return err
+	}
+	if len(trfs) > 0 {
+		ph = progressRange(ph, stabilizePassWeight, 1)
 	}
-	if opts.Contrast != nil {
-		eq = append(eq, fmt.Sprintf("contrast=%f", *opts.Contrast))
+	animate, scripts, err := animateClips(opts)
+	for _, script := range scripts {
+		defer os.Remove(script)
 	}
-	if len(eq) > 0 {
-		vfParts = append(vfParts, "eq="+strings.Join(eq, ":"))
+	if err != nil {
+		return err
 	}
 
-	var fc, concatV, concatA strings.Builder
-	for i := range opts.Clips {
-		if len(vfParts) > 0 {
-			fmt.Fprintf(&fc, "[%d:v]%s[v%d];", i, strings.Join(vfParts, ","), i)
-		} else {
-			fmt.Fprintf(&fc, "[%d:v]null[v%d];", i, i)
-		}
+	var fc, concatV strings.Builder
+	for i, clip := range opts.Clips {
+		// Clip corrections work on the source frames, before the clip is
+		// retimed and before the timeline-wide filters.
+		pre := append(stabilize[i], colorFilters(nil, nil, clip.Grade)...)
+		pre = append(pre, animate[i]...)
+		clip.clipChain(&fc, false, fmt.Sprintf("[%d:v]", i), fmt.Sprintf("[v%d]", i), pre, vfParts)
 		fmt.Fprintf(&concatV, "[v%d]", i)
-
-		if hasAudio {
-			if opts.Clips[i].HasAudio {
-				var clipAF []string
-				if opts.Volume != nil && *opts.Volume != 1.0 {
-					clipAF = append(clipAF, fmt.Sprintf("volume=%f", *opts.Volume))
-				}
-				if opts.Speed != nil && *opts.Speed != 1.0 && *opts.Speed > 0 {
-					clipAF = append(clipAF, buildAtempoChain(*opts.Speed)...)
-				}
-				if len(clipAF) > 0 {
-					fmt.Fprintf(&fc, "[%d:a]%s[a%d];", i, strings.Join(clipAF, ","), i)
-				} else {
-					fmt.Fprintf(&fc, "[%d:a]anull[a%d];", i, i)
-				}
-			} else {
-				silenceDur := opts.Clips[i].Duration
-				if opts.Speed != nil && *opts.Speed > 0 {
-					silenceDur /= *opts.Speed
-				}
-				fmt.Fprintf(&fc, "anullsrc=r=44100:cl=stereo:d=%.3f[a%d];", silenceDur, i)
-			}
-			fmt.Fprintf(&concatA, "[a%d]", i)
-		}
 	}
+	fmt.Fprintf(&fc, "%sconcat=n=%d:v=1:a=0[outv]", concatV.String(), n)
 
-	// Apply global post-concat audio effects (normalize, fade, bass, treble)
-	totalOutputDuration := totalDuration
-	if opts.Speed != nil && *opts.Speed > 0 {
-		totalOutputDuration /= *opts.Speed
+	if hasAudio {
+		// Apply global post-concat audio effects (normalize, fade, bass, treble)
+		loudnorm, err := measureTimelineLoudness(ctx, opts, false, totalDuration, ph, onStage)
+		if err != nil {
+			return err
+		}
+		if loudnorm != "" {
+			ph = progressRange(ph, loudnessPassWeight, 1)
+		}
+		globalAF := buildGlobalAudioFilters(loudnorm, opts.FadeIn, opts.FadeOut, opts.Bass, opts.Treble, totalDuration)
+		fc.WriteString(";" + buildTimelineAudioGraph(opts, false, globalAF))
 	}
-	globalAF := buildGlobalAudioFilters(opts.Normalize, opts.FadeIn, opts.FadeOut, opts.Bass, opts.Treble, totalOutputDuration)
 
-	if hasAudio && len(globalAF) > 0 {
-		fmt.Fprintf(&fc, "%sconcat=n=%d:v=1:a=0[outv];%sconcat=n=%d:v=0:a=1[outa_pre];[outa_pre]%s[outa]",
-			concatV.String(), n, concatA.String(), n, strings.Join(globalAF, ","))
-	} else if hasAudio {
-		fmt.Fprintf(&fc, "%sconcat=n=%d:v=1:a=0[outv];%sconcat=n=%d:v=0:a=1[outa]", concatV.String(), n, concatA.String(), n)
-	} else {
-		fmt.Fprintf(&fc, "%sconcat=n=%d:v=1:a=0[outv]", concatV.String(), n)
+	outputFormat := strings.TrimPrefix(strings.ToLower(filepath.Ext(opts.OutputPath)), ".")
+	vCodec := VideoEncoderFor(outputFormat, opts.VideoCodec, opts.HWEncoder)
+	outV := "[outv]"
+	if up := hwUploadFilter(vCodec); up != "" {
+		fc.WriteString(";[outv]" + up + "[outvhw]")
+		outV = "[outvhw]"
 	}
+	args = append(hwDeviceArgs(vCodec), args...)
 
-	args = append(args, "-filter_complex", fc.String(), "-map", "[outv]")
+	args = append(args, "-filter_complex", fc.String(), "-map", outV)
 	if hasAudio {
 		args = append(args, "-map", "[outa]")
 	}
-
-	// Output video codec: explicit user choice > hw encoder > libx264.
-	vCodec := "libx264"
-	if opts.HWEncoder != "" {
-		vCodec = opts.HWEncoder
-	}
-	if opts.VideoCodec != nil && *opts.VideoCodec != "" && *opts.VideoCodec != "copy" {
-		vCodec = *opts.VideoCodec
-	}
 	args = append(args, "-c:v", vCodec)
-
 	// Preset and quality flags differ per encoder family.
-	switch vCodec {
-	case "h264_nvenc", "hevc_nvenc":
-		args = append(args, "-preset", "p4") // p1=fastest … p7=best quality
-		if opts.CRF != nil {
-			args = append(args, "-cq", fmt.Sprintf("%d", *opts.CRF))
-		}
-	case "h264_qsv", "hevc_qsv":
-		args = append(args, "-preset", "fast")
-		if opts.CRF != nil {
-			args = append(args, "-global_quality", fmt.Sprintf("%d", *opts.CRF))
-		}
-	case "h264_videotoolbox":
-		// videotoolbox doesn't support CRF; rely on bitrate or default quality.
-		if opts.VideoBitrate == nil {
-			args = append(args, "-b:v", "8000k")
-		}
-	default: // libx264, libx265
-		args = append(args, "-preset", getPresetFromMode(opts.Preset, opts.PresetMode))
-		if opts.CRF != nil {
-			args = append(args, "-crf", fmt.Sprintf("%d", *opts.CRF))
-		}
-	}
+	args = append(args, videoQualityArgs(vCodec, opts.CRF, getPresetFromMode(opts.Preset, opts.PresetMode), opts.VideoBitrate != nil)...)
+	args = append(args, intermediateArgs(vCodec, opts.VideoProfile)...)
 	if opts.VideoBitrate != nil {
 		args = append(args, "-b:v", *opts.VideoBitrate)
 	}
 
 	// Output audio codec.
 	if hasAudio {
-		aCodec := "aac"
-		if opts.AudioCodec != nil && *opts.AudioCodec != "copy" {
-			aCodec = *opts.AudioCodec
-		}
-		args = append(args, "-c:a", aCodec)
+		args = append(args, resolveAudioCodecFor(outputFormat, vCodec, opts.AudioCodec)...)
 		if opts.AudioBitrate != nil {
 			args = append(args, "-b:a", *opts.AudioBitrate)
 		}
@@ -864,6 +1013,129 @@ func timelineExportReencode(ctx context.Context, opts TimelineExportOptions, ph
 	return nil
 }
 
+// timelineInputArgs returns one input per clip, with -ss / -t before each -i
+// for fast input seeking, then the music bed.
+func timelineInputArgs(opts TimelineExportOptions) []string {
+	var args []string
+	for _, clip := range opts.Clips {
+		args = append(args,
+			"-ss", fmt.Sprintf("%.6f", clip.SourceStart),
+			"-t", fmt.Sprintf("%.6f", clip.Duration),
+			"-i", clip.FilePath,
+		)
+	}
+	if opts.Music != nil {
+		args = append(args, opts.Music.inputArgs()...)
+	}
+	return args
+}
+
+// audioLayout is the channel layout of a resampled timeline: 5.1 when any
+// clip is upmixed to it, mono when every clip with audio is downmixed to
+// mono, else stereo.
+func (opts TimelineExportOptions) audioLayout() string {
+	mono := false
+	for _, clip := range opts.Clips {
+		if !clip.HasAudio {
+			continue
+		}
+		switch clip.Channels.Mode {
+		case "5.1":
+			return "5.1"
+		case "mono":
+			mono = true
+		default:
+			return "stereo"
+		}
+	}
+	if mono {
+		return "mono"
+	}
+	return "stereo"
+}
+
+// buildTimelineAudioGraph builds the audio half of a timeline filter_complex:
+// per-clip volume/speed (or silence for clips without audio), concat, then
+// the music bed, if any, then globalAF, ending in [outa]. resample brings
+// every clip to 44.1 kHz in the timeline's audioLayout so mixed sources
+// concat cleanly.
+func buildTimelineAudioGraph(opts TimelineExportOptions, resample bool, globalAF []string) string {
+	var fc, concatA strings.Builder
+	layout := "stereo"
+	if resample {
+		layout = opts.audioLayout()
+	}
+	for i, clip := range opts.Clips {
+		if clip.HasAudio {
+			clipAF := append(clip.Channels.filters(), opts.Cleanup.filters()...)
+			clipAF = append(clipAF, clip.Animate.volumeFilters()...)
+			if resample {
+				clipAF = append(clipAF, "aresample=44100", "aformat=channel_layouts="+layout)
+			}
+			if opts.Volume != nil && *opts.Volume != 1.0 {
+				clipAF = append(clipAF, fmt.Sprintf("volume=%f", *opts.Volume))
+			}
+			var post []string
+			if opts.Speed != nil && *opts.Speed != 1.0 && *opts.Speed > 0 {
+				post = buildAtempoChain(*opts.Speed)
+			}
+			clip.clipChain(&fc, true, fmt.Sprintf("[%d:a]", i), fmt.Sprintf("[a%d]", i), clipAF, post)
+		} else {
+			silenceDur := clip.OutputDuration()
+			if opts.Speed != nil && *opts.Speed > 0 {
+				silenceDur /= *opts.Speed
+			}
+			fmt.Fprintf(&fc, "anullsrc=r=44100:cl=%s:d=%.3f[a%d];", layout, silenceDur, i)
+		}
+		fmt.Fprintf(&concatA, "[a%d]", i)
+	}
+	out := "[outa]"
+	if len(globalAF) > 0 {
+		out = "[outa_pre]"
+	}
+	if opts.Music != nil {
+		fmt.Fprintf(&fc, "%sconcat=n=%d:v=0:a=1[outa_cat];", concatA.String(), len(opts.Clips))
+		fc.WriteString(opts.Music.mixGraph(len(opts.Clips), "[outa_cat]", out))
+	} else {
+		fmt.Fprintf(&fc, "%sconcat=n=%d:v=0:a=1%s", concatA.String(), len(opts.Clips), out)
+	}
+	if len(globalAF) > 0 {
+		fmt.Fprintf(&fc, ";[outa_pre]%s[outa]", strings.Join(globalAF, ","))
+	}
+	return fc.String()
+}
+
+func (opts TimelineExportOptions) loudnessTarget() LoudnessTarget {
+	if opts.Loudness != nil {
+		return *opts.Loudness
+	}
+	return DefaultLoudnessTarget
+}
+
+// measureTimelineLoudness runs the analysis pass of two-pass normalization
+// over the concatenated timeline audio and returns the second-pass loudnorm
+// filter, or "" when Normalize is off.
+func measureTimelineLoudness(ctx context.Context, opts TimelineExportOptions, resample bool, outputDuration float64, ph ProgressHandler, onStage func(string)) (string, error) {
+	if !opts.Normalize {
+		return "", nil
+	}
+	onStage("analyzing loudness")
+	target := opts.loudnessTarget()
+	args := timelineInputArgs(opts)
+	args = append(args,
+		"-filter_complex", buildTimelineAudioGraph(opts, resample, []string{target.measureFilter()}),
+		"-map", "[outa]",
+	)
+	m, err := runLoudnessAnalysis(ctx, opts.FFmpegPath, args, &outputDuration, progressRange(ph, 0, loudnessPassWeight))
+	if err != nil {
+		return "", fmt.Errorf("loudness analysis: %w", err)
+	}
+	if opts.OnLoudness != nil {
+		opts.OnLoudness(target, *m)
+	}
+	return target.normalizeFilter(m), nil
+}
+
 // ─── Merge (multi-file, always re-encodes) ────────────────────────────────────
 
 type mergeClipInfo struct {
@@ -951,22 +1223,20 @@ func Merge(ctx context.Context, opts MergeOptions, ph ProgressHandler) error {
 	}
 	fmt.Fprintf(&fc, "%sconcat=n=%d:v=1:a=1[outv][outa]", concatInputs.String(), len(opts.InputPaths))
 
-	vCodec := "libx264"
-	if opts.HWEncoder != "" {
-		vCodec = opts.HWEncoder
+	outputFormat := strings.TrimPrefix(strings.ToLower(filepath.Ext(opts.OutputPath)), ".")
+	vCodec := VideoEncoderFor(outputFormat, nil, opts.HWEncoder)
+	outV := "[outv]"
+	if up := hwUploadFilter(vCodec); up != "" {
+		fmt.Fprintf(&fc, ";[outv]%s[outvhw]", up)
+		outV = "[outvhw]"
 	}
+	args = append(hwDeviceArgs(vCodec), args...)
 	args = append(args, "-filter_complex", fc.String())
-	args = append(args, "-map", "[outv]", "-map", "[outa]")
+	args = append(args, "-map", outV, "-map", "[outa]")
 	args = append(args, "-c:v", vCodec)
-	switch vCodec {
-	case "h264_nvenc", "hevc_nvenc":
-		args = append(args, "-preset", "p4", "-cq", "25")
-	case "h264_qsv", "hevc_qsv":
-		args = append(args, "-preset", "fast", "-global_quality", "25")
-	default:
-		args = append(args, "-preset", "veryfast", "-crf", "25")
-	}
-	args = append(args, "-c:a", "aac", "-b:a", "128k", "-shortest", "-y", opts.OutputPath)
+	crf := 25
+	args = append(args, videoQualityArgs(vCodec, &crf, "veryfast", false)...)
+	args = append(args, "-c:a", defaultAudioCodecForFormat(outputFormat), "-b:a", "128k", "-shortest", "-y", opts.OutputPath)
 
 	return runFFmpeg(ctx, opts.FFmpegPath, args, &totalDuration, ph)
 }
@@ -1061,55 +1331,92 @@ func mergeStreamCopy(ctx context.Context, opts MergeOptions, totalDuration float
 // runFFmpeg starts an FFmpeg process with -progress pipe:1, drains stderr safely,
 // and feeds progress events to ph. Safe to cancel via ctx.
 func runFFmpeg(ctx context.Context, ffmpegPath string, args []string, totalDuration *float64, ph ProgressHandler) error {
+	_, err := runFFmpegCapture(ctx, ffmpegPath, args, totalDuration, ph)
+	return err
+}
+
+// runFFmpegCapture is runFFmpeg but also returns the tail of stderr, for
+// filters that report their results there (loudnorm, ...).
+func runFFmpegCapture(ctx context.Context, ffmpegPath string, args []string, totalDuration *float64, ph ProgressHandler) (string, error) {
+	return runFFmpegScan(ctx, ffmpegPath, args, totalDuration, ph, nil)
+}
+
+// runFFmpegScan is runFFmpegCapture but also hands every stderr line to onLine
+// (may be nil), for analysers such as silencedetect whose output can be far
+// longer than the retained tail.
+func runFFmpegScan(ctx context.Context, ffmpegPath string, args []string, totalDuration *float64, ph ProgressHandler, onLine func(string)) (string, error) {
 	cmd := exec.CommandContext(ctx, ffmpegPath, args...)
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return fmt.Errorf("stdout pipe: %w", err)
+		return "", fmt.Errorf("stdout pipe: %w", err)
 	}
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
-		return fmt.Errorf("stderr pipe: %w", err)
+		return "", fmt.Errorf("stderr pipe: %w", err)
 	}
 
 	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("ffmpeg start: %w", err)
+		return "", fmt.Errorf("ffmpeg start: %w", err)
 	}
 
-	// Drain stderr in background; cap at 64 KB to avoid unbounded growth.
-	var errBuf bytes.Buffer
+	// Drain stderr in background; keep only the last 64 KB to avoid unbounded growth.
+	errBuf := &tailBuffer{max: 64 * 1024}
 	errDone := make(chan struct{})
 	go func() {
-		io.Copy(&errBuf, io.LimitReader(stderr, 64*1024))
-		close(errDone)
+		defer close(errDone)
+		if onLine == nil {
+			io.Copy(errBuf, stderr)
+			return
+		}
+		r := bufio.NewReader(stderr)
+		for {
+			line, err := r.ReadString('\n')
+			if line != "" {
+				errBuf.Write([]byte(line))
+				onLine(strings.TrimRight(line, "\r\n"))
+			}
+			if err != nil {
+				return
+			}
+		}
 	}()
 
+	progress := &progressParser{totalDuration: totalDuration}
 	scanner := bufio.NewScanner(stdout)
 	for scanner.Scan() {
-		line := scanner.Text()
-		if ph != nil && strings.HasPrefix(line, "out_time_ms=") {
-			val := strings.TrimPrefix(line, "out_time_ms=")
-			if ms, err := strconv.ParseFloat(val, 64); err == nil && ms >= 0 {
-				if totalDuration != nil && *totalDuration > 0 {
-					ph((ms/1000.0)/(*totalDuration), 1.0, ms)
-				}
-			}
+		if ev, ok := progress.parseLine(scanner.Text()); ok && ph != nil {
+			ph(ev)
 		}
 	}
 	// Ignore scanner.Err() — partial reads are fine; cmd.Wait catches real failures.
 
-	waitErr := cmd.Wait()
 	<-errDone
+	waitErr := cmd.Wait()
 
+	text := strings.TrimSpace(errBuf.String())
 	if waitErr != nil {
-		if text := strings.TrimSpace(errBuf.String()); text != "" {
-			return fmt.Errorf("ffmpeg: %w: %s", waitErr, text)
-		}
-		return fmt.Errorf("ffmpeg: %w", waitErr)
+		return text, newError(ctx, waitErr, text)
 	}
-	return nil
+	return text, nil
 }
 
+// tailBuffer is an io.Writer that keeps only the last max bytes written.
+type tailBuffer struct {
+	buf []byte
+	max int
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if over := len(t.buf) - t.max; over > 0 {
+		t.buf = append(t.buf[:0], t.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string { return string(t.buf) }
+
 func getPreset(opts ConvertOptions) string {
 	return getPresetFromMode(opts.Preset, opts.PresetMode)
 }
@@ -1133,7 +1440,7 @@ func defaultAudioCodecForFormat(format string) string {
 		return "pcm_s16le"
 	case "flac":
 		return "flac"
-	case "ogg":
+	case "ogg", "webm":
 		return "libopus"
 	default:
 		return "aac"
@@ -1215,10 +1522,11 @@ func buildAtempoChain(speed float64) []string {
 }
 
 // buildGlobalAudioFilters builds post-concat audio effects applied to the whole output.
-func buildGlobalAudioFilters(normalize bool, fadeIn, fadeOut, bass, treble *float64, outputDuration float64) []string {
+// loudnorm is the normalization filter (see LoudnessTarget.normalizeFilter), or "" for none.
+func buildGlobalAudioFilters(loudnorm string, fadeIn, fadeOut, bass, treble *float64, outputDuration float64) []string {
 	var filters []string
-	if normalize {
-		filters = append(filters, "loudnorm")
+	if loudnorm != "" {
+		filters = append(filters, loudnorm)
 	}
 	if fadeIn != nil && *fadeIn > 0 {
 		filters = append(filters, fmt.Sprintf("afade=t=in:st=0:d=%.3f", *fadeIn))
@@ -1236,16 +1544,18 @@ func buildGlobalAudioFilters(normalize bool, fadeIn, fadeOut, bass, treble *floa
 	return filters
 }
 
-// buildAudioFilterChain builds the complete -af filter string for single-file operations.
-func buildAudioFilterChain(volume, speed *float64, normalize bool, fadeIn, fadeOut, bass, treble *float64, outputDuration float64) []string {
-	var filters []string
+// buildAudioFilterChain builds the complete -af filter string for single-file
+// operations. Channel operations come first, so the effects see the final
+// layout, then the cleanup chain.
+func buildAudioFilterChain(ch ChannelOptions, cleanup *AudioCleanup, volume, speed *float64, loudnorm string, fadeIn, fadeOut, bass, treble *float64, outputDuration float64) []string {
+	filters := append(ch.filters(), cleanup.filters()...)
 	if volume != nil && math.Abs(*volume-1.0) > 0.001 {
 		filters = append(filters, fmt.Sprintf("volume=%f", *volume))
 	}
 	if speed != nil && *speed != 1.0 && *speed > 0 {
 		filters = append(filters, buildAtempoChain(*speed)...)
 	}
-	filters = append(filters, buildGlobalAudioFilters(normalize, fadeIn, fadeOut, bass, treble, outputDuration)...)
+	filters = append(filters, buildGlobalAudioFilters(loudnorm, fadeIn, fadeOut, bass, treble, outputDuration)...)
 	return filters
 }
 
@@ -2,9 +2,13 @@ package ffmpeg_test
 
 import (
 	"context"
+	"fmt"
+	"math"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -99,6 +103,51 @@ func TestConvert_WebM_vp9(t *testing.T) {
 	assertOutput(t, out)
 }
 
+func TestConvert_MP4_SVTAV1(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.mp4")
+	c, cancel := mkctx(); defer cancel()
+	o := convertBase(testData("v1.mp4"), out)
+	o.VideoCodec, o.CRF, o.Preset = pstr("libsvtav1"), pint(40), pstr("ultrafast")
+	if err := ffmpeg.Convert(c, o, nil); err != nil { t.Fatal(err) }
+	assertOutput(t, out)
+}
+
+func TestConvert_WebM_AOMAV1_DefaultOpus(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.webm")
+	c, cancel := mkctx(); defer cancel()
+	o := convertBase(testData("v1.mp4"), out)
+	o.VideoCodec, o.CRF, o.Preset, o.TrimDuration = pstr("libaom-av1"), pint(45), pstr("ultrafast"), pf64(1.0)
+	if err := ffmpeg.Convert(c, o, nil); err != nil { t.Fatal(err) }
+	assertOutput(t, out)
+}
+
+func TestConvert_MOV_ProRes_PCM(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.mov")
+	c, cancel := mkctx(); defer cancel()
+	o := convertBase(testData("v1.mp4"), out)
+	o.VideoCodec, o.VideoProfile, o.TrimDuration = pstr("prores_ks"), pstr("proxy"), pf64(1.0)
+	if err := ffmpeg.Convert(c, o, nil); err != nil { t.Fatal(err) }
+	assertOutput(t, out)
+}
+
+func TestConvert_MXF_DNxHR(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.mxf")
+	c, cancel := mkctx(); defer cancel()
+	o := convertBase(testData("v1.mp4"), out)
+	o.VideoCodec, o.VideoProfile, o.TrimDuration = pstr("dnxhd"), pstr("lb"), pf64(1.0)
+	if err := ffmpeg.Convert(c, o, nil); err != nil { t.Fatal(err) }
+	assertOutput(t, out)
+}
+
+func TestConvert_MKV_FFV1(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.mkv")
+	c, cancel := mkctx(); defer cancel()
+	o := convertBase(testData("v1.mp4"), out)
+	o.VideoCodec, o.TrimDuration = pstr("ffv1"), pf64(1.0)
+	if err := ffmpeg.Convert(c, o, nil); err != nil { t.Fatal(err) }
+	assertOutput(t, out)
+}
+
 func TestConvert_AVI_x264(t *testing.T) {
 	out := filepath.Join(t.TempDir(), "out.avi")
 	c, cancel := mkctx(); defer cancel()
@@ -108,6 +157,22 @@ func TestConvert_AVI_x264(t *testing.T) {
 	assertOutput(t, out)
 }
 
+func TestConvert_StreamSelection(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.mkv")
+	c, cancel := mkctx(); defer cancel()
+	o := convertBase(testData("v1.mp4"), out)
+	o.VideoCodec, o.AudioCodec = pstr("copy"), pstr("copy")
+	o.Streams = &ffmpeg.StreamSelection{AllAudio: true, Include: []ffmpeg.StreamSelector{{Type: "video"}},
+		Metadata: []ffmpeg.StreamMetadata{{Index: 1, Language: pstr("eng"), Title: pstr("Main mix")}}}
+	if err := ffmpeg.Convert(c, o, nil); err != nil { t.Fatal(err) }
+	_, fp := bin()
+	info, err := ffmpeg.GetMediaInfo(c, fp, out)
+	if err != nil { t.Fatal(err) }
+	var audio *ffmpeg.StreamInfo
+	for i := range info.Streams { if info.Streams[i].Type == "audio" { audio = &info.Streams[i]; break } }
+	if audio == nil || audio.Language != "eng" || audio.Title != "Main mix" { t.Errorf("audio stream: %+v", audio) }
+}
+
 // ─── Convert: audio-only formats ─────────────────────────────────────────────
 
 func TestConvert_MP3(t *testing.T) {
@@ -219,6 +284,179 @@ func TestConvert_AudioEffects(t *testing.T) {
 	assertOutput(t, out)
 }
 
+func TestConvert_ChannelsMono(t *testing.T) {
+	ff, fp := bin()
+	out := filepath.Join(t.TempDir(), "mono.m4a")
+	c, cancel := mkctx(); defer cancel()
+	err := ffmpeg.Convert(c, ffmpeg.ConvertOptions{
+		InputPath: testData("v1.mp4"), OutputPath: out,
+		FFmpegPath: ff, FFprobePath: fp, TrimDuration: pf64(2.0),
+		Channels: ffmpeg.ChannelOptions{Mode: "mono"},
+	}, nil)
+	if err != nil { t.Fatal(err) }
+	info, _ := ffmpeg.GetMediaInfo(c, fp, out)
+	if info == nil || info.AudioChannels != 1 { t.Fatalf("want 1 channel, got %+v", info) }
+}
+
+func TestConvert_ChannelsSwapPan(t *testing.T) {
+	ff, fp := bin()
+	out := filepath.Join(t.TempDir(), "swap.mp3")
+	c, cancel := mkctx(); defer cancel()
+	err := ffmpeg.Convert(c, ffmpeg.ConvertOptions{
+		InputPath: testData("v1.mp4"), OutputPath: out,
+		FFmpegPath: ff, FFprobePath: fp, TrimDuration: pf64(2.0),
+		Channels: ffmpeg.ChannelOptions{Mode: "swap", Pan: pf64(-0.3)},
+	}, nil)
+	if err != nil { t.Fatal(err) }
+	assertOutput(t, out)
+}
+
+func TestConvert_CleanupPreset(t *testing.T) {
+	ff, fp := bin()
+	out := filepath.Join(t.TempDir(), "voice.m4a")
+	c, cancel := mkctx(); defer cancel()
+	cleanup := ffmpeg.ResolveAudioCleanup("voice", nil, nil, nil, pstr("off"), nil, pstr("gentle"), nil)
+	err := ffmpeg.Convert(c, ffmpeg.ConvertOptions{
+		InputPath: testData("v1.mp4"), OutputPath: out,
+		FFmpegPath: ff, FFprobePath: fp, TrimDuration: pf64(2.0),
+		Cleanup: &cleanup,
+	}, nil)
+	if err != nil { t.Fatal(err) }
+	assertOutput(t, out)
+}
+
+func TestConvert_Stabilize(t *testing.T) {
+	ff, fp := bin()
+	caps, err := ffmpeg.ProbeCapabilities(ff)
+	if err != nil { t.Fatal(err) }
+	out := filepath.Join(t.TempDir(), "stable.mp4")
+	c, cancel := mkctx(); defer cancel()
+	stab := ffmpeg.ResolveStabilization(nil, pint(15), pf64(2), caps.RequireFeature("stabilization") != nil)
+	var last float64
+	err = ffmpeg.Convert(c, ffmpeg.ConvertOptions{
+		InputPath: testData("v1.mp4"), OutputPath: out,
+		FFmpegPath: ff, FFprobePath: fp, TrimDuration: pf64(2.0),
+		VideoCodec: pstr("libx264"), Preset: pstr("ultrafast"), Stabilize: &stab,
+	}, func(ev ffmpeg.ProgressEvent) {
+		if ev.Progress < last-0.01 { t.Errorf("progress went back from %.2f to %.2f", last, ev.Progress) }
+		last = ev.Progress
+	})
+	if err != nil { t.Fatal(err) }
+	assertOutput(t, out)
+}
+
+// identityCube is a 2-point identity 3D LUT.
+const identityCube = `TITLE "identity"
+LUT_3D_SIZE 2
+0 0 0
+1 0 0
+0 1 0
+1 1 0
+0 0 1
+1 0 1
+0 1 1
+1 1 1
+`
+
+func TestParseCubeLUT(t *testing.T) {
+	lut, err := ffmpeg.ParseCubeLUT(strings.NewReader("# comment\n" + identityCube))
+	if err != nil { t.Fatal(err) }
+	if lut.Title != "identity" || lut.Size != 2 { t.Errorf("got %+v", lut) }
+	for name, bad := range map[string]string{
+		"1D":         "LUT_1D_SIZE 2\n0 0 0\n1 1 1\n",
+		"short":      "LUT_3D_SIZE 2\n0 0 0\n",
+		"no size":    "0 0 0\n",
+		"not number": strings.Replace(identityCube, "1 1 1", "1 x 1", 1),
+	} {
+		if _, err := ffmpeg.ParseCubeLUT(strings.NewReader(bad)); err == nil { t.Errorf("%s: expected error", name) }
+	}
+}
+
+func TestConvert_ColorGrade(t *testing.T) {
+	ff, fp := bin()
+	dir := t.TempDir()
+	lut := filepath.Join(dir, "identity.cube")
+	if err := os.WriteFile(lut, []byte(identityCube), 0o644); err != nil { t.Fatal(err) }
+	out := filepath.Join(dir, "graded.mp4")
+	c, cancel := mkctx(); defer cancel()
+	err := ffmpeg.Convert(c, ffmpeg.ConvertOptions{
+		InputPath: testData("v1.mp4"), OutputPath: out,
+		FFmpegPath: ff, FFprobePath: fp, TrimDuration: pf64(2.0),
+		VideoCodec: pstr("libx264"), Preset: pstr("ultrafast"), Contrast: pf64(1.1),
+		Grade: &ffmpeg.ColorGrade{Saturation: pf64(1.3), Gamma: pf64(0.9), Hue: pf64(10), Curves: "vintage", LUTPath: lut},
+	}, nil)
+	if err != nil { t.Fatal(err) }
+	assertOutput(t, out)
+}
+
+func TestStreamInfoHDR(t *testing.T) {
+	for trc, want := range map[string]string{"smpte2084": ffmpeg.HDRPQ, "arib-std-b67": ffmpeg.HDRHLG, "bt709": "", "": ""} {
+		if got := (ffmpeg.StreamInfo{ColorTransfer: trc}).HDR(); got != want { t.Errorf("%q: got %q, want %q", trc, got, want) }
+	}
+}
+
+func TestConvert_HDRToneMap(t *testing.T) {
+	ff, fp := bin()
+	caps, err := ffmpeg.ProbeCapabilities(ff)
+	if err != nil { t.Fatal(err) }
+	if caps.RequireFeature("tonemap") != nil { t.Skip("no zscale/tonemap") }
+	dir := t.TempDir()
+	// Tag an SDR clip as HLG; the tone mapping need not look right here.
+	hlg := filepath.Join(dir, "hlg.mp4")
+	tag := exec.Command(ff, "-v", "error", "-i", testData("v1.mp4"), "-t", "1", "-an", "-c:v", "libx264", "-preset", "ultrafast",
+		"-color_primaries", "bt2020", "-color_trc", "arib-std-b67", "-colorspace", "bt2020nc", "-y", hlg)
+	if b, err := tag.CombinedOutput(); err != nil { t.Fatalf("%v: %s", err, b) }
+	c, cancel := mkctx(); defer cancel()
+	info, err := ffmpeg.GetMediaInfo(c, fp, hlg)
+	if err != nil { t.Fatal(err) }
+	if info.HDR != ffmpeg.HDRHLG { t.Fatalf("HDR = %q, want hlg", info.HDR) }
+	out := filepath.Join(dir, "sdr.mp4")
+	err = ffmpeg.Convert(c, ffmpeg.ConvertOptions{
+		InputPath: hlg, OutputPath: out, FFmpegPath: ff, FFprobePath: fp,
+		VideoCodec: pstr("libx264"), Preset: pstr("ultrafast"),
+		HDR: &ffmpeg.HDRHandling{Algorithm: "mobius"},
+	}, nil)
+	if err != nil { t.Fatal(err) }
+	info, err = ffmpeg.GetMediaInfo(c, fp, out)
+	if err != nil { t.Fatal(err) }
+	if info.HDR != "" || info.Streams[0].ColorTransfer != "bt709" { t.Errorf("output is %q / %q, want SDR bt709", info.HDR, info.Streams[0].ColorTransfer) }
+}
+
+func TestInterlaceReport(t *testing.T) {
+	for _, c := range []struct {
+		r    ffmpeg.InterlaceReport
+		want string
+	}{
+		{ffmpeg.InterlaceReport{TFF: 480, Progressive: 20, Undetermined: 100}, "tff"},
+		{ffmpeg.InterlaceReport{BFF: 300, TFF: 10, Progressive: 5}, "bff"},
+		{ffmpeg.InterlaceReport{TFF: 3, Progressive: 590}, "progressive"},
+	} {
+		if got := c.r.FieldOrder(); got != c.want { t.Errorf("%+v: got %s, want %s", c.r, got, c.want) }
+	}
+}
+
+func TestConvert_DeinterlaceFrameRate(t *testing.T) {
+	ff, fp := bin()
+	c, cancel := mkctx(); defer cancel()
+	r, err := ffmpeg.DetectInterlace(c, ff, []string{"-i", testData("v1.mp4")})
+	if err != nil { t.Fatal(err) }
+	if r.Interlaced() { t.Errorf("v1.mp4 detected as interlaced: %+v", r) }
+	for _, mode := range []string{"drop", "blend", "interpolate"} {
+		out := filepath.Join(t.TempDir(), mode+".mp4")
+		var detected bool
+		err := ffmpeg.Convert(c, ffmpeg.ConvertOptions{
+			InputPath: testData("v1.mp4"), OutputPath: out,
+			FFmpegPath: ff, FFprobePath: fp, TrimDuration: pf64(1.0),
+			VideoCodec: pstr("libx264"), Preset: pstr("ultrafast"),
+			FPS: pstr("30000/1001"), FPSMode: mode,
+			Deinterlace: &ffmpeg.Deinterlace{Filter: "yadif", Auto: true, OnDetect: func(ffmpeg.InterlaceReport) { detected = true }},
+		}, nil)
+		if err != nil { t.Fatalf("%s: %v", mode, err) }
+		assertOutput(t, out)
+		if !detected { t.Errorf("%s: OnDetect not called", mode) }
+	}
+}
+
 func TestConvert_Speed_Half(t *testing.T) {
 	ff, fp := bin()
 	out := filepath.Join(t.TempDir(), "slow.mp4")
@@ -328,6 +566,61 @@ func TestConvert_CRF_Range(t *testing.T) {
 	}
 }
 
+// ─── Loudness ─────────────────────────────────────────────────────────────────
+
+func TestResolveLoudnessTarget(t *testing.T) {
+	got := ffmpeg.ResolveLoudnessTarget("podcast", nil, pf64(-2.0), nil)
+	want := ffmpeg.LoudnessTarget{I: -16, TP: -2, LRA: 11}
+	if got != want {
+		t.Errorf("podcast with TP override: got %+v, want %+v", got, want)
+	}
+	if got := ffmpeg.ResolveLoudnessTarget("", nil, nil, nil); got != ffmpeg.DefaultLoudnessTarget {
+		t.Errorf("no preset: got %+v, want default", got)
+	}
+}
+
+func TestConvert_LoudnessTwoPass(t *testing.T) {
+	ff, fp := bin()
+	out := filepath.Join(t.TempDir(), "podcast.mp3")
+	c, cancel := mkctx(); defer cancel()
+	target := ffmpeg.LoudnessPresets["podcast"]
+	measured := false
+	err := ffmpeg.Convert(c, ffmpeg.ConvertOptions{
+		InputPath: testData("v1.mp4"), OutputPath: out,
+		FFmpegPath: ff, FFprobePath: fp,
+		RemoveVideo: true, TrimDuration: pf64(3.0),
+		Normalize: true, Loudness: &target,
+		OnLoudness: func(ffmpeg.LoudnessTarget, ffmpeg.LoudnessMeasurement) { measured = true },
+	}, nil)
+	if err != nil { t.Fatal(err) }
+	assertOutput(t, out)
+	if !measured {
+		t.Error("expected first-pass measurement callback")
+	}
+	c2, cancel2 := mkctx(); defer cancel2()
+	if _, err := ffmpeg.MeasureLoudness(c2, ff, out, target); err != nil {
+		t.Errorf("MeasureLoudness: %v", err)
+	}
+}
+
+func TestSubtitleCodecFor(t *testing.T) {
+	for _, tc := range []struct{ format, codec, want string }{
+		{"mkv", "mov_text", "srt"}, {"mkv", "ass", "copy"}, {"mkv", "hdmv_pgs_subtitle", "copy"},
+		{"mp4", "subrip", "mov_text"}, {"webm", "ass", "webvtt"}, {"mp4", "dvd_subtitle", ""}, {"mp3", "subrip", ""},
+	} {
+		got, err := ffmpeg.SubtitleCodecFor(tc.format, ffmpeg.StreamInfo{Type: "subtitle", Codec: tc.codec})
+		if got != tc.want || (err != nil) != (tc.want == "") { t.Errorf("%s→%s: got %q, %v", tc.codec, tc.format, got, err) }
+	}
+}
+
+func TestLoudnessStream(t *testing.T) {
+	v, a1, a2 := ffmpeg.StreamInfo{Index: 0, Type: "video"}, ffmpeg.StreamInfo{Index: 1, Type: "audio"}, ffmpeg.StreamInfo{Index: 2, Type: "audio"}
+	st, err := ffmpeg.LoudnessStream([]ffmpeg.StreamInfo{v, a2})
+	if err != nil || st == nil || st.Index != 2 { t.Errorf("got %v, %v; want stream 2", st, err) }
+	if st, err := ffmpeg.LoudnessStream([]ffmpeg.StreamInfo{v}); err != nil || st != nil { t.Errorf("video only: got %v, %v", st, err) }
+	if _, err := ffmpeg.LoudnessStream([]ffmpeg.StreamInfo{v, a1, a2}); err == nil { t.Error("two audio streams accepted") }
+}
+
 // ─── Merge ────────────────────────────────────────────────────────────────────
 
 func TestMerge_TwoFiles(t *testing.T) {
@@ -360,6 +653,37 @@ func TestCanFastMerge(t *testing.T) {
 	_ = result
 }
 
+// ─── Compose ──────────────────────────────────────────────────────────────────
+
+func TestCompose_PiPAndGrid(t *testing.T) {
+	ff, fp := bin()
+	src := func(path string, offset float64) ffmpeg.ComposeSource {
+		return ffmpeg.ComposeSource{FilePath: testData(path), Duration: 2, Offset: offset, HasVideo: true, HasAudio: true}
+	}
+	for _, tc := range []struct{ layout string; sources []ffmpeg.ComposeSource; audio []int }{
+		{"pip", []ffmpeg.ComposeSource{src("v1.mp4", 0), src("v2.mp4", 0.5)}, []int{0, 1}},
+		{"side_by_side", []ffmpeg.ComposeSource{src("v1.mp4", 0), src("v2.mp4", 1)}, []int{0}},
+		{"grid", []ffmpeg.ComposeSource{src("v1.mp4", 0), src("v2.mp4", 0), src("v1.mp4", 1)}, nil},
+	} {
+		t.Run(tc.layout, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), tc.layout+".mp4")
+			c, cancel := mkctx(); defer cancel()
+			opts := ffmpeg.ComposeOptions{
+				Sources: tc.sources, Layout: tc.layout, Width: 640, Height: 360, Duration: 3, Audio: tc.audio,
+				PiP: ffmpeg.PiP{Corner: "top_left", Scale: 0.3, Margin: 8, Border: 2},
+				OutputPath: out, FFmpegPath: ff,
+			}
+			if err := ffmpeg.Compose(c, opts, nil); err != nil { t.Fatal(err) }
+			assertOutput(t, out)
+			info, err := ffmpeg.GetMediaInfo(c, fp, out)
+			if err != nil { t.Fatal(err) }
+			if info.Resolution != "640x360" { t.Errorf("resolution %s, want 640x360", info.Resolution) }
+			if info.Duration == nil || math.Abs(*info.Duration-3) > 0.2 { t.Errorf("duration %v, want 3", info.Duration) }
+			if info.HasAudio != (len(tc.audio) > 0) { t.Errorf("has audio %v with audio sources %v", info.HasAudio, tc.audio) }
+		})
+	}
+}
+
 // ─── Timeline Export ──────────────────────────────────────────────────────────
 
 func mkClip(path string, start, dur float64) ffmpeg.TimelineExportClip {
@@ -430,6 +754,139 @@ func TestTimeline_Precise_Multi_Effects(t *testing.T) {
 	assertOutput(t, out)
 }
 
+func TestTimeline_ClipChannels(t *testing.T) {
+	ff, fp := bin()
+	out := filepath.Join(t.TempDir(), "lav.mp4")
+	c, cancel := mkctx(); defer cancel()
+	left, down := mkClip(testData("v1.mp4"), 0, 1.5), mkClip(testData("v1.mp4"), 2, 1.5)
+	left.Channels, down.Channels = ffmpeg.ChannelOptions{Mode: "left"}, ffmpeg.ChannelOptions{Mode: "stereo"}
+	err := ffmpeg.TimelineExport(c, ffmpeg.TimelineExportOptions{
+		Clips: []ffmpeg.TimelineExportClip{left, down},
+		OutputPath: out, FFmpegPath: ff, FFprobePath: fp,
+	}, nil, nil)
+	if err != nil { t.Fatal(err) }
+	assertOutput(t, out)
+}
+
+func TestTimeline_Cleanup(t *testing.T) {
+	ff, fp := bin()
+	out := filepath.Join(t.TempDir(), "clean.mp4")
+	c, cancel := mkctx(); defer cancel()
+	err := ffmpeg.TimelineExport(c, ffmpeg.TimelineExportOptions{
+		Clips: []ffmpeg.TimelineExportClip{mkClip(testData("v1.mp4"), 0, 1.5), mkClip(testData("v1.mp4"), 2, 1.5)},
+		OutputPath: out, FFmpegPath: ff, FFprobePath: fp,
+		Cleanup: &ffmpeg.AudioCleanup{HighPass: 100, Denoise: "light", Limiter: -2},
+	}, nil, nil)
+	if err != nil { t.Fatal(err) }
+	assertOutput(t, out)
+}
+
+func TestTimeline_MusicDucking(t *testing.T) {
+	ff, fp := bin()
+	out := filepath.Join(t.TempDir(), "ducked.mp4")
+	c, cancel := mkctx(); defer cancel()
+	music := ffmpeg.ResolveMusicBed(testData("v1.mp4"), pf64(-18), nil, pf64(4), nil, nil, true)
+	err := ffmpeg.TimelineExport(c, ffmpeg.TimelineExportOptions{
+		Clips: []ffmpeg.TimelineExportClip{mkClip(testData("v1.mp4"), 0, 1.5), mkClip(testData("v1.mp4"), 2, 1.5)},
+		OutputPath: out, FFmpegPath: ff, FFprobePath: fp, Music: &music,
+	}, nil, nil)
+	if err != nil { t.Fatal(err) }
+	assertOutput(t, out)
+}
+
+func TestTimeline_ClipStabilizeDeshake(t *testing.T) {
+	ff, fp := bin()
+	out := filepath.Join(t.TempDir(), "deshake.mp4")
+	c, cancel := mkctx(); defer cancel()
+	shaky := mkClip(testData("v1.mp4"), 0, 1.5)
+	shaky.Stabilize = &ffmpeg.Stabilization{Deshake: true}
+	err := ffmpeg.TimelineExport(c, ffmpeg.TimelineExportOptions{
+		Clips: []ffmpeg.TimelineExportClip{shaky, mkClip(testData("v1.mp4"), 2, 1.5)},
+		OutputPath: out, FFmpegPath: ff, FFprobePath: fp,
+	}, nil, nil)
+	if err != nil { t.Fatal(err) }
+	assertOutput(t, out)
+}
+
+func TestTimeline_ClipGrade(t *testing.T) {
+	ff, fp := bin()
+	out := filepath.Join(t.TempDir(), "graded.mp4")
+	c, cancel := mkctx(); defer cancel()
+	warm := mkClip(testData("v1.mp4"), 0, 1.5)
+	warm.Grade = &ffmpeg.ColorGrade{Saturation: pf64(0.5), Curves: "lighter"}
+	err := ffmpeg.TimelineExport(c, ffmpeg.TimelineExportOptions{
+		Clips: []ffmpeg.TimelineExportClip{warm, mkClip(testData("v1.mp4"), 2, 1.5)},
+		OutputPath: out, FFmpegPath: ff, FFprobePath: fp,
+		Grade: &ffmpeg.ColorGrade{Gamma: pf64(1.1)},
+	}, nil, nil)
+	if err != nil { t.Fatal(err) }
+	assertOutput(t, out)
+}
+
+func TestClipOutputDuration(t *testing.T) {
+	c := mkClip("x.mp4", 0, 4)
+	c.Ramp = []ffmpeg.SpeedKeyframe{{Time: 0, Speed: 2}, {Time: 2, Speed: 2}, {Time: 4, Speed: 0.5}}
+	c.Freeze = &ffmpeg.FreezeFrame{At: 1, Hold: 1.5}
+	// 2 s at 2x, then 2 s slowing linearly from 2x to 0.5x: 2·ln(4)/1.5.
+	want := 1 + 2*math.Log(4)/1.5 + 1.5
+	if got := c.OutputDuration(); math.Abs(got-want) > 1e-9 { t.Errorf("got %.4f, want %.4f", got, want) }
+}
+
+func TestTimeline_Retime(t *testing.T) {
+	ff, fp := bin()
+	out := filepath.Join(t.TempDir(), "retimed.mp4")
+	c, cancel := mkctx(); defer cancel()
+	rev := mkClip(testData("v1.mp4"), 0, 1.5)
+	rev.Reverse = true
+	rev.Freeze = &ffmpeg.FreezeFrame{At: 0.5, Hold: 1}
+	ramp := mkClip(testData("v1.mp4"), 2, 2)
+	ramp.Ramp = []ffmpeg.SpeedKeyframe{{Time: 0.5, Speed: 1}, {Time: 1.5, Speed: 0.5}}
+	opts := ffmpeg.TimelineExportOptions{
+		Clips: []ffmpeg.TimelineExportClip{rev, ramp},
+		OutputPath: out, FFmpegPath: ff, FFprobePath: fp, FadeOut: pf64(0.5),
+	}
+	var last float64
+	err := ffmpeg.TimelineExport(c, opts, func(ev ffmpeg.ProgressEvent) { last = ev.Progress }, nil)
+	if err != nil { t.Fatal(err) }
+	assertOutput(t, out)
+	info, err := ffmpeg.GetMediaInfo(c, fp, out)
+	if err != nil { t.Fatal(err) }
+	want := rev.OutputDuration() + ramp.OutputDuration()
+	if info.Duration == nil || math.Abs(*info.Duration-want) > 0.2 { t.Errorf("duration %v, want %.2f", info.Duration, want) }
+	if last > 1.001 { t.Errorf("progress overshot: %.2f", last) }
+}
+
+func TestKeyframesAt(t *testing.T) {
+	k := ffmpeg.Keyframes{{Time: 1, Value: 0, Ease: "ease_in"}, {Time: 3, Value: 1, Ease: "hold"}, {Time: 4, Value: 0.5}}
+	for _, tc := range []struct{ t, want float64 }{{0, 0}, {2, 0.25}, {3, 1}, {3.9, 1}, {4, 0.5}, {9, 0.5}} {
+		if got := k.At(tc.t); math.Abs(got-tc.want) > 1e-9 { t.Errorf("At(%g) = %g, want %g", tc.t, got, tc.want) }
+	}
+}
+
+func TestTimeline_Keyframes(t *testing.T) {
+	ff, fp := bin()
+	out := filepath.Join(t.TempDir(), "keyframes.mp4")
+	c, cancel := mkctx(); defer cancel()
+	src, err := ffmpeg.GetMediaInfo(c, fp, testData("v1.mp4"))
+	if err != nil { t.Fatal(err) }
+	clip := mkClip(testData("v1.mp4"), 0, 3)
+	fmt.Sscanf(src.Resolution, "%dx%d", &clip.Width, &clip.Height)
+	clip.Animate = &ffmpeg.ClipAnimation{
+		Volume:     ffmpeg.Keyframes{{Time: 0, Value: 0}, {Time: 1, Value: 1, Ease: "ease_out"}},
+		Brightness: ffmpeg.Keyframes{{Time: 2, Value: 0}, {Time: 3, Value: -0.5}},
+		Zoom:       ffmpeg.Keyframes{{Time: 0.5, Value: 1, Ease: "ease_in_out"}, {Time: 2.5, Value: 2}},
+		FocusX:     ffmpeg.Keyframes{{Time: 0, Value: 0.25}},
+	}
+	err = ffmpeg.TimelineExport(c, ffmpeg.TimelineExportOptions{
+		Clips: []ffmpeg.TimelineExportClip{clip}, OutputPath: out, FFmpegPath: ff, FFprobePath: fp,
+	}, nil, nil)
+	if err != nil { t.Fatal(err) }
+	assertOutput(t, out)
+	info, err := ffmpeg.GetMediaInfo(c, fp, out)
+	if err != nil { t.Fatal(err) }
+	if info.Resolution != src.Resolution { t.Errorf("resolution %s, want %s", info.Resolution, src.Resolution) }
+}
+
 func TestTimeline_AudioOnly_FLAC(t *testing.T) {
 	ff, fp := bin()
 	out := filepath.Join(t.TempDir(), "audio.flac")
@@ -541,3 +998,256 @@ func TestGenerateWaveform_AudioOnly(t *testing.T) {
 		t.Error("expected waveform bars from mp3")
 	}
 }
+
+// ─── Audio analysis ───────────────────────────────────────────────────────────
+
+func TestAnalyzeAudio(t *testing.T) {
+	ff, _ := bin()
+	c, cancel := mkctx(); defer cancel()
+	a, err := ffmpeg.AnalyzeAudio(c, ff, testData("v1.mp4"), ffmpeg.AudioAnalysisOptions{}, nil)
+	if err != nil { t.Fatal(err) }
+	if a.Duration <= 0 || len(a.RMS) == 0 {
+		t.Errorf("expected duration and RMS series, got %+v", a)
+	}
+	if a.IntegratedLUFS >= 0 {
+		t.Errorf("integrated loudness should be negative, got %.1f", a.IntegratedLUFS)
+	}
+}
+
+// ─── Jump cuts ────────────────────────────────────────────────────────────────
+
+func TestJumpCutRanges(t *testing.T) {
+	silences := []ffmpeg.TimeRange{{Start: 0, End: 1}, {Start: 3, End: 5}, {Start: 5.9, End: 6.5}, {Start: 9, End: 10}}
+	opts := ffmpeg.SilenceOptions{Padding: 0.25, MinKeep: 0.2}
+	got := ffmpeg.JumpCutRanges(silences, 10, opts)
+	// Leading/trailing silence is cut fully; the 0.6s pause shrinks to 0.1s
+	// and is still removed; padding is kept around speech.
+	want := []ffmpeg.TimeRange{{Start: 0.75, End: 3.25}, {Start: 4.75, End: 6.15}, {Start: 6.25, End: 9.25}}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if math.Abs(got[i].Start-want[i].Start) > 1e-9 || math.Abs(got[i].End-want[i].End) > 1e-9 {
+			t.Errorf("range %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDetectSilence(t *testing.T) {
+	ff, _ := bin()
+	c, cancel := mkctx(); defer cancel()
+	if _, err := ffmpeg.DetectSilence(c, ff, testData("v1.mp4"), 5, ffmpeg.DefaultSilenceOptions, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// ─── Scenes ───────────────────────────────────────────────────────────────────
+
+func TestScenesFromCuts(t *testing.T) {
+	cuts := []ffmpeg.SceneCut{{Time: 0.2, Score: 0.9}, {Time: 4, Score: 0.5}, {Time: 4.5, Score: 0.4}, {Time: 9.8, Score: 0.6}}
+	got := ffmpeg.ScenesFromCuts(cuts, 10, 1)
+	want := []ffmpeg.TimeRange{{Start: 0, End: 4}, {Start: 4, End: 10}}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("scene %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDetectScenes(t *testing.T) {
+	ff, _ := bin()
+	c, cancel := mkctx(); defer cancel()
+	if _, err := ffmpeg.DetectScenes(c, ff, testData("v1.mp4"), 0.3, 5, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// ─── QC ───────────────────────────────────────────────────────────────────────
+
+func TestQCReportEvaluate(t *testing.T) {
+	r := &ffmpeg.QCReport{
+		BlackSegments:  []ffmpeg.TimeRange{{Start: 0, End: 0.8}, {Start: 10, End: 13}},
+		FreezeSegments: []ffmpeg.TimeRange{{Start: 20, End: 22}},
+		ClippedSamples: 3,
+		Thresholds:     ffmpeg.QCThresholds{MaxBlackSec: 1, MaxFreezeSec: 5, MaxClippedSamples: 10},
+	}
+	r.Evaluate()
+	if r.Passed || len(r.Issues) != 1 || r.Issues[0].Kind != "black" || len(r.Issues[0].Ranges) != 1 {
+		t.Fatalf("unexpected issues: %+v", r.Issues)
+	}
+	r.Thresholds.MaxBlackSec = 5
+	r.Evaluate()
+	if !r.Passed { t.Fatalf("expected pass, got %+v", r.Issues) }
+}
+
+func TestRunQC(t *testing.T) {
+	ff, _ := bin()
+	c, cancel := mkctx(); defer cancel()
+	r, err := ffmpeg.RunQC(c, ff, testData("v1.mp4"), ffmpeg.QCOptions{HasVideo: true, HasAudio: true, Duration: 5}, nil)
+	if err != nil { t.Fatal(err) }
+	if r.Issues == nil { t.Error("issues should not be nil") }
+}
+
+// ─── Quality ──────────────────────────────────────────────────────────────────
+
+func TestMeasureQuality(t *testing.T) {
+	ff, _ := bin()
+	c, cancel := mkctx(); defer cancel()
+	out := filepath.Join(t.TempDir(), "q.mp4")
+	if err := ffmpeg.Convert(c, ffmpeg.ConvertOptions{InputPath: testData("v1.mp4"), OutputPath: out, FFmpegPath: ff, VideoCodec: pstr("libx264"), CRF: pint(30), ResizeHeight: pint(360)}, nil); err != nil { t.Fatal(err) }
+	s, err := ffmpeg.MeasureQuality(c, ffmpeg.QualityOptions{FFmpegPath: ff, DistortedPath: out, References: []ffmpeg.QualityReference{{Path: testData("v1.mp4")}}, Width: 640, Height: 360}, nil)
+	if err != nil { t.Fatal(err) }
+	if s.PSNR == nil || s.SSIM == nil || *s.SSIM <= 0 || *s.SSIM > 1 { t.Errorf("unexpected scores: %+v", s) }
+}
+
+// ─── Metadata ─────────────────────────────────────────────────────────────────
+
+func TestClipChapters(t *testing.T) {
+	opts := ffmpeg.TimelineExportOptions{
+		Clips: []ffmpeg.TimelineExportClip{mkClip("a.mp4", 0, 4), mkClip("b.mp4", 0, 2), mkClip("c.mp4", 0, 1)},
+		Speed: pf64(2),
+	}
+	got := ffmpeg.ClipChapters(opts, []string{"Intro", ""})
+	want := []ffmpeg.Chapter{{Start: 0, End: 2, Title: "Intro"}, {Start: 2, End: 3, Title: "Chapter 2"}, {Start: 3, End: 3.5, Title: "Chapter 3"}}
+	if fmt.Sprint(got) != fmt.Sprint(want) { t.Errorf("got %v, want %v", got, want) }
+}
+
+func TestWriteMetadata_ChaptersAndCover(t *testing.T) {
+	ff, fp := bin()
+	dir := t.TempDir()
+	c, cancel := mkctx(); defer cancel()
+	video := filepath.Join(dir, "chapters.mp4")
+	opts := ffmpeg.TimelineExportOptions{
+		Clips: []ffmpeg.TimelineExportClip{mkClip(testData("v1.mp4"), 0, 2), mkClip(testData("v2.mp4"), 0, 2)},
+		OutputPath: video, FFmpegPath: ff, FFprobePath: fp,
+	}
+	if err := ffmpeg.TimelineExport(c, opts, nil, nil); err != nil { t.Fatal(err) }
+	m := &ffmpeg.Metadata{Tags: map[string]string{"title": "Cut = 1; #2", "date": "2024"}, Chapters: ffmpeg.ClipChapters(opts, []string{"One", "Two"}), FastStart: true}
+	if err := ffmpeg.WriteMetadata(c, ff, fp, video, m, nil); err != nil { t.Fatal(err) }
+	info, err := ffmpeg.GetMediaInfo(c, fp, video)
+	if err != nil { t.Fatal(err) }
+	if info.Tags["title"] != "Cut = 1; #2" { t.Errorf("title %q", info.Tags["title"]) }
+	if len(info.Chapters) != 2 || info.Chapters[1].Title != "Two" || math.Abs(info.Chapters[1].Start-2) > 0.1 {
+		t.Errorf("chapters %v", info.Chapters)
+	}
+	late := &ffmpeg.Metadata{Chapters: []ffmpeg.Chapter{{Start: 0, Title: "One"}, {Start: 60, Title: "Past the end"}}}
+	if err := ffmpeg.WriteMetadata(c, ff, fp, video, late, nil); err == nil { t.Error("chapter past the end accepted") }
+
+	cover := filepath.Join(dir, "cover.png")
+	if err := exec.Command(ff, "-f", "lavfi", "-i", "color=c=red:s=64x64", "-frames:v", "1", "-y", cover).Run(); err != nil { t.Fatal(err) }
+	audio := filepath.Join(dir, "tagged.mp3")
+	err = ffmpeg.TimelineExport(c, ffmpeg.TimelineExportOptions{
+		Clips: []ffmpeg.TimelineExportClip{mkClip(testData("v1.mp4"), 0, 2)}, OutputPath: audio, FFmpegPath: ff, FFprobePath: fp, RemoveVideo: true,
+	}, nil, nil)
+	if err != nil { t.Fatal(err) }
+	if err := ffmpeg.WriteMetadata(c, ff, fp, audio, &ffmpeg.Metadata{Tags: map[string]string{"artist": "Someone"}, CoverPath: cover}, nil); err != nil { t.Fatal(err) }
+	info, err = ffmpeg.GetMediaInfo(c, fp, audio)
+	if err != nil { t.Fatal(err) }
+	if info.Tags["artist"] != "Someone" { t.Errorf("artist %q", info.Tags["artist"]) }
+	pics := 0
+	for _, st := range info.Streams {
+		if st.AttachedPic { pics++ }
+	}
+	if pics != 1 { t.Errorf("%d attached pictures, want 1", pics) }
+}
+
+// ─── Progress ─────────────────────────────────────────────────────────────────
+
+func TestConvert_ProgressEvents(t *testing.T) {
+	ff, fp := bin()
+	c, cancel := mkctx(); defer cancel()
+	var events []ffmpeg.ProgressEvent
+	out := filepath.Join(t.TempDir(), "p.mp4")
+	err := ffmpeg.Convert(c, ffmpeg.ConvertOptions{InputPath: testData("v1.mp4"), OutputPath: out, FFmpegPath: ff, FFprobePath: fp, VideoCodec: pstr("libx264")}, func(ev ffmpeg.ProgressEvent) { events = append(events, ev) })
+	if err != nil { t.Fatal(err) }
+	if len(events) == 0 { t.Fatal("no progress events") }
+	last := events[len(events)-1]
+	if !last.Done || last.Progress != 1 || last.Frame <= 0 || last.Speed <= 0 {
+		t.Errorf("unexpected final event: %+v", last)
+	}
+}
+
+// ─── Error classification ─────────────────────────────────────────────────────
+
+func TestClassifyStderr(t *testing.T) {
+	cases := map[string]ffmpeg.ErrorCode{
+		"[h264_nvenc @ 0x1] OpenEncodeSessionEx failed: out of memory (10)\nError initializing output stream 0:0": ffmpeg.CodeHWEncoderInit,
+		"Unknown encoder 'libsvtav1'":                                                        ffmpeg.CodeEncoderNotFound,
+		"Decoder (codec av1) not found for input stream #0:0":                                ffmpeg.CodeDecoderNotFound,
+		"[webm @ 0x1] Only VP8 or VP9 or AV1 video and Vorbis or Opus audio and WebVTT subtitles are supported for WebM.\nCould not write header for output file #0": ffmpeg.CodeUnsupportedCodec,
+		"in.mp4: Invalid data found when processing input":                                   ffmpeg.CodeCorruptInput,
+		"av_interleaved_write_frame(): No space left on device":                              ffmpeg.CodeNoSpace,
+		"[AVFilterGraph @ 0x1] No such filter: 'foo'\nError initializing complex filters.":    ffmpeg.CodeInvalidFilter,
+		"out.mp4: Permission denied":                                                         ffmpeg.CodePermissionDenied,
+		"something odd happened":                                                             ffmpeg.CodeUnknown,
+	}
+	for stderr, want := range cases {
+		if got, hint, _ := ffmpeg.ClassifyStderr(stderr); got != want || hint == "" {
+			t.Errorf("%q: got %s, want %s", stderr, got, want)
+		}
+	}
+}
+
+func TestHWEncoderFailed(t *testing.T) {
+	err := &ffmpeg.Error{Code: ffmpeg.CodeHWEncoderInit}
+	if !ffmpeg.HWEncoderFailed(err, "h264_nvenc") { t.Error("h264_nvenc init failure not reported") }
+	if ffmpeg.HWEncoderFailed(err, "libx264") { t.Error("software encoder reported as a hardware failure") }
+}
+
+func TestConvert_ErrorCode(t *testing.T) {
+	ff, _ := bin()
+	c, cancel := mkctx(); defer cancel()
+	err := ffmpeg.Convert(c, ffmpeg.ConvertOptions{InputPath: testData("v1.mp4"), OutputPath: filepath.Join(t.TempDir(), "o.mp4"), FFmpegPath: ff, VideoCodec: pstr("no_such_encoder")}, nil)
+	if got := ffmpeg.CodeOf(err); got != ffmpeg.CodeEncoderNotFound { t.Fatalf("code %q, err %v", got, err) }
+}
+
+// ─── Capabilities ─────────────────────────────────────────────────────────────
+
+func TestProbeCapabilities(t *testing.T) {
+	ff, _ := bin()
+	caps, err := ffmpeg.ProbeCapabilities(ff)
+	if err != nil { t.Fatal(err) }
+	if caps.Version == "" || !caps.HasEncoder("aac") || !caps.HasFilter("loudnorm") || !caps.CanWrite("mp4") {
+		t.Fatalf("unexpected capabilities: version %q, %d encoders, %d filters, %d muxers", caps.Version, len(caps.Encoders), len(caps.Filters), len(caps.Muxers))
+	}
+	if caps.CanWrite("xyz") { t.Error("unknown format should not be writable") }
+}
+
+// ─── Hardware encoders ────────────────────────────────────────────────────────
+
+// fakeFFmpeg writes a script that lists NVENC and VAAPI encoders but has no
+// NVIDIA device: only h264_vaapi (given its device) can encode.
+func fakeFFmpeg(t *testing.T) string {
+	if runtime.GOOS == "windows" { t.Skip("fake ffmpeg is a shell script") }
+	path := filepath.Join(t.TempDir(), "ffmpeg")
+	script := `#!/bin/sh
+case "$*" in
+*-version*) echo "ffmpeg version 6.1-fake Copyright (c) the FFmpeg developers" ;;
+*-encoders*) printf 'Encoders:\n V..... = Video\n ------\n V....D libx264  x264\n V....D h264_nvenc  NVENC H.264\n V....D hevc_nvenc  NVENC HEVC\n V....D h264_vaapi  VAAPI H.264\n V....D hevc_vaapi  VAAPI HEVC\n A....D aac  AAC\n' ;;
+*nvenc*) echo "[h264_nvenc @ 0x1] No NVENC capable devices found" >&2; exit 1 ;;
+*-vaapi_device*"-c:v h264_vaapi"*) exit 0 ;;
+*vaapi*) echo "[AVHWDeviceContext @ 0x1] Failed to initialise VAAPI connection: -1 (unknown libva error)." >&2; exit 1 ;;
+esac
+`
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil { t.Fatal(err) }
+	return path
+}
+
+func TestDetectHWEncoders_FakeFFmpeg(t *testing.T) {
+	ff := fakeFFmpeg(t)
+	caps, err := ffmpeg.ProbeCapabilities(ff)
+	if err != nil { t.Fatal(err) }
+	hw := ffmpeg.DetectHWEncoders(ff, caps, "auto")
+	if hw.Encoders["h264"] != "h264_vaapi" || hw.Encoders["hevc"] != "" || hw.Accel() != "vaapi" {
+		t.Fatalf("encoders: %v", hw.Encoders)
+	}
+	for _, enc := range []string{"h264_nvenc", "hevc_nvenc", "hevc_vaapi"} {
+		if hw.Failed[enc] == "" { t.Errorf("%s should have failed verification: %v", enc, hw.Failed) }
+	}
+	if hw := ffmpeg.DetectHWEncoders(ff, caps, "cuda"); len(hw.Encoders) != 0 { t.Errorf("cuda: %v", hw.Encoders) }
+	if hw := ffmpeg.DetectHWEncoders(ff, caps, "none"); len(hw.Encoders) != 0 { t.Errorf("none: %v", hw.Encoders) }
+	if got := ffmpeg.SoftwareEncoderFor("hevc_nvenc", caps); got != "libx265" { t.Errorf("software for hevc_nvenc: %s", got) }
+}
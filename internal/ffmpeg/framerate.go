@@ -0,0 +1,20 @@
+package ffmpeg
+
+// ─── Frame rate conversion ────────────────────────────────────────────────────
+
+// FrameRateModes are the ways Convert changes the frame rate: "drop" drops or
+// duplicates frames, "blend" cross-fades neighbouring frames, and
+// "interpolate" builds new frames from motion estimation (slow).
+var FrameRateModes = []string{"drop", "blend", "interpolate"}
+
+// frameRateFilter returns the filter converting to rate (e.g. "30000/1001")
+// in mode; "" is "drop".
+func frameRateFilter(rate, mode string) string {
+	switch mode {
+	case "blend":
+		return "framerate=fps=" + rate
+	case "interpolate":
+		return "minterpolate=fps=" + rate + ":mi_mode=mci:mc_mode=aobmc:me_mode=bidir:vsbmc=1"
+	}
+	return "fps=" + rate
+}
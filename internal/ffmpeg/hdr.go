@@ -0,0 +1,85 @@
+package ffmpeg
+
+import "fmt"
+
+// ─── HDR ──────────────────────────────────────────────────────────────────────
+
+// HDR formats, by transfer function.
+const (
+	HDRPQ  = "pq"  // SMPTE ST 2084: HDR10, HDR10+ and Dolby Vision base layers
+	HDRHLG = "hlg" // ARIB STD-B67: broadcast HDR and most phone cameras
+)
+
+// HDR returns the stream's HDR format, HDRPQ or HDRHLG, or "" for SDR.
+func (st StreamInfo) HDR() string {
+	switch st.ColorTransfer {
+	case "smpte2084":
+		return HDRPQ
+	case "arib-std-b67":
+		return HDRHLG
+	}
+	return ""
+}
+
+// HDRModes are what a request may do with an HDR source: "auto" tone maps
+// when the filters are available, "tonemap" requires them, "preserve" keeps
+// HDR (see HDRCodecs) and "off" leaves the colours alone.
+var HDRModes = []string{"auto", "tonemap", "preserve", "off"}
+
+// ToneMapAlgorithms are the tonemap filter's curves. hable keeps the most
+// highlight detail; mobius stays closest to the source in the mid-tones.
+var ToneMapAlgorithms = []string{"hable", "mobius", "reinhard", "clip", "gamma", "linear"}
+
+// HDRCodecs are the encoders that can carry HDR: 10-bit, with the BT.2020
+// colour description written to the bitstream.
+var HDRCodecs = []string{"libx265", "libsvtav1", "libaom-av1"}
+
+// HDRHandling converts or keeps an HDR source's colours. Convert applies it
+// only when the source is HDR.
+type HDRHandling struct {
+	// Preserve keeps HDR instead of tone mapping; the encoder must be one of
+	// HDRCodecs.
+	Preserve bool
+	// Algorithm is the tone curve, one of ToneMapAlgorithms; "" is hable.
+	Algorithm string
+}
+
+// filters returns the filters for src: zscale linearizes the source, tonemap
+// compresses its highlights into SDR range, and zscale converts the result to
+// BT.709. Preserving needs no filters.
+func (h *HDRHandling) filters(src StreamInfo) []string {
+	if h.Preserve {
+		return nil
+	}
+	algorithm := h.Algorithm
+	if algorithm == "" {
+		algorithm = "hable"
+	}
+	return []string{
+		// The input is described explicitly since not every file tags its
+		// frames; npl=100 maps SDR reference white to 100 nits.
+		fmt.Sprintf("zscale=tin=%s:pin=bt2020:min=bt2020nc:t=linear:npl=100", src.ColorTransfer),
+		"format=gbrpf32le",
+		"zscale=p=bt709",
+		fmt.Sprintf("tonemap=tonemap=%s:desat=0", algorithm),
+		"zscale=t=bt709:m=bt709:r=tv",
+		"format=yuv420p",
+	}
+}
+
+// encoderArgs returns the output flags for src and the encoder vCodec: the
+// BT.709 description after tone mapping, or 10-bit BT.2020 with the source's
+// transfer when preserving. x265 also writes the HDR10 SEI messages for PQ.
+func (h *HDRHandling) encoderArgs(src StreamInfo, vCodec string) []string {
+	if !h.Preserve {
+		return []string{"-color_primaries", "bt709", "-color_trc", "bt709", "-colorspace", "bt709"}
+	}
+	args := []string{
+		"-pix_fmt", "yuv420p10le",
+		"-color_primaries", "bt2020", "-color_trc", src.ColorTransfer, "-colorspace", "bt2020nc",
+	}
+	if vCodec == "libx265" && src.HDR() == HDRPQ {
+		args = append(args, "-x265-params", "hdr10=1:hdr10-opt=1:repeat-headers=1")
+	}
+	return args
+}
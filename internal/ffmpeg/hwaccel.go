@@ -0,0 +1,181 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ─── Hardware encoders ────────────────────────────────────────────────────────
+
+// HWAccels are the hardware encoder families, in order of preference.
+var HWAccels = []string{"nvenc", "qsv", "vaapi", "amf", "videotoolbox"}
+
+// HWCodecs are the codecs a request can ask a hardware encoder for.
+var HWCodecs = []string{"h264", "hevc", "av1"}
+
+// VAAPIDevice is the DRM render node VAAPI encodes run on.
+var VAAPIDevice = "/dev/dri/renderD128"
+
+// HWEncoderFor returns the ffmpeg encoder for accel and codec, e.g. "nvenc" +
+// "hevc" → "hevc_nvenc". "cuda" is accepted as an alias for nvenc.
+func HWEncoderFor(accel, codec string) string {
+	if accel == "cuda" {
+		accel = "nvenc"
+	}
+	return codec + "_" + accel
+}
+
+// HWAccelOf returns the family of a hardware encoder ("hevc_vaapi" → "vaapi"),
+// or "" for software encoders.
+func HWAccelOf(encoder string) string {
+	i := strings.LastIndexByte(encoder, '_')
+	if i < 0 {
+		return ""
+	}
+	for _, accel := range HWAccels {
+		if encoder[i+1:] == accel {
+			return accel
+		}
+	}
+	return ""
+}
+
+// SoftwareEncoderFor returns the software encoder for a codec ("hevc") or for
+// the codec of a hardware encoder ("hevc_nvenc"), preferring what the build
+// has (caps may be nil).
+func SoftwareEncoderFor(encoder string, caps *Capabilities) string {
+	switch {
+	case strings.HasPrefix(encoder, "hevc"):
+		return "libx265"
+	case strings.HasPrefix(encoder, "av1"):
+		if caps.HasEncoder("libsvtav1") || !caps.HasEncoder("libaom-av1") {
+			return "libsvtav1"
+		}
+		return "libaom-av1"
+	}
+	return "libx264"
+}
+
+// hwDeviceArgs are the global options an encoder needs before the inputs.
+func hwDeviceArgs(encoder string) []string {
+	if HWAccelOf(encoder) == "vaapi" {
+		return []string{"-vaapi_device", VAAPIDevice}
+	}
+	return nil
+}
+
+// hwUploadFilter is appended to the video chain of encoders that only take
+// frames in device memory, or "".
+func hwUploadFilter(encoder string) string {
+	if HWAccelOf(encoder) == "vaapi" {
+		return "format=nv12,hwupload"
+	}
+	return ""
+}
+
+// videoQualityArgs returns the preset and rate-control flags for vCodec. crf is
+// mapped onto each hardware family's constant-quality option; preset applies
+// to the software encoders (see softwareQualityArgs).
+func videoQualityArgs(vCodec string, crf *int, preset string, hasBitrate bool) []string {
+	var args []string
+	switch HWAccelOf(vCodec) {
+	case "nvenc":
+		args = append(args, "-preset", "p4") // p1=fastest … p7=best quality
+		if crf != nil {
+			args = append(args, "-cq", fmt.Sprintf("%d", *crf))
+		}
+	case "qsv":
+		args = append(args, "-preset", "fast")
+		if crf != nil {
+			args = append(args, "-global_quality", fmt.Sprintf("%d", *crf))
+		}
+	case "vaapi":
+		if crf != nil {
+			args = append(args, "-rc_mode", "CQP", "-qp", fmt.Sprintf("%d", *crf))
+		}
+	case "amf":
+		args = append(args, "-quality", "balanced")
+		if crf != nil {
+			q := fmt.Sprintf("%d", *crf)
+			args = append(args, "-rc", "cqp", "-qp_i", q, "-qp_p", q)
+		}
+	case "videotoolbox":
+		// videotoolbox doesn't support CRF; rely on bitrate or default quality.
+		if !hasBitrate {
+			args = append(args, "-b:v", "8000k")
+		}
+	default:
+		args = softwareQualityArgs(vCodec, crf, preset, hasBitrate)
+	}
+	return args
+}
+
+// HWSupport is the outcome of DetectHWEncoders.
+type HWSupport struct {
+	// Encoders maps a codec ("h264", "hevc", "av1") to its verified encoder.
+	Encoders map[string]string
+	// Failed maps encoders the build lists but that could not encode to the
+	// reason, e.g. no device.
+	Failed map[string]string
+}
+
+// Accel returns the family of the verified H.264 encoder, or "".
+func (s *HWSupport) Accel() string {
+	return HWAccelOf(s.Encoders["h264"])
+}
+
+// DetectHWEncoders finds a working hardware encoder for each of HWCodecs.
+// Candidates the build lists are verified with VerifyHWEncoder in HWAccels
+// order; accel restricts the search to one family ("auto" tries all, "none"
+// none).
+func DetectHWEncoders(ffmpegPath string, caps *Capabilities, accel string) *HWSupport {
+	s := &HWSupport{Encoders: map[string]string{}, Failed: map[string]string{}}
+	if caps == nil || accel == "none" {
+		return s
+	}
+	if accel == "cuda" {
+		accel = "nvenc"
+	}
+	for _, codec := range HWCodecs {
+		for _, a := range HWAccels {
+			if accel != "auto" && a != accel {
+				continue
+			}
+			enc := HWEncoderFor(a, codec)
+			if !caps.Encoders[enc] {
+				continue
+			}
+			if err := VerifyHWEncoder(ffmpegPath, enc); err != nil {
+				s.Failed[enc] = hwFailureReason(err)
+				continue
+			}
+			s.Encoders[codec] = enc
+			break
+		}
+	}
+	return s
+}
+
+// VerifyHWEncoder encodes a few frames of a generated picture with encoder to
+// a null sink. Listing an encoder only means ffmpeg was built with it; this
+// checks there is a device and driver to run it.
+func VerifyHWEncoder(ffmpegPath, encoder string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	args := append([]string{"-hide_banner", "-v", "error"}, hwDeviceArgs(encoder)...)
+	args = append(args, "-f", "lavfi", "-i", "color=c=black:s=256x256:r=25:d=1")
+	if up := hwUploadFilter(encoder); up != "" {
+		args = append(args, "-vf", up)
+	}
+	args = append(args, "-frames:v", "5", "-c:v", encoder, "-f", "null", "-")
+	return runFFmpeg(ctx, ffmpegPath, args, nil, nil)
+}
+
+func hwFailureReason(err error) string {
+	if e := AsError(err); e != nil && e.Detail != "" {
+		return e.Detail
+	}
+	return err.Error()
+}
@@ -0,0 +1,87 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// ─── Deinterlacing ────────────────────────────────────────────────────────────
+
+// Deinterlacers are the deinterlace filters: bwdif keeps more detail on
+// motion, yadif is faster.
+var Deinterlacers = []string{"bwdif", "yadif"}
+
+// Deinterlace converts interlaced video to progressive at the same frame rate.
+type Deinterlace struct {
+	Filter string // one of Deinterlacers
+	// Auto runs DetectInterlace first and leaves progressive video alone.
+	Auto bool
+	// OnDetect (may be nil) receives the Auto detection result.
+	OnDetect func(InterlaceReport)
+}
+
+// filter returns the deinterlace filter. deint=all also catches interlaced
+// video that is not flagged as such, which is common in archive transfers.
+func (d *Deinterlace) filter() string {
+	return d.Filter + "=mode=send_frame:parity=auto:deint=all"
+}
+
+// interlaceSampleFrames is how many frames DetectInterlace classifies.
+const interlaceSampleFrames = 600
+
+// InterlaceReport is idet's classification of the sampled frames.
+type InterlaceReport struct {
+	TFF          int `json:"tff"` // top field first
+	BFF          int `json:"bff"` // bottom field first
+	Progressive  int `json:"progressive"`
+	Undetermined int `json:"undetermined"`
+}
+
+// Interlaced reports whether most classified frames are interlaced.
+func (r InterlaceReport) Interlaced() bool {
+	return r.TFF+r.BFF > r.Progressive
+}
+
+// FieldOrder returns "tff" or "bff" for interlaced video, else "progressive".
+func (r InterlaceReport) FieldOrder() string {
+	switch {
+	case !r.Interlaced():
+		return "progressive"
+	case r.BFF > r.TFF:
+		return "bff"
+	}
+	return "tff"
+}
+
+// idetRe matches idet's multi-frame summary, which uses neighbouring frames
+// and so is steadier than its single-frame one.
+var idetRe = regexp.MustCompile(`Multi frame detection:\s*TFF:\s*(\d+)\s*BFF:\s*(\d+)\s*Progressive:\s*(\d+)\s*Undetermined:\s*(\d+)`)
+
+// DetectInterlace runs idet over the first interlaceSampleFrames frames of
+// inputArgs (the -ss / -i / -t of an encode).
+func DetectInterlace(ctx context.Context, ffmpegPath string, inputArgs []string) (*InterlaceReport, error) {
+	args := append([]string{"-hide_banner", "-nostats"}, inputArgs...)
+	args = append(args,
+		"-an", "-sn", "-frames:v", strconv.Itoa(interlaceSampleFrames),
+		"-vf", "idet", "-v", "info", "-f", "null", "-",
+	)
+	out, err := runFFmpegCapture(ctx, ffmpegPath, args, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("interlace detection: %w", err)
+	}
+	return parseIdet(out)
+}
+
+func parseIdet(out string) (*InterlaceReport, error) {
+	m := idetRe.FindStringSubmatch(out)
+	if m == nil {
+		return nil, fmt.Errorf("interlace detection: no idet summary in ffmpeg output")
+	}
+	n := make([]int, 4)
+	for i := range n {
+		n[i], _ = strconv.Atoi(m[i+1])
+	}
+	return &InterlaceReport{TFF: n[0], BFF: n[1], Progressive: n[2], Undetermined: n[3]}, nil
+}
@@ -0,0 +1,214 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+// ─── Keyframed clip properties ────────────────────────────────────────────────
+
+// Easings shape the change from one keyframe to the next: ease_in starts
+// slowly, ease_out ends slowly, ease_in_out does both and hold keeps the value
+// until the next keyframe.
+var Easings = []string{"linear", "ease_in", "ease_out", "ease_in_out", "hold"}
+
+// Keyframe sets a property to Value at Time, in seconds into the clip's
+// source (before Reverse and the ramp).
+type Keyframe struct {
+	Time  float64
+	Value float64
+	// Ease is the easing towards the next keyframe, one of Easings; "" is
+	// linear.
+	Ease string
+}
+
+// Keyframes animate one property. They are sorted by Time; the value holds
+// before the first and after the last.
+type Keyframes []Keyframe
+
+// ClipAnimation animates a clip's properties. Empty Keyframes leave the
+// property alone.
+type ClipAnimation struct {
+	Volume     Keyframes // linear gain
+	Brightness Keyframes // eq's: -1 … 1, 0 unchanged
+	Contrast   Keyframes // eq's: 1 unchanged
+	Saturation Keyframes // eq's: 0 grey, 1 unchanged
+	// Zoom magnifies the picture: 1 is the full frame, 2 shows its middle
+	// quarter. It needs the clip's Width and Height.
+	Zoom Keyframes
+	// FocusX and FocusY are the point zoomed into, as fractions of the
+	// frame; empty is the centre.
+	FocusX Keyframes
+	FocusY Keyframes
+}
+
+// IsSet reports whether a is non-nil and animates anything.
+func (a *ClipAnimation) IsSet() bool {
+	return a != nil && len(a.Volume)+len(a.Brightness)+len(a.Contrast)+len(a.Saturation)+len(a.Zoom) > 0
+}
+
+// easeAt returns the easing curve at progress p (0 … 1).
+func easeAt(ease string, p float64) float64 {
+	switch ease {
+	case "ease_in":
+		return p * p
+	case "ease_out":
+		return 1 - (1-p)*(1-p)
+	case "ease_in_out":
+		return p * p * (3 - 2*p)
+	case "hold":
+		return 0
+	}
+	return p
+}
+
+// easeExpr is easeAt as an expression in p, which must be parenthesized.
+func easeExpr(ease, p string) string {
+	switch ease {
+	case "ease_in":
+		return fmt.Sprintf("%s*%s", p, p)
+	case "ease_out":
+		return fmt.Sprintf("(1-(1-%s)*(1-%s))", p, p)
+	case "ease_in_out":
+		return fmt.Sprintf("%s*%s*(3-2*%s)", p, p, p)
+	case "hold":
+		return "0"
+	}
+	return p
+}
+
+// At returns the property's value at t.
+func (k Keyframes) At(t float64) float64 {
+	if t <= k[0].Time {
+		return k[0].Value
+	}
+	for i := 1; i < len(k); i++ {
+		if t < k[i].Time {
+			a, b := k[i-1], k[i]
+			return a.Value + (b.Value-a.Value)*easeAt(a.Ease, (t-a.Time)/(b.Time-a.Time))
+		}
+	}
+	return k[len(k)-1].Value
+}
+
+// expr returns the keyframes as an expression in the time variable v for
+// filters evaluated per frame, nested like rampPTS.
+func (k Keyframes) expr(v string) string {
+	expr := fmt.Sprintf("%.6f", k[len(k)-1].Value)
+	for i := len(k) - 2; i >= 0; i-- {
+		a, b := k[i], k[i+1]
+		p := fmt.Sprintf("((%s-%.6f)/%.6f)", v, a.Time, b.Time-a.Time)
+		seg := fmt.Sprintf("%.6f+(%.6f)*%s", a.Value, b.Value-a.Value, easeExpr(a.Ease, p))
+		expr = fmt.Sprintf("if(lt(%s,%.6f),%s,%s)", v, b.Time, seg, expr)
+	}
+	return fmt.Sprintf("if(lt(%s,%.6f),%.6f,%s)", v, k[0].Time, k[0].Value, expr)
+}
+
+// volumeFilters returns the animated gain, or nil.
+func (a *ClipAnimation) volumeFilters() []string {
+	if a == nil || len(a.Volume) == 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("volume=volume='%s':eval=frame", a.Volume.expr("t"))}
+}
+
+// eqFilters returns the animated brightness, contrast and saturation, or nil.
+func (a *ClipAnimation) eqFilters() []string {
+	if a == nil {
+		return nil
+	}
+	var opts []string
+	for _, p := range []struct {
+		name string
+		k    Keyframes
+	}{{"brightness", a.Brightness}, {"contrast", a.Contrast}, {"saturation", a.Saturation}} {
+		if len(p.k) > 0 {
+			opts = append(opts, fmt.Sprintf("%s='%s'", p.name, p.k.expr("t")))
+		}
+	}
+	if len(opts) == 0 {
+		return nil
+	}
+	return []string{"eq=" + strings.Join(opts, ":") + ":eval=frame"}
+}
+
+// zoomStep is the interval between a zoom's crop updates.
+const zoomStep = 1.0 / 50
+
+// zoomScript returns the sendcmd script resizing the crop named target over
+// a clip of duration seconds: crop's size cannot be an expression of time,
+// so the script sets it every zoomStep while the zoom changes.
+func (a *ClipAnimation) zoomScript(target string, width, height int, duration float64) string {
+	var b strings.Builder
+	end := math.Min(a.Zoom[len(a.Zoom)-1].Time, duration)
+	for t := math.Max(a.Zoom[0].Time, 0); ; t += zoomStep {
+		w, h := zoomSize(width, height, a.Zoom.At(t))
+		fmt.Fprintf(&b, "%.4f %s w %d, %s h %d;\n", t, target, w, target, h)
+		if t >= end {
+			break
+		}
+	}
+	return b.String()
+}
+
+// zoomSize is the crop that magnifies a width×height frame by zoom, rounded
+// down to even sizes for chroma subsampling.
+func zoomSize(width, height int, zoom float64) (int, int) {
+	return int(float64(width)/zoom) &^ 1, int(float64(height)/zoom) &^ 1
+}
+
+// zoomFilters crops the focus area, resized per script, and scales it back
+// to the frame size. The crop's position follows the focus every frame.
+func (a *ClipAnimation) zoomFilters(target, script string, width, height int) []string {
+	focus := func(k Keyframes) string {
+		if len(k) == 0 {
+			return "0.5"
+		}
+		return k.expr("t")
+	}
+	w, h := zoomSize(width, height, a.Zoom[0].Value)
+	return []string{
+		"sendcmd=f=" + filterPath(script),
+		fmt.Sprintf("%s=w=%d:h=%d:x='(in_w-out_w)*(%s)':y='(in_h-out_h)*(%s)'", target, w, h, focus(a.FocusX), focus(a.FocusY)),
+		fmt.Sprintf("scale=%d:%d", width, height),
+		"setsar=1",
+	}
+}
+
+// animateClips returns each clip's animated video filters. Zooms' sendcmd
+// scripts are written to files the caller removes, even on error.
+func animateClips(opts TimelineExportOptions) ([][]string, []string, error) {
+	filters := make([][]string, len(opts.Clips))
+	var files []string
+	for i, c := range opts.Clips {
+		if !c.Animate.IsSet() || !c.HasVideo {
+			continue
+		}
+		filters[i] = c.Animate.eqFilters()
+		if len(c.Animate.Zoom) == 0 {
+			continue
+		}
+		if c.Width <= 0 || c.Height <= 0 {
+			return nil, files, fmt.Errorf("clip[%d]: zoom needs the source's frame size", i)
+		}
+		f, err := os.CreateTemp("", "ffm_zoom_*.cmd")
+		if err != nil {
+			return nil, files, fmt.Errorf("clip[%d]: zoom script: %w", i, err)
+		}
+		files = append(files, f.Name())
+		// The instance name keeps the commands off any other crop in the
+		// graph.
+		target := fmt.Sprintf("crop@zoom%d", i)
+		_, err = f.WriteString(c.Animate.zoomScript(target, c.Width, c.Height, c.Duration))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, files, fmt.Errorf("clip[%d]: zoom script: %w", i, err)
+		}
+		filters[i] = append(filters[i], c.Animate.zoomFilters(target, f.Name(), c.Width, c.Height)...)
+	}
+	return filters, files, nil
+}
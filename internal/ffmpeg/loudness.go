@@ -0,0 +1,144 @@
+package ffmpeg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ─── Loudness (EBU R128) ──────────────────────────────────────────────────────
+
+// LoudnessTarget is an EBU R128 loudnorm target.
+type LoudnessTarget struct {
+	I   float64 `json:"i"`   // integrated loudness, LUFS
+	TP  float64 `json:"tp"`  // maximum true peak, dBTP
+	LRA float64 `json:"lra"` // loudness range, LU
+}
+
+// LoudnessPresets are the named targets accepted by the API.
+var LoudnessPresets = map[string]LoudnessTarget{
+	"podcast":   {I: -16, TP: -1.5, LRA: 11},
+	"streaming": {I: -14, TP: -1, LRA: 11},
+	"broadcast": {I: -23, TP: -1, LRA: 7},
+}
+
+// DefaultLoudnessTarget matches loudnorm's own defaults and is used when
+// Normalize is set without an explicit target.
+var DefaultLoudnessTarget = LoudnessTarget{I: -24, TP: -2, LRA: 7}
+
+// ResolveLoudnessTarget starts from the named preset (or the default) and
+// applies any explicit overrides.
+func ResolveLoudnessTarget(preset string, i, tp, lra *float64) LoudnessTarget {
+	t := DefaultLoudnessTarget
+	if p, ok := LoudnessPresets[preset]; ok {
+		t = p
+	}
+	if i != nil {
+		t.I = *i
+	}
+	if tp != nil {
+		t.TP = *tp
+	}
+	if lra != nil {
+		t.LRA = *lra
+	}
+	return t
+}
+
+// LoudnessMeasurement is the first-pass loudnorm analysis of a signal.
+type LoudnessMeasurement struct {
+	InputI       float64 `json:"input_i"`
+	InputTP      float64 `json:"input_tp"`
+	InputLRA     float64 `json:"input_lra"`
+	InputThresh  float64 `json:"input_thresh"`
+	TargetOffset float64 `json:"target_offset"`
+}
+
+// usable reports whether the measurement is inside the ranges loudnorm accepts
+// for its measured_* options. Silent input measures as -inf and is not usable.
+func (m *LoudnessMeasurement) usable() bool {
+	in := func(v, lo, hi float64) bool { return !math.IsNaN(v) && v >= lo && v <= hi }
+	return in(m.InputI, -99, 0) &&
+		in(m.InputTP, -99, 99) &&
+		in(m.InputLRA, 0, 99) &&
+		in(m.InputThresh, -99, 0) &&
+		in(m.TargetOffset, -99, 99)
+}
+
+// measureFilter is the first-pass filter that prints its analysis as JSON.
+func (t LoudnessTarget) measureFilter() string {
+	return fmt.Sprintf("loudnorm=I=%.1f:TP=%.1f:LRA=%.1f:print_format=json", t.I, t.TP, t.LRA)
+}
+
+// normalizeFilter is the second-pass filter. With a usable measurement it runs
+// in linear mode so the whole programme gets one gain; otherwise it falls back
+// to single-pass dynamic normalization. loudnorm always outputs 192 kHz, so the
+// result is resampled to 48 kHz for the encoder.
+func (t LoudnessTarget) normalizeFilter(m *LoudnessMeasurement) string {
+	if m == nil || !m.usable() {
+		return fmt.Sprintf("loudnorm=I=%.1f:TP=%.1f:LRA=%.1f,aresample=48000", t.I, t.TP, t.LRA)
+	}
+	return fmt.Sprintf(
+		"loudnorm=I=%.1f:TP=%.1f:LRA=%.1f:measured_I=%.2f:measured_TP=%.2f:measured_LRA=%.2f:measured_thresh=%.2f:offset=%.2f:linear=true,aresample=48000",
+		t.I, t.TP, t.LRA, m.InputI, m.InputTP, m.InputLRA, m.InputThresh, m.TargetOffset)
+}
+
+// loudnessPassWeight is the share of overall progress given to the analysis
+// pass of a two-pass normalization.
+const loudnessPassWeight = 0.25
+
+// MeasureLoudness runs a loudnorm analysis over the first audio stream of
+// inputPath. It is used to verify the loudness of finished outputs.
+func MeasureLoudness(ctx context.Context, ffmpegPath, inputPath string, target LoudnessTarget) (*LoudnessMeasurement, error) {
+	args := []string{"-i", inputPath, "-vn", "-af", target.measureFilter()}
+	return runLoudnessAnalysis(ctx, ffmpegPath, args, nil, nil)
+}
+
+// runLoudnessAnalysis decodes the audio described by args to a null sink. The
+// args must include a filter chain ending in target.measureFilter().
+func runLoudnessAnalysis(ctx context.Context, ffmpegPath string, args []string, totalDuration *float64, ph ProgressHandler) (*LoudnessMeasurement, error) {
+	full := append([]string{"-hide_banner", "-nostats"}, args...)
+	// loudnorm prints its report at info level.
+	full = append(full, "-progress", "pipe:1", "-v", "info", "-f", "null", "-")
+	stderr, err := runFFmpegCapture(ctx, ffmpegPath, full, totalDuration, ph)
+	if err != nil {
+		return nil, err
+	}
+	return parseLoudnormOutput(stderr)
+}
+
+// parseLoudnormOutput extracts the JSON block loudnorm prints when it finishes.
+func parseLoudnormOutput(stderr string) (*LoudnessMeasurement, error) {
+	start := strings.LastIndex(stderr, "{")
+	end := strings.LastIndex(stderr, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("loudnorm: no measurement in ffmpeg output")
+	}
+	var raw struct {
+		InputI       string `json:"input_i"`
+		InputTP      string `json:"input_tp"`
+		InputLRA     string `json:"input_lra"`
+		InputThresh  string `json:"input_thresh"`
+		TargetOffset string `json:"target_offset"`
+	}
+	if err := json.Unmarshal([]byte(stderr[start:end+1]), &raw); err != nil {
+		return nil, fmt.Errorf("loudnorm json parse failed: %w", err)
+	}
+	parse := func(s string) float64 {
+		v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return math.NaN()
+		}
+		return v
+	}
+	return &LoudnessMeasurement{
+		InputI:       parse(raw.InputI),
+		InputTP:      parse(raw.InputTP),
+		InputLRA:     parse(raw.InputLRA),
+		InputThresh:  parse(raw.InputThresh),
+		TargetOffset: parse(raw.TargetOffset),
+	}, nil
+}
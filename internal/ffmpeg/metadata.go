@@ -0,0 +1,180 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ─── Metadata and chapters ────────────────────────────────────────────────────
+
+// MetadataTags are the global tags a request can set.
+var MetadataTags = []string{"title", "artist", "album", "comment", "date"}
+
+// CoverArtFormats are the audio outputs that can carry cover art.
+var CoverArtFormats = []string{"mp3", "m4a", "flac"}
+
+// ChapterFormats are the outputs that can carry chapters.
+var ChapterFormats = []string{"mp4", "mkv"}
+
+// Chapter is a named range of a file, in seconds.
+type Chapter struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Title string  `json:"title,omitempty"`
+}
+
+// Metadata is written to a finished output by WriteMetadata.
+type Metadata struct {
+	// Tags maps MetadataTags to values; they replace the output's own.
+	Tags map[string]string
+	// CoverPath (may be "") is a JPEG or PNG attached as cover art.
+	CoverPath string
+	// Chapters replace the output's chapters. An End of 0 runs to the next
+	// chapter or the end of the file.
+	Chapters []Chapter
+	// FastStart keeps the MP4 index at the front of the file.
+	FastStart bool
+}
+
+// IsSet reports whether m is non-nil and writes anything.
+func (m *Metadata) IsSet() bool {
+	return m != nil && (len(m.Tags) > 0 || m.CoverPath != "" || len(m.Chapters) > 0)
+}
+
+// ClipChapters returns one chapter per timeline clip, at the clips' places in
+// the output. titles[i] (when present and not "") names clip i's chapter;
+// the others are "Chapter n".
+func ClipChapters(opts TimelineExportOptions, titles []string) []Chapter {
+	scale := 1.0
+	if opts.Speed != nil && *opts.Speed > 0 {
+		scale = 1 / *opts.Speed
+	}
+	chapters := make([]Chapter, 0, len(opts.Clips))
+	at := 0.0
+	for i, c := range opts.Clips {
+		d := c.OutputDuration() * scale
+		title := fmt.Sprintf("Chapter %d", i+1)
+		if i < len(titles) && titles[i] != "" {
+			title = titles[i]
+		}
+		chapters = append(chapters, Chapter{Start: at, End: at + d, Title: title})
+		at += d
+	}
+	return chapters
+}
+
+// ffmetadataEscaper escapes the characters FFMETADATA files treat specially.
+var ffmetadataEscaper = strings.NewReplacer(`\`, `\\`, "=", `\=`, ";", `\;`, "#", `\#`, "\n", "\\\n")
+
+// ffmetadata returns chapters as an FFMETADATA file, with the open ends
+// closed at the next chapter or at duration and no end past duration.
+func ffmetadata(chapters []Chapter, duration float64) string {
+	var b strings.Builder
+	b.WriteString(";FFMETADATA1\n")
+	for i, ch := range chapters {
+		end := ch.End
+		if end <= 0 {
+			end = duration
+			if i+1 < len(chapters) {
+				end = chapters[i+1].Start
+			}
+		}
+		if duration > 0 && end > duration {
+			end = duration
+		}
+		fmt.Fprintf(&b, "[CHAPTER]\nTIMEBASE=1/1000\nSTART=%d\nEND=%d\ntitle=%s\n",
+			int64(math.Round(ch.Start*1000)), int64(math.Round(end*1000)), ffmetadataEscaper.Replace(ch.Title))
+	}
+	return b.String()
+}
+
+// WriteMetadata rewrites path with m's tags, cover art and chapters. Every
+// stream is copied, so it takes a moment even for long files. Chapters must
+// start before the end of the output; their ends are cut to it.
+func WriteMetadata(ctx context.Context, ffmpegPath, ffprobePath, path string, m *Metadata, ph ProgressHandler) error {
+	if !m.IsSet() {
+		return nil
+	}
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	info, err := GetMediaInfo(ctx, ffprobePath, path)
+	if err != nil {
+		return fmt.Errorf("writing metadata: %w", err)
+	}
+	duration := 0.0
+	if info.Duration != nil {
+		duration = *info.Duration
+	}
+	args := []string{"-i", path}
+	inputs := 1
+
+	chapterInput := -1
+	if len(m.Chapters) > 0 {
+		for i, ch := range m.Chapters {
+			if duration > 0 && ch.Start >= duration {
+				return fmt.Errorf("chapters[%d] starts at %.2fs, past the end of the output (%.2fs)", i, ch.Start, duration)
+			}
+		}
+		f, err := os.CreateTemp("", "ffm_chapters_*.txt")
+		if err != nil {
+			return fmt.Errorf("chapters: %w", err)
+		}
+		defer os.Remove(f.Name())
+		_, err = f.WriteString(ffmetadata(m.Chapters, duration))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("chapters: %w", err)
+		}
+		args = append(args, "-f", "ffmetadata", "-i", f.Name())
+		chapterInput = inputs
+		inputs++
+	}
+	coverInput := -1
+	if m.CoverPath != "" {
+		args = append(args, "-i", m.CoverPath)
+		coverInput = inputs
+	}
+
+	args = append(args, "-map", "0")
+	if coverInput >= 0 {
+		// The new cover replaces any the output already has.
+		args = append(args, "-map", "-0:v", "-map", fmt.Sprintf("%d:v", coverInput))
+	}
+	args = append(args, "-c", "copy")
+	if coverInput >= 0 {
+		args = append(args, "-disposition:v", "attached_pic")
+		if format == "mp3" {
+			// ID3v2.3 is what most players read cover art from.
+			args = append(args, "-id3v2_version", "3", "-metadata:s:v", "title=Album cover", "-metadata:s:v", "comment=Cover (front)")
+		}
+	}
+	if chapterInput >= 0 {
+		args = append(args, "-map_chapters", fmt.Sprintf("%d", chapterInput))
+	}
+	for _, key := range MetadataTags {
+		if v, ok := m.Tags[key]; ok {
+			args = append(args, "-metadata", key+"="+v)
+		}
+	}
+	if m.FastStart && format == "mp4" {
+		args = append(args, "-movflags", "+faststart")
+	}
+
+	// The output keeps its extension so ffmpeg picks the same muxer.
+	tmp := filepath.Join(filepath.Dir(path), ".meta_"+filepath.Base(path))
+	args = append(args, "-progress", "pipe:1", "-v", "warning", "-y", tmp)
+	if err := runFFmpeg(ctx, ffmpegPath, args, &duration, ph); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("writing metadata: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("writing metadata: %w", err)
+	}
+	return nil
+}
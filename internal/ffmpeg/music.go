@@ -0,0 +1,68 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ─── Music ducking ────────────────────────────────────────────────────────────
+
+// MusicBed is a music track mixed under a timeline's audio. sidechaincompress
+// ducks it whenever the timeline audio (the dialogue) is above Threshold.
+type MusicBed struct {
+	FilePath  string
+	Gain      float64 // music level before ducking, dB
+	Threshold float64 // dialogue level that starts ducking, dB
+	Ratio     float64 // 1-20
+	Attack    float64 // ms
+	Release   float64 // ms
+	// Loop repeats a track shorter than the timeline.
+	Loop bool
+}
+
+// DefaultMusicBed holds the settings a request leaves out: the music sits
+// 12 dB down and ducks hard while anyone speaks, recovering over 0.4 s.
+var DefaultMusicBed = MusicBed{Gain: -12, Threshold: -30, Ratio: 8, Attack: 20, Release: 400}
+
+// ResolveMusicBed applies any explicit settings to DefaultMusicBed.
+func ResolveMusicBed(path string, gain, threshold, ratio, attack, release *float64, loop bool) MusicBed {
+	m := DefaultMusicBed
+	m.FilePath, m.Loop = path, loop
+	if gain != nil {
+		m.Gain = *gain
+	}
+	if threshold != nil {
+		m.Threshold = *threshold
+	}
+	if ratio != nil {
+		m.Ratio = *ratio
+	}
+	if attack != nil {
+		m.Attack = *attack
+	}
+	if release != nil {
+		m.Release = *release
+	}
+	return m
+}
+
+// inputArgs returns the music input; it follows the clip inputs.
+func (m *MusicBed) inputArgs() []string {
+	if m.Loop {
+		return []string{"-stream_loop", "-1", "-i", m.FilePath}
+	}
+	return []string{"-i", m.FilePath}
+}
+
+// mixGraph ducks input idx under the timeline audio [in] and mixes the two
+// into [out]. The mix is as long as the timeline, and normalize=0 keeps the
+// dialogue at its own level.
+func (m *MusicBed) mixGraph(idx int, in, out string) string {
+	var fc strings.Builder
+	fmt.Fprintf(&fc, "%sasplit=2[dlg][key];", in)
+	fmt.Fprintf(&fc, "[%d:a]aformat=channel_layouts=stereo,volume=%.2fdB[mus];", idx, m.Gain)
+	fmt.Fprintf(&fc, "[mus][key]sidechaincompress=threshold=%.5f:ratio=%.2f:attack=%.2f:release=%.2f[ducked];",
+		dbToLinear(m.Threshold), m.Ratio, m.Attack, m.Release)
+	fmt.Fprintf(&fc, "[dlg][ducked]amix=inputs=2:duration=first:normalize=0%s", out)
+	return fc.String()
+}
@@ -0,0 +1,96 @@
+package ffmpeg
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ─── Progress ─────────────────────────────────────────────────────────────────
+
+// ProgressEvent is one block of ffmpeg's -progress output. Progress is
+// normalised to [0,1] over the whole operation (multi-pass operations rescale
+// it); the other fields are ffmpeg's counters for the current pass.
+type ProgressEvent struct {
+	Progress    float64 `json:"progress"`
+	OutTimeMs   float64 `json:"out_time_ms"` // raw out_time_ms; ffmpeg reports microseconds
+	Frame       int64   `json:"frame"`
+	FPS         float64 `json:"fps"`
+	BitrateKbps float64 `json:"bitrate_kbps"`
+	TotalSize   int64   `json:"total_size"`
+	Speed       float64 `json:"speed"`
+	DupFrames   int64   `json:"dup_frames"`
+	DropFrames  int64   `json:"drop_frames"`
+	Done        bool    `json:"done"` // progress=end
+}
+
+// ProgressHandler receives one event per progress block.
+type ProgressHandler func(ProgressEvent)
+
+// progressParser accumulates key=value lines until the "progress=" line that
+// closes each block.
+type progressParser struct {
+	totalDuration *float64
+	ev            ProgressEvent
+}
+
+// parseLine consumes one line and returns the completed event at the end of a
+// block.
+func (p *progressParser) parseLine(line string) (ProgressEvent, bool) {
+	key, val, ok := strings.Cut(strings.TrimSpace(line), "=")
+	if !ok {
+		return ProgressEvent{}, false
+	}
+	val = strings.TrimSpace(val)
+	switch key {
+	case "frame":
+		p.ev.Frame, _ = strconv.ParseInt(val, 10, 64)
+	case "fps":
+		p.ev.FPS, _ = strconv.ParseFloat(val, 64)
+	case "bitrate": // "1234.5kbits/s" or "N/A"
+		p.ev.BitrateKbps, _ = strconv.ParseFloat(strings.TrimSuffix(val, "kbits/s"), 64)
+	case "total_size":
+		p.ev.TotalSize, _ = strconv.ParseInt(val, 10, 64)
+	case "out_time_ms":
+		if v, err := strconv.ParseFloat(val, 64); err == nil && v >= 0 {
+			p.ev.OutTimeMs = v
+		}
+	case "dup_frames":
+		p.ev.DupFrames, _ = strconv.ParseInt(val, 10, 64)
+	case "drop_frames":
+		p.ev.DropFrames, _ = strconv.ParseInt(val, 10, 64)
+	case "speed": // "1.23x" or "N/A"
+		p.ev.Speed, _ = strconv.ParseFloat(strings.TrimSuffix(val, "x"), 64)
+	case "progress":
+		ev := p.ev
+		ev.Done = val == "end"
+		if d := p.totalDuration; d != nil && *d > 0 {
+			ev.Progress = clamp01(ev.OutTimeMs / 1e6 / *d)
+		}
+		if ev.Done && ev.Progress > 0 {
+			ev.Progress = 1
+		}
+		return ev, true
+	}
+	return ProgressEvent{}, false
+}
+
+// progressRange maps a pass's [0,1] progress into [from, to] of the whole job.
+func progressRange(ph ProgressHandler, from, to float64) ProgressHandler {
+	if ph == nil {
+		return nil
+	}
+	return func(ev ProgressEvent) {
+		ev.Progress = from + ev.Progress*(to-from)
+		ph(ev)
+	}
+}
+
+func clamp01(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
@@ -0,0 +1,254 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ─── Quality control ──────────────────────────────────────────────────────────
+
+// QCOptions configures RunQC. Zero detector values use the defaults; the Max*
+// thresholds decide which findings are reported as issues.
+type QCOptions struct {
+	HasVideo   bool
+	HasAudio   bool
+	Duration   float64
+	Channels   int
+	SampleRate int
+
+	BlackMinSec         float64 // blackdetect d, default 0.5 s
+	BlackPixelThreshold float64 // blackdetect pix_th, default 0.10
+	FreezeNoiseDB       float64 // freezedetect n, default -60 dB
+	FreezeMinSec        float64 // freezedetect d, default 2 s
+	SilenceDB           float64 // silencedetect n, default -50 dB
+	SilenceMinSec       float64 // silencedetect d, default 2 s
+
+	Thresholds QCThresholds
+}
+
+// QCThresholds are the longest tolerated black, frozen and silent segments and
+// the number of clipped samples tolerated. Zero means any finding is an issue.
+type QCThresholds struct {
+	MaxBlackSec       float64 `json:"max_black_secs"`
+	MaxFreezeSec      float64 `json:"max_freeze_secs"`
+	MaxSilenceSec     float64 `json:"max_silence_secs"`
+	MaxClippedSamples int64   `json:"max_clipped_samples"`
+}
+
+// QCIssue is one threshold violation.
+type QCIssue struct {
+	Kind    string      `json:"kind"` // "black" | "freeze" | "silence" | "clipping"
+	Message string      `json:"message"`
+	Ranges  []TimeRange `json:"ranges"`
+}
+
+// QCReport is the result of RunQC.
+type QCReport struct {
+	Passed         bool         `json:"passed"`
+	Duration       float64      `json:"duration"`
+	Thresholds     QCThresholds `json:"thresholds"`
+	BlackSegments  []TimeRange  `json:"black_segments"`
+	FreezeSegments []TimeRange  `json:"freeze_segments"`
+	Silences       []TimeRange  `json:"silences"`
+	ClippedSamples int64        `json:"clipped_samples"`
+	ClippingRanges []TimeRange  `json:"clipping_ranges"`
+	IntegratedLUFS *float64     `json:"integrated_lufs,omitempty"`
+	TruePeakDBFS   *float64     `json:"true_peak_dbfs,omitempty"`
+	Issues         []QCIssue    `json:"issues"`
+}
+
+// qcVideoPassWeight is the share of progress given to the video detectors when
+// the audio is also analysed.
+const qcVideoPassWeight = 0.7
+
+// RunQC scans inputPath for black and frozen video (blackdetect, freezedetect)
+// and for silence and clipping in the audio (AnalyzeAudio), then evaluates the
+// findings against opts.Thresholds.
+func RunQC(ctx context.Context, ffmpegPath, inputPath string, opts QCOptions, ph ProgressHandler) (*QCReport, error) {
+	report := &QCReport{
+		Duration:       opts.Duration,
+		Thresholds:     opts.Thresholds,
+		BlackSegments:  []TimeRange{},
+		FreezeSegments: []TimeRange{},
+		Silences:       []TimeRange{},
+		ClippingRanges: []TimeRange{},
+	}
+
+	videoShare := 1.0
+	if opts.HasAudio {
+		videoShare = qcVideoPassWeight
+	}
+	if opts.HasVideo {
+		black, freeze, err := detectVideoDefects(ctx, ffmpegPath, inputPath, opts, progressRange(ph, 0, videoShare))
+		if err != nil {
+			return nil, err
+		}
+		report.BlackSegments, report.FreezeSegments = black, freeze
+	}
+	if opts.HasAudio {
+		audioFrom := 0.0
+		if opts.HasVideo {
+			audioFrom = videoShare
+		}
+		silenceDB := opts.SilenceDB
+		if silenceDB == 0 {
+			silenceDB = -50
+		}
+		silenceMin := opts.SilenceMinSec
+		if silenceMin <= 0 {
+			silenceMin = 2
+		}
+		a, err := AnalyzeAudio(ctx, ffmpegPath, inputPath, AudioAnalysisOptions{
+			Channels:      opts.Channels,
+			SampleRate:    opts.SampleRate,
+			Duration:      opts.Duration,
+			SilenceDB:     silenceDB,
+			SilenceMinSec: silenceMin,
+		}, progressRange(ph, audioFrom, 1))
+		if err != nil {
+			return nil, fmt.Errorf("qc: %w", err)
+		}
+		report.Silences = a.Silences
+		report.ClippedSamples = a.ClippedSamples
+		report.ClippingRanges = a.ClippingRanges
+		report.IntegratedLUFS = &a.IntegratedLUFS
+		report.TruePeakDBFS = &a.TruePeakDBFS
+		if report.Duration <= 0 {
+			report.Duration = a.Duration
+		}
+	}
+	if ph != nil {
+		ph(ProgressEvent{Progress: 1, OutTimeMs: report.Duration * 1e6, Done: true})
+	}
+
+	report.Evaluate()
+	return report, nil
+}
+
+// Evaluate fills Issues and Passed from the detected segments and Thresholds.
+func (r *QCReport) Evaluate() {
+	r.Issues = []QCIssue{}
+	segments := func(kind, label string, ranges []TimeRange, max float64) {
+		var over []TimeRange
+		for _, s := range ranges {
+			if s.Duration() > max {
+				over = append(over, s)
+			}
+		}
+		if len(over) > 0 {
+			r.Issues = append(r.Issues, QCIssue{
+				Kind:    kind,
+				Message: fmt.Sprintf("%d %s segment(s) longer than %.1fs", len(over), label, max),
+				Ranges:  over,
+			})
+		}
+	}
+	segments("black", "black", r.BlackSegments, r.Thresholds.MaxBlackSec)
+	segments("freeze", "frozen", r.FreezeSegments, r.Thresholds.MaxFreezeSec)
+	segments("silence", "silent", r.Silences, r.Thresholds.MaxSilenceSec)
+	if r.ClippedSamples > r.Thresholds.MaxClippedSamples {
+		r.Issues = append(r.Issues, QCIssue{
+			Kind:    "clipping",
+			Message: fmt.Sprintf("%d clipped sample(s), %d tolerated", r.ClippedSamples, r.Thresholds.MaxClippedSamples),
+			Ranges:  r.ClippingRanges,
+		})
+	}
+	r.Passed = len(r.Issues) == 0
+}
+
+// Summary is a one-line description of the issues, for logs and job errors.
+func (r *QCReport) Summary() string {
+	if r.Passed {
+		return "QC passed"
+	}
+	msgs := make([]string, len(r.Issues))
+	for i, is := range r.Issues {
+		msgs[i] = is.Message
+	}
+	return "QC failed: " + strings.Join(msgs, "; ")
+}
+
+func detectVideoDefects(ctx context.Context, ffmpegPath, inputPath string, opts QCOptions, ph ProgressHandler) (black, freeze []TimeRange, err error) {
+	blackMin := opts.BlackMinSec
+	if blackMin <= 0 {
+		blackMin = 0.5
+	}
+	pixTh := opts.BlackPixelThreshold
+	if pixTh <= 0 {
+		pixTh = 0.10
+	}
+	freezeNoise := opts.FreezeNoiseDB
+	if freezeNoise == 0 {
+		freezeNoise = -60
+	}
+	freezeMin := opts.FreezeMinSec
+	if freezeMin <= 0 {
+		freezeMin = 2
+	}
+	args := []string{
+		"-hide_banner", "-nostats",
+		"-i", inputPath,
+		"-an",
+		"-vf", fmt.Sprintf("blackdetect=d=%.3f:pix_th=%.3f,freezedetect=n=%.1fdB:d=%.3f", blackMin, pixTh, freezeNoise, freezeMin),
+		"-progress", "pipe:1", "-v", "info",
+		"-f", "null", "-",
+	}
+	duration := opts.Duration
+	parser := &videoDefectParser{}
+	if _, err := runFFmpegScan(ctx, ffmpegPath, args, &duration, ph, parser.parseLine); err != nil {
+		return nil, nil, fmt.Errorf("qc video scan: %w", err)
+	}
+	black = parser.black
+	if black == nil {
+		black = []TimeRange{}
+	}
+	return black, parser.finishFreeze(duration), nil
+}
+
+// videoDefectParser collects blackdetect's "black_start:… black_end:…" lines
+// and freezedetect's lavfi.freezedetect.freeze_start/freeze_end metadata.
+type videoDefectParser struct {
+	black       []TimeRange
+	freeze      []TimeRange
+	freezeStart *float64
+}
+
+func (p *videoDefectParser) parseLine(line string) {
+	if i := strings.Index(line, "black_start:"); i >= 0 {
+		start, ok1 := leadingFloat(line[i+len("black_start:"):])
+		j := strings.Index(line, "black_end:")
+		if j < 0 || !ok1 {
+			return
+		}
+		if end, ok := leadingFloat(line[j+len("black_end:"):]); ok {
+			p.black = append(p.black, TimeRange{Start: start, End: end})
+		}
+		return
+	}
+	if i := strings.Index(line, "freeze_start:"); i >= 0 {
+		if v, ok := leadingFloat(line[i+len("freeze_start:"):]); ok {
+			p.freezeStart = &v
+		}
+		return
+	}
+	if i := strings.Index(line, "freeze_end:"); i >= 0 && p.freezeStart != nil {
+		if v, ok := leadingFloat(line[i+len("freeze_end:"):]); ok {
+			p.freeze = append(p.freeze, TimeRange{Start: *p.freezeStart, End: v})
+		}
+		p.freezeStart = nil
+	}
+}
+
+// finishFreeze closes a freeze that lasts to the end of the stream;
+// freezedetect reports no freeze_end for it.
+func (p *videoDefectParser) finishFreeze(duration float64) []TimeRange {
+	if p.freezeStart != nil && duration > *p.freezeStart {
+		p.freeze = append(p.freeze, TimeRange{Start: *p.freezeStart, End: duration})
+		p.freezeStart = nil
+	}
+	if p.freeze == nil {
+		return []TimeRange{}
+	}
+	return p.freeze
+}
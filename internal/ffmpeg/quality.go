@@ -0,0 +1,136 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ─── Objective quality (PSNR / SSIM / VMAF) ───────────────────────────────────
+
+// QualityReference is one span of a source file; the spans are concatenated
+// to rebuild the reference for an output.
+type QualityReference struct {
+	Path     string
+	Start    float64 // 0 = from the beginning
+	Duration float64 // 0 = to the end
+}
+
+// QualityOptions configures MeasureQuality. The references are scaled to
+// Width x Height (the output's resolution) so both sides are compared frame
+// for frame at the same size.
+type QualityOptions struct {
+	FFmpegPath    string
+	DistortedPath string
+	References    []QualityReference
+	Width         int
+	Height        int
+	Duration      float64 // output duration, for progress
+	VMAF          bool    // also run libvmaf; requires an ffmpeg built with it
+}
+
+// QualityScores are the averages over all frames. A score is nil when it was
+// not computed or not finite (PSNR of identical frames is infinite).
+type QualityScores struct {
+	PSNR *float64 `json:"psnr,omitempty"` // dB, average over Y/U/V
+	SSIM *float64 `json:"ssim,omitempty"` // 0..1, all planes
+	VMAF *float64 `json:"vmaf,omitempty"` // 0..100
+}
+
+// MeasureQuality compares the output at opts.DistortedPath with the reference
+// spans and returns PSNR, SSIM and optionally VMAF.
+func MeasureQuality(ctx context.Context, opts QualityOptions, ph ProgressHandler) (*QualityScores, error) {
+	if len(opts.References) == 0 {
+		return nil, fmt.Errorf("quality: no reference")
+	}
+	if opts.Width <= 0 || opts.Height <= 0 {
+		return nil, fmt.Errorf("quality: output resolution unknown")
+	}
+	args := []string{"-hide_banner", "-nostats", "-i", opts.DistortedPath}
+	for _, ref := range opts.References {
+		if ref.Start > 0 {
+			args = append(args, "-ss", fmt.Sprintf("%.6f", ref.Start))
+		}
+		if ref.Duration > 0 {
+			args = append(args, "-t", fmt.Sprintf("%.6f", ref.Duration))
+		}
+		args = append(args, "-i", ref.Path)
+	}
+	args = append(args,
+		"-filter_complex", buildQualityGraph(len(opts.References), opts.Width, opts.Height, opts.VMAF),
+		"-map", "[out]",
+		"-progress", "pipe:1", "-v", "info",
+		"-f", "null", "-",
+	)
+
+	parser := &qualityParser{}
+	duration := opts.Duration
+	if _, err := runFFmpegScan(ctx, opts.FFmpegPath, args, &duration, ph, parser.parseLine); err != nil {
+		return nil, fmt.Errorf("quality: %w", err)
+	}
+	if parser.scores.PSNR == nil && parser.scores.SSIM == nil {
+		return nil, fmt.Errorf("quality: no scores in ffmpeg output")
+	}
+	return &parser.scores, nil
+}
+
+// buildQualityGraph rebuilds the reference from inputs 1..refs (scaled to the
+// output size and concatenated) and chains psnr → ssim → libvmaf with the
+// output (input 0) as the main input. Timestamps are reset on both sides so
+// frames pair up from the first one.
+func buildQualityGraph(refs, width, height int, vmaf bool) string {
+	var fc strings.Builder
+	fc.WriteString("[0:v]setsar=1,setpts=PTS-STARTPTS,format=yuv420p[dist];")
+	for i := 1; i <= refs; i++ {
+		fmt.Fprintf(&fc, "[%d:v]scale=%d:%d:flags=bicubic,setsar=1,setpts=PTS-STARTPTS,format=yuv420p[r%d];", i, width, height, i)
+	}
+	if refs > 1 {
+		for i := 1; i <= refs; i++ {
+			fmt.Fprintf(&fc, "[r%d]", i)
+		}
+		fmt.Fprintf(&fc, "concat=n=%d:v=1:a=0,setpts=PTS-STARTPTS[ref];", refs)
+	} else {
+		fc.WriteString("[r1]null[ref];")
+	}
+	if vmaf {
+		fc.WriteString("[ref]split=3[ref1][ref2][ref3];")
+	} else {
+		fc.WriteString("[ref]split=2[ref1][ref2];")
+	}
+	fc.WriteString("[dist][ref1]psnr[p];[p][ref2]ssim")
+	if vmaf {
+		fc.WriteString("[s];[s][ref3]libvmaf")
+	}
+	fc.WriteString("[out]")
+	return fc.String()
+}
+
+// qualityParser picks the summary lines psnr, ssim and libvmaf log at the end:
+//
+//	PSNR y:40.1 u:44.1 v:44.6 average:41.3 min:38.2 max:45.0
+//	SSIM Y:0.990 (20.0) U:0.992 (21.0) V:0.993 (21.5) All:0.991 (20.5)
+//	VMAF score: 95.12
+type qualityParser struct {
+	scores QualityScores
+}
+
+func (p *qualityParser) parseLine(line string) {
+	switch {
+	case strings.Contains(line, "PSNR ") && strings.Contains(line, "average:"):
+		p.scores.PSNR = qualityValue(line, "average:")
+	case strings.Contains(line, "SSIM ") && strings.Contains(line, "All:"):
+		p.scores.SSIM = qualityValue(line, "All:")
+	case strings.Contains(line, "VMAF score:"):
+		p.scores.VMAF = qualityValue(line, "VMAF score:")
+	}
+}
+
+func qualityValue(line, key string) *float64 {
+	i := strings.Index(line, key)
+	v, ok := leadingFloat(line[i+len(key):])
+	if !ok || math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
@@ -0,0 +1,236 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ─── Clip retiming ────────────────────────────────────────────────────────────
+
+// MaxReverseDuration bounds a reversed clip: reverse and areverse hold the
+// whole clip in memory.
+const MaxReverseDuration = 30.0
+
+// SpeedKeyframe sets a clip's speed at Time, in seconds into the clip at
+// normal speed (after Reverse). Speed changes linearly between keyframes and
+// holds before the first and after the last.
+type SpeedKeyframe struct {
+	Time  float64
+	Speed float64 // 0.1 … 10
+}
+
+// FreezeFrame holds the frame At seconds into the played clip (after Reverse
+// and the ramp) for Hold seconds; the audio is silent meanwhile.
+type FreezeFrame struct {
+	At   float64
+	Hold float64
+}
+
+// retimed reports whether the clip plays other than straight through.
+func (c TimelineExportClip) retimed() bool {
+	return c.Reverse || len(c.Ramp) > 0 || c.Freeze != nil
+}
+
+// OutputDuration is the clip's length in the timeline before the timeline's
+// own speed: its duration through the ramp, plus any freeze.
+func (c TimelineExportClip) OutputDuration() float64 {
+	d := c.Duration
+	if len(c.Ramp) > 0 {
+		d = 0
+		for _, s := range rampSegments(c.Ramp, c.Duration) {
+			d += s.outDuration()
+		}
+	}
+	if c.Freeze != nil {
+		d += c.Freeze.Hold
+	}
+	return d
+}
+
+// outputDuration is the length of the exported timeline.
+func (opts TimelineExportOptions) outputDuration() float64 {
+	d := 0.0
+	for _, c := range opts.Clips {
+		d += c.OutputDuration()
+	}
+	if opts.Speed != nil && *opts.Speed > 0 {
+		d /= *opts.Speed
+	}
+	return d
+}
+
+// rampSegment is a stretch of clip time [start, end) over which the speed
+// changes linearly from from to to.
+type rampSegment struct{ start, end, from, to float64 }
+
+// outDuration is the time the segment takes to play: the integral of
+// 1/speed over it.
+func (s rampSegment) outDuration() float64 {
+	if math.Abs(s.to-s.from) < 1e-9 {
+		return (s.end - s.start) / s.from
+	}
+	return (s.end - s.start) * math.Log(s.to/s.from) / (s.to - s.from)
+}
+
+// ptsExpr is the segment's output time at input time T, counted from the
+// segment start: (T-start)/from at constant speed, otherwise
+// ln(speed(T)/from) / slope.
+func (s rampSegment) ptsExpr() string {
+	if math.Abs(s.to-s.from) < 1e-9 {
+		return fmt.Sprintf("(T-%.6f)/%.6f", s.start, s.from)
+	}
+	slope := (s.to - s.from) / (s.end - s.start)
+	return fmt.Sprintf("log((%.6f+(T-%.6f)*(%.6f))/%.6f)/(%.6f)", s.from, s.start, slope, s.from, slope)
+}
+
+// rampSegments splits [0, duration) at the keyframes, which must be sorted.
+func rampSegments(ramp []SpeedKeyframe, duration float64) []rampSegment {
+	speedAt := func(t float64) float64 {
+		if t <= ramp[0].Time {
+			return ramp[0].Speed
+		}
+		for i := 1; i < len(ramp); i++ {
+			if t <= ramp[i].Time {
+				a, b := ramp[i-1], ramp[i]
+				return a.Speed + (b.Speed-a.Speed)*(t-a.Time)/(b.Time-a.Time)
+			}
+		}
+		return ramp[len(ramp)-1].Speed
+	}
+	cuts := []float64{0}
+	for _, k := range ramp {
+		if k.Time > cuts[len(cuts)-1] && k.Time < duration {
+			cuts = append(cuts, k.Time)
+		}
+	}
+	cuts = append(cuts, duration)
+	var segs []rampSegment
+	for i := 1; i < len(cuts); i++ {
+		segs = append(segs, rampSegment{start: cuts[i-1], end: cuts[i], from: speedAt(cuts[i-1]), to: speedAt(cuts[i])})
+	}
+	return segs
+}
+
+// rampPTS is the setpts expression playing the clip through its ramp.
+func rampPTS(segs []rampSegment) string {
+	expr := ""
+	offset := 0.0
+	for i, s := range segs {
+		term := fmt.Sprintf("%.6f+%s", offset, s.ptsExpr())
+		if i == len(segs)-1 {
+			expr += term + strings.Repeat(")", i)
+		} else {
+			expr += fmt.Sprintf("if(lt(T,%.6f),%s,", s.end, term)
+		}
+		offset += s.outDuration()
+	}
+	return fmt.Sprintf("setpts='(%s)/TB'", expr)
+}
+
+// clipChain writes one clip's video or audio chain to fc, from in to out:
+// pre, then the clip's reverse, ramp and freeze, then post. The ramp and
+// freeze split the stream, so labels derived from out name the pieces.
+func (c TimelineExportClip) clipChain(fc *strings.Builder, audio bool, in, out string, pre, post []string) {
+	prefix := strings.TrimSuffix(out, "]")
+	cur, chain := in, append([]string(nil), pre...)
+	piece := 0
+	label := func() string {
+		piece++
+		return fmt.Sprintf("%s_%d]", prefix, piece)
+	}
+	// flush ends the pending chain in a split into n labels (or one label
+	// when n is 1) and returns them.
+	flush := func(n int) []string {
+		outs := make([]string, n)
+		for i := range outs {
+			outs[i] = label()
+		}
+		filters := chain
+		if n > 1 {
+			filters = append(filters, fmt.Sprintf("%s=%d", pick(audio, "asplit", "split"), n))
+		}
+		if len(filters) == 0 {
+			filters = []string{pick(audio, "anull", "null")}
+		}
+		fmt.Fprintf(fc, "%s%s%s;", cur, strings.Join(filters, ","), strings.Join(outs, ""))
+		chain = nil
+		return outs
+	}
+	concat := func(parts []string) {
+		cur = label()
+		v, a := 1, 0
+		if audio {
+			v, a = 0, 1
+		}
+		fmt.Fprintf(fc, "%sconcat=n=%d:v=%d:a=%d%s;", strings.Join(parts, ""), len(parts), v, a, cur)
+	}
+
+	if c.Reverse {
+		chain = append(chain, pick(audio, "areverse", "reverse"))
+	}
+	segs := []rampSegment(nil)
+	if len(c.Ramp) > 0 {
+		segs = rampSegments(c.Ramp, c.Duration)
+	}
+	switch {
+	case len(segs) > 0 && !audio:
+		chain = append(chain, rampPTS(segs))
+	case len(segs) == 1:
+		chain = append(chain, buildAtempoChain((segs[0].end-segs[0].start)/segs[0].outDuration())...)
+	case len(segs) > 1:
+		// atempo cannot change speed over time: each segment plays at the
+		// tempo that gives it the video's duration.
+		parts := flush(len(segs))
+		for i, s := range segs {
+			f := []string{fmt.Sprintf("atrim=start=%.6f:end=%.6f", s.start, s.end), "asetpts=PTS-STARTPTS"}
+			f = append(f, buildAtempoChain((s.end-s.start)/s.outDuration())...)
+			o := label()
+			fmt.Fprintf(fc, "%s%s%s;", parts[i], strings.Join(f, ","), o)
+			parts[i] = o
+		}
+		concat(parts)
+	}
+	if f := c.Freeze; f != nil {
+		played := c.OutputDuration() - f.Hold
+		at := math.Min(math.Max(f.At, 0), played)
+		switch {
+		case at <= 0:
+			chain = append(chain, freezePad(audio, "start", f.Hold))
+		case at >= played-0.001:
+			chain = append(chain, freezePad(audio, "stop", f.Hold))
+		default:
+			parts := flush(2)
+			head, tail := label(), label()
+			fmt.Fprintf(fc, "%s%s=end=%.6f%s;", parts[0], pick(audio, "atrim", "trim"), at, head)
+			fmt.Fprintf(fc, "%s%s=start=%.6f,%s,%s%s;", parts[1], pick(audio, "atrim", "trim"), at,
+				pick(audio, "asetpts", "setpts")+"=PTS-STARTPTS", freezePad(audio, "start", f.Hold), tail)
+			concat([]string{head, tail})
+		}
+	}
+
+	chain = append(chain, post...)
+	if len(chain) == 0 {
+		chain = []string{pick(audio, "anull", "null")}
+	}
+	fmt.Fprintf(fc, "%s%s%s;", cur, strings.Join(chain, ","), out)
+}
+
+// freezePad holds the first (at "start") or last (at "stop") frame for hold
+// seconds, or adds that much silence.
+func freezePad(audio bool, at string, hold float64) string {
+	if !audio {
+		return fmt.Sprintf("tpad=%s_mode=clone:%s_duration=%.6f", at, at, hold)
+	}
+	if at == "start" {
+		return fmt.Sprintf("adelay=delays=%.0f:all=1", hold*1000)
+	}
+	return fmt.Sprintf("apad=pad_dur=%.6f", hold)
+}
+
+func pick(audio bool, a, v string) string {
+	if audio {
+		return a
+	}
+	return v
+}
@@ -0,0 +1,88 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ─── Scene detection ──────────────────────────────────────────────────────────
+
+// SceneCut is a detected shot change.
+type SceneCut struct {
+	Time  float64 `json:"time"`
+	Score float64 `json:"score"` // ffmpeg scene score, 0..1
+}
+
+// DefaultSceneThreshold is the select scene score above which a frame starts a
+// new shot.
+const DefaultSceneThreshold = 0.3
+
+// DetectScenes runs ffmpeg's scene-change scoring over the first video stream
+// of inputPath and returns every frame whose score exceeds threshold. Frames
+// are downscaled first; the score is insensitive to resolution.
+func DetectScenes(ctx context.Context, ffmpegPath, inputPath string, threshold, duration float64, ph ProgressHandler) ([]SceneCut, error) {
+	if threshold <= 0 {
+		threshold = DefaultSceneThreshold
+	}
+	args := []string{
+		"-hide_banner", "-nostats",
+		"-i", inputPath,
+		"-an",
+		"-vf", fmt.Sprintf("scale=320:-2,select='gt(scene,%.3f)',metadata=print", threshold),
+		"-progress", "pipe:1", "-v", "info",
+		"-f", "null", "-",
+	}
+	parser := &sceneParser{}
+	if _, err := runFFmpegScan(ctx, ffmpegPath, args, &duration, ph, parser.parseLine); err != nil {
+		return nil, fmt.Errorf("scene detection: %w", err)
+	}
+	if parser.cuts == nil {
+		return []SceneCut{}, nil
+	}
+	return parser.cuts, nil
+}
+
+// ScenesFromCuts splits [0, duration) at each cut. Cuts closer than minScene to
+// the previous boundary are folded into the running scene.
+func ScenesFromCuts(cuts []SceneCut, duration, minScene float64) []TimeRange {
+	scenes := []TimeRange{}
+	start := 0.0
+	for _, c := range cuts {
+		if c.Time-start < minScene || c.Time >= duration {
+			continue
+		}
+		scenes = append(scenes, TimeRange{Start: start, End: c.Time})
+		start = c.Time
+	}
+	if duration > start {
+		if n := len(scenes); n > 0 && duration-start < minScene {
+			scenes[n-1].End = duration
+		} else {
+			scenes = append(scenes, TimeRange{Start: start, End: duration})
+		}
+	}
+	return scenes
+}
+
+// sceneParser pairs metadata=print's "pts_time:" frame lines with the
+// lavfi.scene_score line that follows.
+type sceneParser struct {
+	cuts    []SceneCut
+	pending *float64
+}
+
+func (p *sceneParser) parseLine(line string) {
+	if i := strings.Index(line, "pts_time:"); i >= 0 {
+		if v, ok := leadingFloat(line[i+len("pts_time:"):]); ok {
+			p.pending = &v
+		}
+		return
+	}
+	if i := strings.Index(line, "lavfi.scene_score="); i >= 0 && p.pending != nil {
+		if v, ok := leadingFloat(line[i+len("lavfi.scene_score="):]); ok {
+			p.cuts = append(p.cuts, SceneCut{Time: *p.pending, Score: v})
+		}
+		p.pending = nil
+	}
+}
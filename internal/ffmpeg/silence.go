@@ -0,0 +1,130 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ─── Silence detection / jump cuts ────────────────────────────────────────────
+
+// SilenceOptions configures DetectSilence and JumpCutRanges.
+type SilenceOptions struct {
+	ThresholdDB float64 // silencedetect noise floor, e.g. -40
+	MinSilence  float64 // shortest pause to cut, seconds
+	Padding     float64 // audio kept either side of each pause, seconds
+	MinKeep     float64 // drop kept segments shorter than this, seconds
+}
+
+// DefaultSilenceOptions suit talking-head recordings.
+var DefaultSilenceOptions = SilenceOptions{ThresholdDB: -40, MinSilence: 0.5, Padding: 0.1, MinKeep: 0.2}
+
+// DetectSilence runs silencedetect over the first audio stream of inputPath.
+// duration closes a trailing silence when ffmpeg does not report its end.
+func DetectSilence(ctx context.Context, ffmpegPath, inputPath string, duration float64, opts SilenceOptions, ph ProgressHandler) ([]TimeRange, error) {
+	args := []string{
+		"-hide_banner", "-nostats",
+		"-i", inputPath,
+		"-vn",
+		"-af", fmt.Sprintf("silencedetect=n=%.1fdB:d=%.3f", opts.ThresholdDB, opts.MinSilence),
+		"-progress", "pipe:1", "-v", "info",
+		"-f", "null", "-",
+	}
+	parser := &silenceParser{}
+	if _, err := runFFmpegScan(ctx, ffmpegPath, args, &duration, ph, parser.parseLine); err != nil {
+		return nil, fmt.Errorf("silence detection: %w", err)
+	}
+	return parser.finish(duration), nil
+}
+
+// JumpCutRanges returns the parts of [0, duration) to keep once the given
+// silences are removed. Each cut is shrunk by opts.Padding on both sides so
+// speech is not clipped, overlapping segments are merged, and segments shorter
+// than opts.MinKeep are dropped.
+func JumpCutRanges(silences []TimeRange, duration float64, opts SilenceOptions) []TimeRange {
+	keep := []TimeRange{}
+	cursor := 0.0
+	for _, s := range silences {
+		cutStart := s.Start + opts.Padding
+		cutEnd := s.End - opts.Padding
+		if s.Start <= 0 {
+			cutStart = 0 // leading silence: no need to keep padding before the first word
+		}
+		if s.End >= duration {
+			cutEnd = duration
+		}
+		if cutEnd <= cutStart {
+			continue
+		}
+		if cutStart > cursor {
+			keep = appendRange(keep, TimeRange{Start: cursor, End: cutStart})
+		}
+		cursor = math.Max(cursor, cutEnd)
+	}
+	if duration > cursor {
+		keep = appendRange(keep, TimeRange{Start: cursor, End: duration})
+	}
+
+	out := keep[:0]
+	for _, r := range keep {
+		if r.Duration() >= opts.MinKeep {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// appendRange appends r, merging it into the last range when they touch.
+func appendRange(ranges []TimeRange, r TimeRange) []TimeRange {
+	if n := len(ranges); n > 0 && r.Start <= ranges[n-1].End {
+		ranges[n-1].End = math.Max(ranges[n-1].End, r.End)
+		return ranges
+	}
+	return append(ranges, r)
+}
+
+// silenceParser collects ranges from silencedetect's silence_start /
+// silence_end log lines.
+type silenceParser struct {
+	ranges []TimeRange
+	open   *float64
+}
+
+func (p *silenceParser) parseLine(line string) {
+	if i := strings.Index(line, "silence_start: "); i >= 0 {
+		if v, ok := leadingFloat(line[i+len("silence_start: "):]); ok {
+			p.open = &v
+		}
+		return
+	}
+	if i := strings.Index(line, "silence_end: "); i >= 0 && p.open != nil {
+		if v, ok := leadingFloat(line[i+len("silence_end: "):]); ok {
+			p.ranges = append(p.ranges, TimeRange{Start: math.Max(0, *p.open), End: v})
+			p.open = nil
+		}
+	}
+}
+
+// finish closes a silence still open at end of stream (older ffmpeg builds do
+// not print a final silence_end).
+func (p *silenceParser) finish(duration float64) []TimeRange {
+	if p.open != nil && duration > *p.open {
+		p.ranges = append(p.ranges, TimeRange{Start: math.Max(0, *p.open), End: duration})
+		p.open = nil
+	}
+	if p.ranges == nil {
+		return []TimeRange{}
+	}
+	return p.ranges
+}
+
+func leadingFloat(s string) (float64, bool) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(fields[0], 64)
+	return v, err == nil
+}
@@ -0,0 +1,125 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// ─── Stabilization ────────────────────────────────────────────────────────────
+
+// Stabilization smooths camera shake. With libvidstab it takes two passes:
+// vidstabdetect writes the camera motion to a transforms file, then
+// vidstabtransform applies a smoothed inverse of it. Deshake is the
+// single-pass fallback for builds without libvidstab.
+type Stabilization struct {
+	Shakiness int // 1 (steady) … 10 (very shaky), for vidstabdetect
+	Smoothing int // frames averaged on each side of the current one
+	// Zoom is added, in percent, to the optimal zoom that hides the borders
+	// the correction uncovers.
+	Zoom    float64
+	Deshake bool // use deshake instead of libvidstab
+}
+
+// DefaultStabilization holds the settings a request leaves out.
+var DefaultStabilization = Stabilization{Shakiness: 5, Smoothing: 10}
+
+// ResolveStabilization applies any explicit settings to DefaultStabilization.
+func ResolveStabilization(shakiness, smoothing *int, zoom *float64, deshake bool) Stabilization {
+	s := DefaultStabilization
+	s.Deshake = deshake
+	if shakiness != nil {
+		s.Shakiness = *shakiness
+	}
+	if smoothing != nil {
+		s.Smoothing = *smoothing
+	}
+	if zoom != nil {
+		s.Zoom = *zoom
+	}
+	return s
+}
+
+// stabilizePassWeight is the share of overall progress given to the
+// vidstabdetect pass, which decodes the whole input but encodes nothing.
+const stabilizePassWeight = 0.3
+
+// filters returns the correction filters that apply trf, the transforms file
+// from detect (unused with Deshake).
+func (s *Stabilization) filters(trf string) []string {
+	if s.Deshake {
+		return []string{"deshake"}
+	}
+	return []string{
+		fmt.Sprintf("vidstabtransform=input=%s:smoothing=%d:optzoom=1:zoom=%.1f", filterPath(trf), s.Smoothing, s.Zoom),
+		// Resampling during the transform softens the picture slightly.
+		"unsharp=5:5:0.8:3:3:0.4",
+	}
+}
+
+// detect runs the vidstabdetect pass over inputArgs (the -ss / -i / -t of the
+// encode) and returns the transforms file, which the caller removes. With
+// Deshake there is no pass and the file is "".
+func (s *Stabilization) detect(ctx context.Context, ffmpegPath string, inputArgs []string, duration *float64, ph ProgressHandler) (string, error) {
+	if s.Deshake {
+		return "", nil
+	}
+	f, err := os.CreateTemp("", "ffm_vidstab_*.trf")
+	if err != nil {
+		return "", fmt.Errorf("failed to create transforms file: %w", err)
+	}
+	f.Close()
+	args := append([]string{"-hide_banner", "-nostats"}, inputArgs...)
+	args = append(args,
+		"-an", "-sn",
+		"-vf", fmt.Sprintf("vidstabdetect=shakiness=%d:accuracy=15:result=%s", s.Shakiness, filterPath(f.Name())),
+		"-progress", "pipe:1", "-v", "warning", "-f", "null", "-",
+	)
+	if err := runFFmpeg(ctx, ffmpegPath, args, duration, ph); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("stabilization analysis: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// stabilizeClips runs the detection pass of each stabilized clip, sharing
+// [0, stabilizePassWeight] of ph by duration. It returns the correction
+// filters per clip (nil for the others) and the transforms files to remove,
+// which are returned even on error.
+func stabilizeClips(ctx context.Context, opts TimelineExportOptions, ph ProgressHandler, onStage func(string)) ([][]string, []string, error) {
+	filters := make([][]string, len(opts.Clips))
+	var files []string
+	total := 0.0
+	for _, c := range opts.Clips {
+		if c.Stabilize != nil && c.HasVideo {
+			total += c.Duration
+		}
+	}
+	if total > 0 {
+		onStage("stabilizing")
+	}
+	done := 0.0
+	for i, c := range opts.Clips {
+		if c.Stabilize == nil || !c.HasVideo {
+			continue
+		}
+		from, to := done/total, (done+c.Duration)/total
+		done += c.Duration
+		inputArgs := []string{
+			"-ss", fmt.Sprintf("%.6f", c.SourceStart),
+			"-t", fmt.Sprintf("%.6f", c.Duration),
+			"-i", c.FilePath,
+		}
+		dur := c.Duration
+		trf, err := c.Stabilize.detect(ctx, opts.FFmpegPath, inputArgs, &dur,
+			progressRange(ph, from*stabilizePassWeight, to*stabilizePassWeight))
+		if err != nil {
+			return nil, files, fmt.Errorf("clip[%d]: %w", i, err)
+		}
+		if trf != "" {
+			files = append(files, trf)
+		}
+		filters[i] = c.Stabilize.filters(trf)
+	}
+	return filters, files, nil
+}
@@ -0,0 +1,297 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ─── Stream selection ─────────────────────────────────────────────────────────
+
+// StreamInfo is one stream of a probed file.
+type StreamInfo struct {
+	Index         int    `json:"index"`
+	Type          string `json:"type"` // video | audio | subtitle | data | attachment
+	Codec         string `json:"codec"`
+	Language      string `json:"language,omitempty"`
+	Title         string `json:"title,omitempty"`
+	Width         int    `json:"width,omitempty"`
+	Height        int    `json:"height,omitempty"`
+	Channels      int    `json:"channels,omitempty"`
+	ChannelLayout string `json:"channel_layout,omitempty"`
+	SampleRate    int    `json:"sample_rate,omitempty"`
+	PixelFormat   string `json:"pix_fmt,omitempty"`
+	// Colour description of a video stream, as ffprobe names it (e.g.
+	// bt2020nc, smpte2084, bt2020). HDR returns the HDR format it implies.
+	ColorSpace     string `json:"color_space,omitempty"`
+	ColorTransfer  string `json:"color_transfer,omitempty"`
+	ColorPrimaries string `json:"color_primaries,omitempty"`
+	// FieldOrder is the container's scan type: progressive, tt or bb (top or
+	// bottom field first), tb or bt. It is often missing or wrong; see
+	// DetectInterlace.
+	FieldOrder string `json:"field_order,omitempty"`
+	Default    bool   `json:"default"`
+	Forced     bool   `json:"forced"`
+	// AttachedPic marks cover art, which ffprobe reports as a video stream.
+	AttachedPic bool `json:"attached_pic,omitempty"`
+}
+
+// StreamSelector matches streams; every field that is set must match.
+type StreamSelector struct {
+	Index    *int
+	Type     string
+	Language string
+}
+
+func (s StreamSelector) matches(st StreamInfo) bool {
+	return (s.Index == nil || *s.Index == st.Index) &&
+		(s.Type == "" || s.Type == st.Type) &&
+		(s.Language == "" || strings.EqualFold(s.Language, st.Language))
+}
+
+// StreamMetadata overrides the language, title and dispositions of the output
+// stream made from input stream Index.
+type StreamMetadata struct {
+	Index    int
+	Language *string
+	Title    *string
+	Default  *bool
+	Forced   *bool
+}
+
+// StreamSelection replaces ffmpeg's default stream selection (the "best" video
+// and audio stream) in Convert.
+type StreamSelection struct {
+	// Include lists the streams to keep; empty means the first video and
+	// audio stream (the default-disposition one, if any).
+	Include []StreamSelector
+	// Exclude drops streams from the selection.
+	Exclude []StreamSelector
+	// AllAudio keeps every audio stream.
+	AllAudio bool
+	Metadata []StreamMetadata
+}
+
+// Resolve picks the streams sel keeps, in input order. It fails when nothing
+// is left or Metadata names a stream that is not kept.
+func (sel *StreamSelection) Resolve(streams []StreamInfo) ([]StreamInfo, error) {
+	for _, inc := range sel.Include {
+		if inc.Index != nil && !hasStream(streams, *inc.Index) {
+			return nil, fmt.Errorf("stream %d does not exist", *inc.Index)
+		}
+	}
+	keep := map[int]bool{}
+	if len(sel.Include) > 0 {
+		for _, st := range streams {
+			for _, inc := range sel.Include {
+				if inc.matches(st) {
+					keep[st.Index] = true
+				}
+			}
+		}
+	} else {
+		if v := defaultStream(streams, "video"); v != nil {
+			keep[v.Index] = true
+		}
+		if a := defaultStream(streams, "audio"); a != nil {
+			keep[a.Index] = true
+		}
+	}
+	var out []StreamInfo
+	for _, st := range streams {
+		if !keep[st.Index] && !(sel.AllAudio && st.Type == "audio") {
+			continue
+		}
+		excluded := false
+		for _, exc := range sel.Exclude {
+			excluded = excluded || exc.matches(st)
+		}
+		if !excluded {
+			out = append(out, st)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("stream selection matches no streams")
+	}
+	for _, m := range sel.Metadata {
+		if !hasStream(out, m.Index) {
+			return nil, fmt.Errorf("stream %d has metadata but is not selected", m.Index)
+		}
+	}
+	return out, nil
+}
+
+func hasStream(streams []StreamInfo, index int) bool {
+	for _, st := range streams {
+		if st.Index == index {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultStream returns the stream of kind ffmpeg would pick: the first with
+// the default disposition, else the first. Cover art is never picked.
+func defaultStream(streams []StreamInfo, kind string) *StreamInfo {
+	var first *StreamInfo
+	for i := range streams {
+		st := &streams[i]
+		if st.Type != kind || st.AttachedPic {
+			continue
+		}
+		if st.Default {
+			return st
+		}
+		if first == nil {
+			first = st
+		}
+	}
+	return first
+}
+
+// streamMapArgs maps the selected streams in order and sets the metadata
+// and dispositions sel asks for. Once any disposition is set, every output
+// stream's is written, so a new default replaces the source's.
+func streamMapArgs(selected []StreamInfo, sel *StreamSelection) []string {
+	var args []string
+	for _, st := range selected {
+		args = append(args, "-map", fmt.Sprintf("0:%d", st.Index))
+	}
+	meta := map[int]StreamMetadata{}
+	dispositions := false
+	for _, m := range sel.Metadata {
+		meta[m.Index] = m
+		dispositions = dispositions || m.Default != nil || m.Forced != nil
+	}
+	// A stream made default takes over from the other streams of its type.
+	newDefault := map[string]bool{}
+	for _, st := range selected {
+		if m, ok := meta[st.Index]; ok && m.Default != nil && *m.Default {
+			newDefault[st.Type] = true
+		}
+	}
+	for i, st := range selected {
+		m, ok := meta[st.Index]
+		if ok && m.Language != nil {
+			args = append(args, fmt.Sprintf("-metadata:s:%d", i), "language="+*m.Language)
+		}
+		if ok && m.Title != nil {
+			args = append(args, fmt.Sprintf("-metadata:s:%d", i), "title="+*m.Title)
+		}
+		if !dispositions {
+			continue
+		}
+		def, forced := st.Default && !newDefault[st.Type], st.Forced
+		if ok && m.Default != nil {
+			def = *m.Default
+		}
+		if ok && m.Forced != nil {
+			forced = *m.Forced
+		}
+		var flags []string
+		if def {
+			flags = append(flags, "default")
+		}
+		if forced {
+			flags = append(flags, "forced")
+		}
+		value := "0"
+		if len(flags) > 0 {
+			value = strings.Join(flags, "+")
+		}
+		args = append(args, fmt.Sprintf("-disposition:%d", i), value)
+	}
+	return args
+}
+
+// convertStreamArgs resolves opts.Streams for Convert, leaving out the stream
+// types the output drops. It also returns the streams kept.
+func convertStreamArgs(opts ConvertOptions, format string, streams []StreamInfo) ([]string, []StreamInfo, error) {
+	selected, err := opts.Streams.Resolve(streams)
+	if err != nil {
+		return nil, nil, err
+	}
+	kept, err := CheckStreams(selected, format, opts.RemoveVideo, opts.RemoveAudio)
+	if err != nil {
+		return nil, nil, err
+	}
+	args := streamMapArgs(kept, opts.Streams)
+	subs := 0
+	for _, st := range kept {
+		if st.Type == "subtitle" {
+			// Checked by CheckStreams.
+			codec, _ := SubtitleCodecFor(format, st)
+			args = append(args, fmt.Sprintf("-c:s:%d", subs), codec)
+			subs++
+		}
+	}
+	return args, kept, nil
+}
+
+// LoudnessStream returns the audio stream of kept that loudness normalization
+// measures, or nil when there is none. Normalization applies one correction
+// to all audio, so it fails when more than one audio stream is kept.
+func LoudnessStream(kept []StreamInfo) (*StreamInfo, error) {
+	var audio *StreamInfo
+	for i := range kept {
+		if kept[i].Type != "audio" {
+			continue
+		}
+		if audio != nil {
+			return nil, fmt.Errorf("loudness normalization measures one audio stream; streams %d and %d are both kept", audio.Index, kept[i].Index)
+		}
+		audio = &kept[i]
+	}
+	return audio, nil
+}
+
+// CheckStreams returns the selected streams a convert to format keeps,
+// leaving out the types it drops. It fails on streams the output cannot
+// hold.
+func CheckStreams(selected []StreamInfo, format string, removeVideo, removeAudio bool) ([]StreamInfo, error) {
+	var kept []StreamInfo
+	for _, st := range selected {
+		switch {
+		case st.Type == "video" && removeVideo, st.Type == "audio" && removeAudio:
+			continue
+		case st.Type == "data" || st.Type == "attachment":
+			return nil, fmt.Errorf("stream %d is a %s stream, which cannot be converted", st.Index, st.Type)
+		case st.Type == "subtitle":
+			if _, err := SubtitleCodecFor(format, st); err != nil {
+				return nil, err
+			}
+		}
+		kept = append(kept, st)
+	}
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("stream selection leaves no streams for %s output", format)
+	}
+	return kept, nil
+}
+
+// ImageSubtitleCodecs are the bitmap subtitle formats, which only MKV can
+// hold: they cannot be converted to text.
+var ImageSubtitleCodecs = map[string]bool{"hdmv_pgs_subtitle": true, "dvd_subtitle": true, "dvb_subtitle": true, "xsub": true}
+
+// SubtitleCodecFor is the encoder that writes subtitle stream st to format.
+// MKV keeps SRT, ASS and bitmap subtitles as they are and converts other
+// text subtitles (mov_text, WebVTT) to SRT.
+func SubtitleCodecFor(format string, st StreamInfo) (string, error) {
+	image := ImageSubtitleCodecs[st.Codec]
+	switch format {
+	case "mp4", "mov", "webm":
+		if image {
+			return "", fmt.Errorf("subtitle stream %d is image-based (%s); %s output only takes text subtitles, use mkv", st.Index, st.Codec, format)
+		}
+		if format == "webm" {
+			return "webvtt", nil
+		}
+		return "mov_text", nil
+	case "mkv":
+		switch {
+		case image, st.Codec == "subrip", st.Codec == "ass", st.Codec == "ssa":
+			return "copy", nil
+		}
+		return "srt", nil
+	}
+	return "", fmt.Errorf("%s output cannot hold subtitle stream %d", format, st.Index)
+}
@@ -0,0 +1,150 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"ffmeditor/internal/ffmpeg"
+)
+
+// GetFileAudioAnalysis returns loudness, RMS, clipping, silence and channel
+// correlation for an uploaded file. The result is computed once per file and
+// served from the analysis cache afterwards; concurrent requests for a file
+// that is still being analyzed wait for the same decode.
+func (h *Handler) GetFileAudioAnalysis(c *fiber.Ctx) error {
+	fileID := c.Params("id")
+	uf := h.storage.Get(fileID)
+	if uf == nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{
+			"error": "File not found",
+		})
+	}
+	if uf.MediaInfo != nil && uf.MediaInfo.Duration != nil && !uf.MediaInfo.HasAudio {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "File has no audio stream",
+		})
+	}
+	if err := h.requireFeatures("audio_analysis"); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	opts := ffmpeg.AudioAnalysisOptions{}
+	if mi := uf.MediaInfo; mi != nil {
+		opts.Channels = mi.AudioChannels
+		opts.SampleRate = mi.AudioSampleRate
+		if mi.Duration != nil {
+			opts.Duration = *mi.Duration
+		}
+	}
+
+	analysis, cached, err := h.analyses.audioAnalysis(fileID, func() (*ffmpeg.AudioAnalysis, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		return ffmpeg.AnalyzeAudio(ctx, h.cfg.FFmpegPath, uf.StoragePath, opts, nil)
+	})
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	// The file may have been deleted while it was being decoded.
+	if h.storage.Get(fileID) == nil {
+		h.analyses.forget(fileID)
+	}
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"file_id":  fileID,
+		"cached":   cached,
+		"analysis": analysis,
+	})
+}
+
+// analysisCache keeps expensive per-file results for as long as the file is
+// uploaded.
+type analysisCache struct {
+	mu     sync.Mutex
+	audio  map[string]*audioAnalysisCall
+	scenes map[sceneCacheKey]*sceneResult
+}
+
+// audioAnalysisCall is one AnalyzeAudio run; done is closed once res and err
+// are set.
+type audioAnalysisCall struct {
+	done chan struct{}
+	res  *ffmpeg.AudioAnalysis
+	err  error
+}
+
+type sceneCacheKey struct {
+	fileID              string
+	threshold, minScene float64
+}
+
+func newAnalysisCache() *analysisCache {
+	return &analysisCache{
+		audio:  make(map[string]*audioAnalysisCall),
+		scenes: make(map[sceneCacheKey]*sceneResult),
+	}
+}
+
+// audioAnalysis returns the file's audio analysis, running analyze only if no
+// other request has already done so or is doing so. cached reports whether the
+// result was finished before the call. Failed runs are not kept.
+func (c *analysisCache) audioAnalysis(fileID string, analyze func() (*ffmpeg.AudioAnalysis, error)) (res *ffmpeg.AudioAnalysis, cached bool, err error) {
+	c.mu.Lock()
+	if call, ok := c.audio[fileID]; ok {
+		c.mu.Unlock()
+		select {
+		case <-call.done:
+			cached = true
+		default:
+			<-call.done
+		}
+		return call.res, cached, call.err
+	}
+	call := &audioAnalysisCall{done: make(chan struct{})}
+	c.audio[fileID] = call
+	c.mu.Unlock()
+
+	call.res, call.err = analyze()
+	if call.err != nil {
+		c.mu.Lock()
+		if c.audio[fileID] == call {
+			delete(c.audio, fileID)
+		}
+		c.mu.Unlock()
+	}
+	close(call.done)
+	return call.res, false, call.err
+}
+
+func (c *analysisCache) sceneResult(fileID string, threshold, minScene float64) (*sceneResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	res, ok := c.scenes[sceneCacheKey{fileID, threshold, minScene}]
+	return res, ok
+}
+
+func (c *analysisCache) storeSceneResult(fileID string, threshold, minScene float64, res *sceneResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.scenes[sceneCacheKey{fileID, threshold, minScene}] = res
+}
+
+// forget drops everything cached for the file.
+func (c *analysisCache) forget(fileID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.audio, fileID)
+	for key := range c.scenes {
+		if key.fileID == fileID {
+			delete(c.scenes, key)
+		}
+	}
+}
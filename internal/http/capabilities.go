@@ -0,0 +1,85 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"ffmeditor/internal/ffmpeg"
+	"ffmeditor/internal/storage"
+	"ffmeditor/internal/validator"
+)
+
+// GetCapabilities reports what this server's ffmpeg build supports: the codecs
+// and output formats requests may use, optional features, and the raw
+// encoder/decoder/filter/muxer lists.
+func (h *Handler) GetCapabilities(c *fiber.Ctx) error {
+	caps := h.cfg.Capabilities
+	resp := fiber.Map{
+		"probed":            caps != nil,
+		"video_codecs":      ffmpeg.Names(validator.AllowedVideoCodecs),
+		"audio_codecs":      ffmpeg.Names(validator.AllowedAudioCodecs),
+		"output_formats":    ffmpeg.Names(validator.AllowedOutputFormats),
+		"hardware_encoder":  h.cfg.ResolvedHWEncoder,
+		"hardware_encoders": h.cfg.HWEncoders,
+		"av1_decode":        caps.CanDecode("av1"),
+		"features":          h.features(),
+	}
+	if caps != nil {
+		resp["version"] = caps.Version
+		resp["encoders"] = ffmpeg.Names(caps.Encoders)
+		resp["decoders"] = ffmpeg.Names(caps.Decoders)
+		resp["filters"] = ffmpeg.Names(caps.Filters)
+		resp["muxers"] = ffmpeg.Names(caps.Muxers)
+	}
+	return c.Status(http.StatusOK).JSON(resp)
+}
+
+// features reports the optional features available; RNNoise also needs its
+// model file.
+func (h *Handler) features() map[string]bool {
+	features := h.cfg.Capabilities.Features()
+	features["rnnoise"] = features["rnnoise"] && h.cfg.RNNoiseModel != ""
+	return features
+}
+
+// requireFeatures returns an error for the first feature (a
+// ffmpeg.FeatureFilters key) this server's ffmpeg cannot provide.
+func (h *Handler) requireFeatures(features ...string) error {
+	for _, f := range features {
+		if err := h.cfg.Capabilities.RequireFeature(f); err != nil {
+			return err
+		}
+		if f == "rnnoise" && h.cfg.RNNoiseModel == "" {
+			return fmt.Errorf("rnnoise is not available: no RNNoise model file is installed (RNNOISE_MODEL)")
+		}
+	}
+	return nil
+}
+
+// requireDecoder returns an error when this server's ffmpeg cannot decode the
+// video of info, which a re-encode needs.
+func (h *Handler) requireDecoder(info *storage.MediaInfo) error {
+	if info == nil || !info.HasVideo || h.cfg.Capabilities.CanDecode(info.VideoCodec) {
+		return nil
+	}
+	return fmt.Errorf("%s input cannot be decoded: this server's ffmpeg has neither %s", info.VideoCodec, strings.Join(ffmpeg.AV1Decoders, " nor "))
+}
+
+// exportFeatures lists the optional features an export with these settings
+// uses; channels are the channel operations of the output or its clips.
+func exportFeatures(normalize bool, loudness validator.LoudnessParams, quality bool, qc *validator.QCParams, cleanup *ffmpeg.AudioCleanup, channels ...*string) []string {
+	features := append(cleanupFeatures(cleanup), channelFeatures(channels...)...)
+	if normalize || loudness.IsSet() {
+		features = append(features, "loudness_normalization")
+	}
+	if quality {
+		features = append(features, "quality_metrics")
+	}
+	if qc != nil {
+		features = append(features, "qc")
+	}
+	return features
+}
@@ -0,0 +1,23 @@
+package http
+
+import "ffmeditor/internal/ffmpeg"
+
+// channelOptions converts a request's channel operation and pan.
+func channelOptions(mode *string, pan *float64) ffmpeg.ChannelOptions {
+	ch := ffmpeg.ChannelOptions{Pan: pan}
+	if mode != nil {
+		ch.Mode = *mode
+	}
+	return ch
+}
+
+// channelFeatures lists the optional features the channel operations of an
+// output or its clips use.
+func channelFeatures(channels ...*string) []string {
+	for _, ch := range channels {
+		if ch != nil && *ch == "5.1" {
+			return []string{"upmix"}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package http
+
+import (
+	"ffmeditor/internal/ffmpeg"
+	"ffmeditor/internal/validator"
+)
+
+// cleanupOptions resolves a request's cleanup chain, or returns nil.
+func (h *Handler) cleanupOptions(p *validator.CleanupParams) *ffmpeg.AudioCleanup {
+	if p == nil {
+		return nil
+	}
+	preset := ""
+	if p.Preset != nil {
+		preset = *p.Preset
+	}
+	cleanup := ffmpeg.ResolveAudioCleanup(preset, p.HighPass, p.Denoise, p.DenoiseModel, p.Gate, p.DeEss, p.Compressor, p.Limiter)
+	cleanup.RNNoiseModel = h.cfg.RNNoiseModel
+	return &cleanup
+}
+
+// cleanupFeatures lists the optional features a cleanup chain uses.
+func cleanupFeatures(cleanup *ffmpeg.AudioCleanup) []string {
+	var features []string
+	if cleanup.IsSet() {
+		features = append(features, "audio_cleanup")
+	}
+	if cleanup.UsesRNNoise() {
+		features = append(features, "rnnoise")
+	}
+	return features
+}
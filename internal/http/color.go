@@ -0,0 +1,137 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"ffmeditor/internal/ffmpeg"
+	"ffmeditor/internal/storage"
+	"ffmeditor/internal/validator"
+)
+
+// maxLUTBytes bounds a .cube upload; a 65-point LUT is about 8 MB.
+const maxLUTBytes = 64 << 20
+
+// UploadLUT stores a .cube 3D LUT for lut_id in convert and timeline
+// requests.
+func (h *Handler) UploadLUT(c *fiber.Ctx) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "No file uploaded"})
+	}
+	if file.Size > maxLUTBytes {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("LUT too large (max %d MB)", maxLUTBytes>>20)})
+	}
+	name := validator.SanitizeFilename(file.Filename)
+	if !strings.EqualFold(filepath.Ext(name), ".cube") {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "LUTs must be .cube files"})
+	}
+	src, err := file.Open()
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to read file"})
+	}
+	defer src.Close()
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to read file"})
+	}
+	lut, err := ffmpeg.ParseCubeLUT(bytes.NewReader(data))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid LUT: " + err.Error()})
+	}
+
+	id := uuid.New().String()
+	path := h.storage.GetStoragePath(id, "cube")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save file"})
+	}
+	info := storage.LUTInfo(*lut)
+	asset := &storage.Asset{
+		ID:           id,
+		Kind:         "lut",
+		OriginalName: name,
+		StoragePath:  path,
+		UploadedAt:   time.Now(),
+		LUT:          &info,
+	}
+	h.storage.StoreAsset(asset)
+	return c.Status(http.StatusOK).JSON(lutJSON(asset))
+}
+
+// ListLUTs lists the uploaded LUTs.
+func (h *Handler) ListLUTs(c *fiber.Ctx) error {
+	luts := []fiber.Map{}
+	for _, a := range h.storage.Assets("lut") {
+		luts = append(luts, lutJSON(a))
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{"luts": luts})
+}
+
+// DeleteLUT removes an uploaded LUT.
+func (h *Handler) DeleteLUT(c *fiber.Ctx) error {
+	a := h.storage.GetAsset("lut", c.Params("id"))
+	if a == nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "LUT not found"})
+	}
+	os.Remove(a.StoragePath)
+	h.storage.DeleteAsset(a.ID)
+	return c.Status(http.StatusOK).JSON(fiber.Map{"success": true})
+}
+
+func lutJSON(a *storage.Asset) fiber.Map {
+	m := fiber.Map{
+		"lut_id":        a.ID,
+		"original_name": a.OriginalName,
+		"uploaded_at":   a.UploadedAt,
+	}
+	if a.LUT != nil {
+		m["title"], m["size"] = a.LUT.Title, a.LUT.Size
+	}
+	return m
+}
+
+// colorGrade resolves a request's colour grade, or returns nil when it has
+// none. It fails when the LUT does not exist.
+func (h *Handler) colorGrade(p validator.ColorParams) (*ffmpeg.ColorGrade, error) {
+	g := &ffmpeg.ColorGrade{
+		Saturation:  p.Saturation,
+		Gamma:       p.Gamma,
+		Hue:         p.Hue,
+		Temperature: p.Temperature,
+	}
+	if p.Curves != nil {
+		g.Curves = *p.Curves
+	}
+	if p.LUTID != nil {
+		a := h.storage.GetAsset("lut", *p.LUTID)
+		if a == nil {
+			return nil, fmt.Errorf("LUT %s not found", *p.LUTID)
+		}
+		g.LUTPath = a.StoragePath
+	}
+	if !g.IsSet() {
+		return nil, nil
+	}
+	return g, nil
+}
+
+// colorFeatures lists the optional features a colour grade uses.
+func colorFeatures(p validator.ColorParams) []string {
+	var features []string
+	if p.LUTID != nil {
+		features = append(features, "lut")
+	}
+	if p.Temperature != nil {
+		features = append(features, "color_temperature")
+	}
+	return features
+}
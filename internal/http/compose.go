@@ -0,0 +1,265 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"ffmeditor/internal/ffmpeg"
+	"ffmeditor/internal/jobs"
+	"ffmeditor/internal/metrics"
+	"ffmeditor/internal/storage"
+	"ffmeditor/internal/validator"
+)
+
+// Default canvas of a composition.
+const (
+	composeWidth  = 1920
+	composeHeight = 1080
+)
+
+// Compose starts a job laying several files out on one canvas: a
+// picture-in-picture inset or a side-by-side, top/bottom or 2×2 grid.
+func (h *Handler) Compose(c *fiber.Ctx) error {
+	var req validator.ComposeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := req.Validate(); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.requireFeatures("composition"); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	sources := make([]ffmpeg.ComposeSource, len(req.Sources))
+	var firstInfo *storage.MediaInfo
+	var inputPaths []string
+	hasVideo := false
+	for i, rs := range req.Sources {
+		uf := h.storage.Get(rs.FileID)
+		if uf == nil {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": fmt.Sprintf("File %s not found", rs.FileID)})
+		}
+		info := uf.MediaInfo
+		if info == nil || info.Duration == nil || *info.Duration <= 0 {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("sources[%d]: file duration unknown", i)})
+		}
+		if err := h.requireDecoder(info); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("sources[%d]: %v", i, err)})
+		}
+		dur := *info.Duration - rs.Start
+		if dur <= 0 {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("sources[%d].start is past the end of the file", i)})
+		}
+		if rs.Duration != nil {
+			dur = math.Min(dur, *rs.Duration)
+		}
+		src := ffmpeg.ComposeSource{
+			FilePath: uf.StoragePath,
+			Start:    rs.Start,
+			Duration: dur,
+			Offset:   rs.Offset,
+			HasVideo: info.HasVideo,
+			HasAudio: info.HasAudio,
+			Volume:   rs.Volume,
+		}
+		sources[i] = src
+		inputPaths = append(inputPaths, uf.StoragePath)
+		hasVideo = hasVideo || info.HasVideo
+		if firstInfo == nil {
+			firstInfo = info
+		}
+	}
+	if !hasVideo {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "compose needs at least one source with video"})
+	}
+	if req.Layout == "pip" && !sources[1].HasVideo {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "the pip inset (sources[1]) has no video"})
+	}
+	audio, err := composeAudio(req.Audio, sources)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	opts := ffmpeg.ComposeOptions{
+		Sources:    sources,
+		Layout:     req.Layout,
+		Width:      composeWidth,
+		Height:     composeHeight,
+		Duration:   composeDuration(req.Layout, req.Duration, sources),
+		Audio:      audio,
+		FFmpegPath: h.cfg.FFmpegPath,
+		VideoCodec: req.VideoCodec,
+		AudioCodec: req.AudioCodec,
+		CRF:        req.CRF,
+		Preset:     req.Preset,
+		PresetMode: h.cfg.PresetMode,
+		FastStart:  req.FastStart,
+		HWEncoder:  h.cfg.ResolvedHWEncoder,
+	}
+	if req.Width != nil {
+		opts.Width = *req.Width
+	}
+	if req.Height != nil {
+		opts.Height = *req.Height
+	}
+	if req.FPS != nil {
+		opts.FPS, _, _ = req.FPS.Rational()
+	}
+	if req.Layout == "pip" {
+		opts.PiP = pipOptions(req.PiP)
+	}
+
+	job := h.jobManager.CreateJob(req.Sources[0].FileID, "Composed_Video", req.OutputFormat)
+	h.jobManager.SetWorkload(job.ID, workload("compose", req.OutputFormat, firstInfo,
+		encoderLabel(req.OutputFormat, req.VideoCodec, h.cfg.ResolvedHWEncoder), opts.Duration))
+
+	format := req.OutputFormat
+	if err := h.jobManager.Enqueue(job, func() {
+		h.performCompose(job, opts, inputPaths, format)
+	}); err != nil {
+		return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"job_id":   job.ID,
+		"status":   job.Status,
+		"duration": opts.Duration,
+	})
+}
+
+func (h *Handler) performCompose(job *jobs.Job, opts ffmpeg.ComposeOptions, inputPaths []string, outputFormat string) {
+	start := time.Now()
+	sampler := metrics.NewSampler()
+	h.jobManager.AddLog(job.ID, fmt.Sprintf("Composing %d sources (%s, %dx%d, %.1fs)",
+		len(opts.Sources), opts.Layout, opts.Width, opts.Height, opts.Duration))
+
+	outputName := fmt.Sprintf("%s_composed.%s", job.ID[:8], outputFormat)
+	opts.OutputPath = filepath.Join(h.cfg.OutputDir, outputName)
+	h.jobManager.SetOutputPath(job.ID, opts.OutputPath)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+	defer cancel()
+	h.jobManager.SetCancelFunc(job.ID, cancel)
+
+	h.jobManager.SetStrategy(job.ID, "reencode")
+	h.jobManager.SetStage(job.ID, "encoding")
+	progress := h.trackProgress(job)
+	composeErr := ffmpeg.Compose(ctx, opts, progress.handle)
+	encoder := ffmpeg.VideoEncoderFor(outputFormat, opts.VideoCodec, opts.HWEncoder)
+	if _, ok := h.hwFallback(job, encoder, composeErr); ok {
+		opts.HWEncoder = ""
+		composeErr = ffmpeg.Compose(ctx, opts, progress.handle)
+	}
+	elapsed := time.Since(start).Seconds()
+	avgCPU, peakRAM := sampler.Stop()
+
+	var inMB, outMB float64
+	for _, p := range inputPaths {
+		inMB += fileSizeMB(p)
+	}
+	if composeErr == nil {
+		outMB = fileSizeMB(opts.OutputPath)
+	}
+	speedRatio := 0.0
+	if elapsed > 0 {
+		speedRatio = opts.Duration / elapsed
+	}
+
+	snap := metrics.Current()
+	h.opStore.Record(metrics.OperationRecord{
+		OperationID:       job.ID,
+		Operation:         "compose",
+		OutputFilename:    outputName,
+		ProcessingTimeSec: elapsed,
+		InputSizeMB:       inMB,
+		OutputSizeMB:      outMB,
+		SpeedRatio:        speedRatio,
+		FFmpegSpeed:       progress.speed,
+		FFmpegFPS:         progress.fps,
+		AvgCPUPercent:     avgCPU,
+		PeakRAMMB:         peakRAM,
+		OutputFormat:      outputFormat,
+		Strategy:          "reencode",
+		Success:           composeErr == nil,
+		Error:             errStr(composeErr),
+		ErrorCode:         errCode(composeErr),
+		GPUUsed:           snap.GPU != nil,
+		Resolution:        fmt.Sprintf("%dx%d", opts.Width, opts.Height),
+		Encoder:           job.Workload.Encoder,
+		MediaSecs:         job.Workload.MediaSecs,
+	})
+
+	if composeErr != nil {
+		h.failJob(job, composeErr)
+		return
+	}
+	h.jobManager.SetStage(job.ID, "done")
+	h.jobManager.AddLog(job.ID, fmt.Sprintf("Completed in %.1fs", elapsed))
+	h.jobManager.SetCompleted(job.ID, outputName)
+}
+
+// composeAudio resolves the request's audio sources: nil picks the first
+// source with audio.
+func composeAudio(picked []int, sources []ffmpeg.ComposeSource) ([]int, error) {
+	if picked == nil {
+		for i, src := range sources {
+			if src.HasAudio {
+				return []int{i}, nil
+			}
+		}
+		return nil, nil
+	}
+	for _, i := range picked {
+		if !sources[i].HasAudio {
+			return nil, fmt.Errorf("audio source %d has no audio stream", i)
+		}
+	}
+	return picked, nil
+}
+
+// composeDuration is the requested length or, for pip, the main source's
+// end; tiled layouts run until the last source ends.
+func composeDuration(layout string, requested *float64, sources []ffmpeg.ComposeSource) float64 {
+	if requested != nil {
+		return *requested
+	}
+	if layout == "pip" {
+		return sources[0].Offset + sources[0].Duration
+	}
+	d := 0.0
+	for _, src := range sources {
+		d = math.Max(d, src.Offset+src.Duration)
+	}
+	return d
+}
+
+// pipOptions converts a request's inset placement, filling the defaults: a
+// quarter-width inset in the bottom-right corner, 32 px in, with no border.
+func pipOptions(p *validator.PiPParams) ffmpeg.PiP {
+	out := ffmpeg.PiP{Corner: "bottom_right", Scale: 0.25, Margin: 32}
+	if p == nil {
+		return out
+	}
+	if p.Corner != nil {
+		out.Corner = *p.Corner
+	}
+	if p.Scale != nil {
+		out.Scale = *p.Scale
+	}
+	if p.Margin != nil {
+		out.Margin = *p.Margin
+	}
+	if p.Border != nil {
+		out.Border = *p.Border
+	}
+	if p.BorderColor != nil {
+		out.BorderColor = *p.BorderColor
+	}
+	return out
+}
@@ -0,0 +1,66 @@
+package http
+
+import (
+	"fmt"
+
+	"ffmeditor/internal/ffmpeg"
+	"ffmeditor/internal/jobs"
+)
+
+// failJob marks job as failed with err. ffmpeg failures also set the job's
+// error_code and error_hint.
+func (h *Handler) failJob(job *jobs.Job, err error) {
+	h.jobManager.AddLog(job.ID, "Error: "+err.Error())
+	if e := ffmpeg.AsError(err); e != nil {
+		if e.Detail != "" {
+			h.jobManager.AddLog(job.ID, fmt.Sprintf("Cause (%s): %s", e.Code, e.Detail))
+		}
+		h.jobManager.SetErrorCode(job.ID, string(e.Code), e.Hint)
+	}
+	h.jobManager.SetError(job.ID, err.Error())
+}
+
+// hwFallback reports whether a run that failed with err should be retried
+// with software, returning the software encoder for the same codec and
+// logging the retry.
+func (h *Handler) hwFallback(job *jobs.Job, hwEncoder string, err error) (string, bool) {
+	if !ffmpeg.HWEncoderFailed(err, hwEncoder) {
+		return "", false
+	}
+	software := ffmpeg.SoftwareEncoderFor(hwEncoder, h.cfg.Capabilities)
+	h.jobManager.AddLog(job.ID, fmt.Sprintf("Hardware encoder %s failed (%s); retrying with %s", hwEncoder, ffmpeg.AsError(err).Detail, software))
+	return software, true
+}
+
+// pickEncoder resolves a request's hw_codec to the hardware encoder verified
+// for that codec at startup, or to its software encoder when there is none.
+func (h *Handler) pickEncoder(job *jobs.Job, hwCodec string) string {
+	enc, hw := h.hwCodecEncoder(hwCodec)
+	if hw {
+		h.jobManager.AddLog(job.ID, fmt.Sprintf("Using hardware encoder %s", enc))
+	} else {
+		h.jobManager.AddLog(job.ID, fmt.Sprintf("No hardware %s encoder available; using %s", hwCodec, enc))
+	}
+	return enc
+}
+
+func (h *Handler) hwCodecEncoder(hwCodec string) (enc string, hw bool) {
+	if enc := h.cfg.HWEncoders[hwCodec]; enc != "" {
+		return enc, true
+	}
+	return ffmpeg.SoftwareEncoderFor(hwCodec, h.cfg.Capabilities), false
+}
+
+// requestCodec is the video codec a request asks for, resolving hw_codec.
+func (h *Handler) requestCodec(videoCodec, hwCodec *string) *string {
+	if hwCodec == nil {
+		return videoCodec
+	}
+	enc, _ := h.hwCodecEncoder(*hwCodec)
+	return &enc
+}
+
+// errCode is the error_code of err for operation records.
+func errCode(err error) string {
+	return string(ffmpeg.CodeOf(err))
+}
@@ -0,0 +1,65 @@
+package http
+
+import (
+	"ffmeditor/internal/ffmpeg"
+	"ffmeditor/internal/jobs"
+	"ffmeditor/internal/metrics"
+	"ffmeditor/internal/storage"
+)
+
+// estimateRunSecs predicts a job's run time from the throughput of past
+// operations like it. It is the jobs.Estimator behind queue_eta_secs.
+func (h *Handler) estimateRunSecs(job *jobs.Job) (float64, bool) {
+	w := job.Workload
+	if w.MediaSecs <= 0 {
+		return 0, false
+	}
+	ratio, ok := h.opStore.SpeedRatio(metrics.ThroughputKey{
+		Operation:    w.Operation,
+		Strategy:     job.Strategy, // empty until the job has picked one
+		OutputFormat: w.OutputFormat,
+		Resolution:   w.Resolution,
+		Encoder:      w.Encoder,
+	})
+	if !ok || ratio <= 0 {
+		return 0, false
+	}
+	return w.MediaSecs / ratio, true
+}
+
+// workload describes a job for estimates and for its operation record.
+func workload(operation, outputFormat string, info *storage.MediaInfo, encoder string, mediaSecs float64) jobs.Workload {
+	w := jobs.Workload{
+		Operation:    operation,
+		OutputFormat: outputFormat,
+		Encoder:      encoder,
+		MediaSecs:    mediaSecs,
+	}
+	if info != nil {
+		w.Resolution = info.Resolution
+	}
+	return w
+}
+
+// encoderLabel names the video encoder a job will use, as
+// ffmpeg.VideoEncoderFor picks it; "copy" and audio-only outputs keep their
+// own labels.
+func encoderLabel(outputFormat string, codec *string, hwEncoder string) string {
+	switch {
+	case isAudioOnlyOutputFormat(outputFormat):
+		return "audio"
+	case codec != nil && *codec == "copy":
+		return "copy"
+	}
+	return ffmpeg.VideoEncoderFor(outputFormat, codec, hwEncoder)
+}
+
+// timelineWorkload describes a timeline export of clips; info is the first
+// clip's source.
+func (h *Handler) timelineWorkload(outputFormat string, codec *string, info *storage.MediaInfo, clips []ffmpeg.TimelineExportClip) jobs.Workload {
+	var secs float64
+	for _, cl := range clips {
+		secs += cl.OutputDuration()
+	}
+	return workload("timeline_export", outputFormat, info, encoderLabel(outputFormat, codec, h.cfg.ResolvedHWEncoder), secs)
+}
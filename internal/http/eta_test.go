@@ -0,0 +1,44 @@
+package http_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	httpapi "ffmeditor/internal/http"
+	"ffmeditor/internal/jobs"
+	"ffmeditor/internal/metrics"
+)
+
+func TestEstimator(t *testing.T) {
+	store := metrics.NewOperationStore(filepath.Join(t.TempDir(), "operations.json"))
+	store.Record(metrics.OperationRecord{Operation: "convert", Strategy: "reencode", OutputFormat: "mp4", Encoder: "libx264", SpeedRatio: 4, Success: true})
+	jm := jobs.NewManager(1)
+	httpapi.NewHandler(nil, nil, jm, store)
+
+	first := jm.CreateJob("f1", "a.mov", "mp4")
+	jm.SetWorkload(first.ID, jobs.Workload{Operation: "convert", OutputFormat: "mp4", Encoder: "libx264", MediaSecs: 40})
+	job := jm.GetJob(first.ID)
+	if job.ETASecs == nil || *job.ETASecs != 10 {
+		t.Fatalf("ETA %v, want 40s of media at 4x = 10", value(job.ETASecs))
+	}
+
+	other := jm.CreateJob("f2", "c.wav", "mp3")
+	jm.SetWorkload(other.ID, jobs.Workload{Operation: "merge", OutputFormat: "mp3", MediaSecs: 40})
+	if job := jm.GetJob(other.ID); job.ETASecs != nil {
+		t.Errorf("ETA %v for an operation without history", *job.ETASecs)
+	}
+
+	// Without the media length there is nothing to scale the history by.
+	unknown := jm.CreateJob("f3", "b.mov", "mp4")
+	jm.SetWorkload(unknown.ID, jobs.Workload{Operation: "convert", OutputFormat: "mp4"})
+	if job := jm.GetJob(unknown.ID); job.ETASecs != nil {
+		t.Errorf("ETA %v without media length", *job.ETASecs)
+	}
+}
+
+func value(p *float64) interface{} {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
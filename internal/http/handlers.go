@@ -27,15 +27,19 @@ type Handler struct {
 	storage    *storage.Storage
 	jobManager *jobs.Manager
 	opStore    *metrics.OperationStore
+	analyses   *analysisCache
 }
 
 func NewHandler(cfg *config.Config, store *storage.Storage, jm *jobs.Manager, opStore *metrics.OperationStore) *Handler {
-	return &Handler{
+	h := &Handler{
 		cfg:        cfg,
 		storage:    store,
 		jobManager: jm,
 		opStore:    opStore,
+		analyses:   newAnalysisCache(),
 	}
+	jm.SetEstimator(h.estimateRunSecs)
+	return h
 }
 
 func (h *Handler) RegisterRoutes(app *fiber.App) {
@@ -54,15 +58,31 @@ func (h *Handler) RegisterRoutes(app *fiber.App) {
 	api.Post("/convert", h.Convert)
 	api.Post("/merge", h.Merge)
 	api.Post("/timeline/export", h.TimelineExport)
+	api.Post("/timeline/jump-cut", h.JumpCut)
+	api.Post("/scenes/split", h.SplitScenes)
+	api.Post("/qc", h.StartQC)
+	api.Post("/compose", h.Compose)
 	api.Get("/jobs/:id", h.GetJob)
 	api.Delete("/jobs/:id", h.CancelJob)
 	api.Get("/download/:id", h.Download)
 	api.Get("/files/:id/waveform", h.GetFileWaveform)
+	api.Get("/files/:id/streams", h.GetFileStreams)
+	api.Get("/files/:id/metadata", h.GetFileMetadata)
+	api.Get("/files/:id/audio-analysis", h.GetFileAudioAnalysis)
+	api.Get("/files/:id/scenes", h.GetFileScenes)
+	api.Get("/files/:id/interlace", h.GetFileInterlace)
 	api.Delete("/files/:id", h.DeleteFile)
+	api.Post("/luts", h.UploadLUT)
+	api.Get("/luts", h.ListLUTs)
+	api.Delete("/luts/:id", h.DeleteLUT)
+	api.Post("/covers", h.UploadCover)
+	api.Get("/covers", h.ListCovers)
+	api.Delete("/covers/:id", h.DeleteCover)
 	api.Get("/metrics/system/current", h.MetricsSystem)
 	api.Get("/metrics/operations", h.MetricsOperations)
 	api.Get("/metrics/summary", h.MetricsSummary)
 	api.Get("/auth/me", h.Me)
+	api.Get("/capabilities", h.GetCapabilities)
 }
 
 // AuthMiddleware validates the Bearer token from Authorization header.
@@ -177,11 +197,18 @@ func (h *Handler) Upload(c *fiber.Ctx) error {
 			duration = *fullInfo.Duration
 		}
 		mediaInfo = &storage.MediaInfo{
-			Duration:   &duration,
-			VideoCodec: fullInfo.VideoCodec,
-			AudioCodec: fullInfo.AudioCodec,
-			HasVideo:   fullInfo.HasVideo,
-			HasAudio:   fullInfo.HasAudio,
+			Duration:        &duration,
+			VideoCodec:      fullInfo.VideoCodec,
+			AudioCodec:      fullInfo.AudioCodec,
+			HasVideo:        fullInfo.HasVideo,
+			HasAudio:        fullInfo.HasAudio,
+			Resolution:      fullInfo.Resolution,
+			AudioChannels:   fullInfo.AudioChannels,
+			AudioSampleRate: fullInfo.AudioSampleRate,
+			HDR:             fullInfo.HDR,
+			Streams:         storageStreams(fullInfo.Streams),
+			Tags:            fullInfo.Tags,
+			Chapters:        storageChapters(fullInfo.Chapters),
 		}
 	} else {
 		mediaInfo = &storage.MediaInfo{}
@@ -206,6 +233,10 @@ func (h *Handler) Upload(c *fiber.Ctx) error {
 			"has_audio":   mediaInfo.HasAudio,
 			"video_codec": mediaInfo.VideoCodec,
 			"audio_codec": mediaInfo.AudioCodec,
+			"hdr":         mediaInfo.HDR,
+			"streams":     mediaInfo.Streams,
+			"tags":        mediaInfo.Tags,
+			"chapters":    mediaInfo.Chapters,
 		},
 	})
 }
@@ -224,6 +255,41 @@ func (h *Handler) Convert(c *fiber.Ctx) error {
 			"error": err.Error(),
 		})
 	}
+	if err := h.requireFeatures(exportFeatures(req.Normalize, req.LoudnessParams, req.QualityMetrics, req.QC, h.cleanupOptions(req.Cleanup), req.Channels)...); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if _, err := h.stabilization(req.Stabilize); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if _, err := h.deinterlace(nil, req.Deinterlace); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if _, _, err := h.frameRate(req.FPS, req.FPSMode); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := h.requireFeatures(colorFeatures(req.ColorParams)...); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if _, err := h.colorGrade(req.ColorParams); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if _, err := h.outputMetadata(req.MetadataParams); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 
 	// Check if file exists
 	uf := h.storage.Get(req.FileID)
@@ -232,9 +298,46 @@ func (h *Handler) Convert(c *fiber.Ctx) error {
 			"error": "File not found",
 		})
 	}
+	if !req.RemoveVideo && (req.VideoCodec == nil || *req.VideoCodec != "copy") {
+		if err := h.requireDecoder(uf.MediaInfo); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+	if _, err := h.hdrHandling(uf.MediaInfo, req.HDR, req.ToneMap); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	// Check the selection against the probed streams now rather than in the job.
+	if sel := streamSelection(req.Streams); sel != nil && uf.MediaInfo != nil && uf.MediaInfo.Streams != nil {
+		selected, err := sel.Resolve(ffmpegStreams(uf.MediaInfo.Streams))
+		var kept []ffmpeg.StreamInfo
+		audioOnly := isAudioOnlyOutputFormat(req.OutputFormat)
+		if err == nil {
+			kept, err = ffmpeg.CheckStreams(selected, strings.ToLower(req.OutputFormat), req.RemoveVideo || audioOnly, req.RemoveAudio && !audioOnly)
+		}
+		if err == nil && (req.Normalize || req.LoudnessParams.IsSet()) && !(req.RemoveAudio && !audioOnly) {
+			_, err = ffmpeg.LoudnessStream(kept)
+		}
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+				"error": "streams: " + err.Error(),
+			})
+		}
+	}
 
 	// Create job
 	job := h.jobManager.CreateJob(req.FileID, uf.OriginalName, req.OutputFormat)
+	mediaSecs := 0.0
+	if req.TrimDuration != nil {
+		mediaSecs = *req.TrimDuration
+	} else if uf.MediaInfo != nil && uf.MediaInfo.Duration != nil {
+		mediaSecs = *uf.MediaInfo.Duration
+	}
+	h.jobManager.SetWorkload(job.ID, workload("convert", req.OutputFormat, uf.MediaInfo,
+		encoderLabel(req.OutputFormat, h.requestCodec(req.VideoCodec, req.HWCodec), ""), mediaSecs))
 
 	reqCopy := req
 	if err := h.jobManager.Enqueue(job, func() {
@@ -268,12 +371,12 @@ func (h *Handler) performConvert(job *jobs.Job, uf *storage.UploadedFile, req *v
 		FFmpegPath:    h.cfg.FFmpegPath,
 		FFprobePath:   h.cfg.FFprobePath,
 		VideoCodec:    req.VideoCodec,
+		VideoProfile:  req.VideoProfile,
 		AudioCodec:    req.AudioCodec,
 		VideoBitrate:  req.VideoBitrate,
 		AudioBitrate:  req.AudioBitrate,
 		CRF:           req.CRF,
 		Preset:        req.Preset,
-		FPS:           req.FPS,
 		RemoveAudio:   req.RemoveAudio,
 		RemoveVideo:   req.RemoveVideo,
 		TrimStart:     req.TrimStart,
@@ -294,6 +397,30 @@ func (h *Handler) performConvert(job *jobs.Job, uf *storage.UploadedFile, req *v
 		Normalize:     req.Normalize,
 		Bass:          req.Bass,
 		Treble:        req.Treble,
+		Channels:      channelOptions(req.Channels, req.Pan),
+		Cleanup:       h.cleanupOptions(req.Cleanup),
+		Streams:       streamSelection(req.Streams),
+	}
+	// Checked in Convert.
+	opts.FPS, opts.FPSMode, _ = h.frameRate(req.FPS, req.FPSMode)
+	opts.Deinterlace, _ = h.deinterlace(job, req.Deinterlace)
+	opts.Stabilize, _ = h.stabilization(req.Stabilize)
+	if opts.Stabilize != nil && opts.Stabilize.Deshake {
+		h.jobManager.AddLog(job.ID, "libvidstab not available, stabilizing with deshake")
+	}
+
+	grade, err := h.colorGrade(req.ColorParams)
+	if err != nil {
+		sampler.Stop()
+		h.failJob(job, err)
+		return
+	}
+	opts.Grade = grade
+	meta, err := h.outputMetadata(req.MetadataParams)
+	if err != nil {
+		sampler.Stop()
+		h.failJob(job, err)
+		return
 	}
 
 	if isAudioOnlyOutputFormat(req.OutputFormat) {
@@ -303,6 +430,7 @@ func (h *Handler) performConvert(job *jobs.Job, uf *storage.UploadedFile, req *v
 		opts.VideoBitrate = nil
 		opts.CRF = nil
 		opts.FPS = nil
+		opts.Deinterlace = nil
 		opts.Preset = nil
 		opts.ResizeWidth = nil
 		opts.ResizeHeight = nil
@@ -311,24 +439,55 @@ func (h *Handler) performConvert(job *jobs.Job, uf *storage.UploadedFile, req *v
 		opts.FastStart = false
 		opts.Brightness = nil
 		opts.Contrast = nil
+		opts.Grade = nil
+	} else if req.HWCodec != nil {
+		enc := h.pickEncoder(job, *req.HWCodec)
+		opts.VideoCodec = &enc
+	}
+	if !opts.RemoveVideo && (req.VideoCodec == nil || *req.VideoCodec != "copy") {
+		// Checked in Convert.
+		opts.HDR, _ = h.hdrHandling(uf.MediaInfo, req.HDR, req.ToneMap)
+		h.logHDR(job, uf.MediaInfo, req.HDR, opts.HDR)
+	}
+
+	var loudness *metrics.LoudnessRecord
+	if req.Normalize || req.LoudnessParams.IsSet() {
+		opts.Normalize = true
+		opts.Loudness = loudnessTarget(req.LoudnessParams)
+		opts.OnLoudness = h.loudnessRecorder(job, &loudness)
+	}
+	if meta != nil {
+		meta.FastStart = opts.FastStart
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
 	defer cancel()
 
-	// Progress handler
-	progressHandler := func(current, total, outTimeMs float64) {
-		h.jobManager.SetProgress(job.ID, current, outTimeMs)
+	progress := h.trackProgress(job)
+	encodeProgress, metaProgress := metadataProgress(progress, meta)
+	convertErr := ffmpeg.Convert(ctx, opts, encodeProgress)
+	if opts.VideoCodec != nil {
+		if software, ok := h.hwFallback(job, *opts.VideoCodec, convertErr); ok {
+			opts.VideoCodec = &software
+			convertErr = ffmpeg.Convert(ctx, opts, encodeProgress)
+		}
+	}
+	if convertErr == nil {
+		convertErr = h.writeMetadata(ctx, job.ID, outputPath, meta, metaProgress)
 	}
-
-	convertErr := ffmpeg.Convert(ctx, opts, progressHandler)
 	elapsed := time.Since(start).Seconds()
 	avgCPU, peakRAM := sampler.Stop()
 
 	inMB := fileSizeMB(uf.StoragePath)
 	outMB := 0.0
+	var quality *metrics.QualityRecord
 	if convertErr == nil {
 		outMB = fileSizeMB(outputPath)
+		h.verifyLoudness(job, outputPath, loudness)
+		if req.QualityMetrics {
+			quality = h.convertQuality(job, uf, req, opts, outputPath)
+		}
+		convertErr = h.exportQC(job, outputPath, req.QC)
 	}
 	var videoDur float64
 	if req.TrimDuration != nil {
@@ -351,20 +510,25 @@ func (h *Handler) performConvert(job *jobs.Job, uf *storage.UploadedFile, req *v
 		InputSizeMB:       inMB,
 		OutputSizeMB:      outMB,
 		SpeedRatio:        speedRatio,
-		FFmpegSpeed:       -1,
-		FFmpegFPS:         -1,
+		FFmpegSpeed:       progress.speed,
+		FFmpegFPS:         progress.fps,
 		AvgCPUPercent:     avgCPU,
 		PeakRAMMB:         peakRAM,
 		OutputFormat:      req.OutputFormat,
 		Strategy:          "reencode",
 		Success:           convertErr == nil,
 		Error:             errStr(convertErr),
+		ErrorCode:         errCode(convertErr),
 		GPUUsed:           snap.GPU != nil,
+		Resolution:        job.Workload.Resolution,
+		Encoder:           job.Workload.Encoder,
+		MediaSecs:         job.Workload.MediaSecs,
+		Loudness:          loudness,
+		Quality:           quality,
 	})
 
 	if convertErr != nil {
-		h.jobManager.AddLog(job.ID, fmt.Sprintf("Error: %v", convertErr))
-		h.jobManager.SetError(job.ID, convertErr.Error())
+		h.failJob(job, convertErr)
 		return
 	}
 	h.jobManager.AddLog(job.ID, "Conversion completed successfully")
@@ -388,6 +552,7 @@ func (h *Handler) Merge(c *fiber.Ctx) error {
 
 	var inputPaths []string
 	var duration float64
+	var firstInfo *storage.MediaInfo
 	for _, id := range req.FileIDs {
 		uf := h.storage.Get(id)
 		if uf == nil {
@@ -396,6 +561,9 @@ func (h *Handler) Merge(c *fiber.Ctx) error {
 			})
 		}
 		inputPaths = append(inputPaths, uf.StoragePath)
+		if firstInfo == nil {
+			firstInfo = uf.MediaInfo
+		}
 		if uf.MediaInfo != nil && uf.MediaInfo.Duration != nil {
 			duration += *uf.MediaInfo.Duration
 		}
@@ -403,6 +571,8 @@ func (h *Handler) Merge(c *fiber.Ctx) error {
 
 	// Create job using the first file's ID as anchor
 	job := h.jobManager.CreateJob(req.FileIDs[0], "Merged_Video", req.OutputFormat)
+	h.jobManager.SetWorkload(job.ID, workload("merge", req.OutputFormat, firstInfo,
+		encoderLabel(req.OutputFormat, nil, h.cfg.ResolvedHWEncoder), duration))
 
 	reqCopy := req
 	if err := h.jobManager.Enqueue(job, func() {
@@ -448,14 +618,12 @@ func (h *Handler) performMerge(job *jobs.Job, inputPaths []string, totalDuration
 	h.jobManager.SetStage(job.ID, "preparing merge")
 	h.jobManager.AddLog(job.ID, fmt.Sprintf("Strategy: %s (%d files)", strategy, len(inputPaths)))
 
-	progressHandler := func(current, total, outTimeMs float64) {
-		if totalDuration > 0 {
-			pct := (outTimeMs / 1000.0) / totalDuration
-			h.jobManager.SetProgress(job.ID, pct, outTimeMs)
-		}
+	progress := h.trackProgress(job)
+	mergeErr := ffmpeg.Merge(ctx, opts, progress.handle)
+	if _, ok := h.hwFallback(job, opts.HWEncoder, mergeErr); ok {
+		opts.HWEncoder = ""
+		mergeErr = ffmpeg.Merge(ctx, opts, progress.handle)
 	}
-
-	mergeErr := ffmpeg.Merge(ctx, opts, progressHandler)
 	elapsed := time.Since(start).Seconds()
 	avgCPU, peakRAM := sampler.Stop()
 
@@ -480,20 +648,23 @@ func (h *Handler) performMerge(job *jobs.Job, inputPaths []string, totalDuration
 		InputSizeMB:       inMB,
 		OutputSizeMB:      outMB,
 		SpeedRatio:        speedRatio,
-		FFmpegSpeed:       -1,
-		FFmpegFPS:         -1,
+		FFmpegSpeed:       progress.speed,
+		FFmpegFPS:         progress.fps,
 		AvgCPUPercent:     avgCPU,
 		PeakRAMMB:         peakRAM,
 		OutputFormat:      req.OutputFormat,
 		Strategy:          strategy,
 		Success:           mergeErr == nil,
 		Error:             errStr(mergeErr),
+		ErrorCode:         errCode(mergeErr),
 		GPUUsed:           snap.GPU != nil,
+		Resolution:        job.Workload.Resolution,
+		Encoder:           job.Workload.Encoder,
+		MediaSecs:         job.Workload.MediaSecs,
 	})
 
 	if mergeErr != nil {
-		h.jobManager.AddLog(job.ID, fmt.Sprintf("Error: %v", mergeErr))
-		h.jobManager.SetError(job.ID, mergeErr.Error())
+		h.failJob(job, mergeErr)
 		return
 	}
 	h.jobManager.SetStage(job.ID, "done")
@@ -531,6 +702,12 @@ func (h *Handler) Download(c *fiber.Ctx) error {
 		})
 	}
 
+	if job.OutputFilename == "" {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{
+			"error": "Job has no output file",
+		})
+	}
+
 	outputPath := filepath.Join(h.cfg.OutputDir, job.OutputFilename)
 	if _, err := os.Stat(outputPath); os.IsNotExist(err) {
 		return c.Status(http.StatusNotFound).JSON(fiber.Map{
@@ -576,6 +753,32 @@ func (h *Handler) TimelineExport(c *fiber.Ctx) error {
 	if err := req.Validate(); err != nil {
 		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
+	var clipChannels []*string
+	for _, rc := range req.Clips {
+		clipChannels = append(clipChannels, rc.Channels)
+	}
+	features := exportFeatures(req.Normalize, req.LoudnessParams, req.QualityMetrics, req.QC, h.cleanupOptions(req.Cleanup), clipChannels...)
+	if req.Music != nil {
+		features = append(features, "music_ducking")
+	}
+	features = append(features, colorFeatures(req.ColorParams)...)
+	for _, rc := range req.Clips {
+		features = append(features, colorFeatures(rc.ColorParams)...)
+		features = append(features, retimeFeatures(rc)...)
+		features = append(features, animationFeatures(rc)...)
+	}
+	if err := h.requireFeatures(features...); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if _, err := h.musicBed(req.Music); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if _, err := h.colorGrade(req.ColorParams); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if _, err := h.outputMetadata(req.MetadataParams); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
 
 	// Resolve file IDs → storage paths and populate HasVideo/HasAudio.
 	clips := make([]ffmpeg.TimelineExportClip, 0, len(req.Clips))
@@ -587,10 +790,32 @@ func (h *Handler) TimelineExport(c *fiber.Ctx) error {
 			})
 		}
 		hasVideo, hasAudio := true, true
+		var width, height int
 		if uf.MediaInfo != nil {
 			hasVideo = uf.MediaInfo.HasVideo
 			hasAudio = uf.MediaInfo.HasAudio
+			fmt.Sscanf(uf.MediaInfo.Resolution, "%dx%d", &width, &height)
+		}
+		retimed := rc.Reverse || rc.Freeze != nil || len(rc.SpeedRamp) > 0
+		animated := rc.Keyframes != nil && rc.Keyframes.HasVideo()
+		zoomed := rc.Keyframes != nil && len(rc.Keyframes.Zoom) > 0 && hasVideo && !isAudioOnlyOutputFormat(req.OutputFormat)
+		if zoomed && (width == 0 || height == 0) {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("file %s: zoom keyframes need a video frame size", rc.FileID)})
+		}
+		if req.Mode == "precise" || rc.Stabilize != nil || retimed || animated {
+			if err := h.requireDecoder(uf.MediaInfo); err != nil {
+				return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+			}
+		}
+		stabilize, err := h.stabilization(rc.Stabilize)
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		grade, err := h.colorGrade(rc.ColorParams)
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 		}
+		ramp, freeze := clipRetime(rc)
 		clips = append(clips, ffmpeg.TimelineExportClip{
 			FileID:      rc.FileID,
 			FilePath:    uf.StoragePath,
@@ -598,16 +823,26 @@ func (h *Handler) TimelineExport(c *fiber.Ctx) error {
 			Duration:    rc.Duration,
 			HasVideo:    hasVideo,
 			HasAudio:    hasAudio,
+			Channels:    channelOptions(rc.Channels, rc.Pan),
+			Stabilize:   stabilize,
+			Grade:       grade,
+			Reverse:     rc.Reverse,
+			Ramp:        ramp,
+			Freeze:      freeze,
+			Animate:     clipAnimation(rc.Keyframes),
+			Width:       width,
+			Height:      height,
 		})
 	}
 
 	firstUF := h.storage.Get(req.Clips[0].FileID)
 	job := h.jobManager.CreateJob(req.Clips[0].FileID, firstUF.OriginalName, req.OutputFormat)
+	h.jobManager.SetWorkload(job.ID, h.timelineWorkload(req.OutputFormat, h.requestCodec(req.VideoCodec, req.HWCodec), firstUF.MediaInfo, clips))
 
 	reqCopy := req
 	clipsCopy := clips
 	if err := h.jobManager.Enqueue(job, func() {
-		h.performTimelineExport(job, clipsCopy, &reqCopy)
+		h.performTimelineExport(job, h.trackProgress(job), clipsCopy, &reqCopy)
 	}); err != nil {
 		return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -633,7 +868,7 @@ func errStr(err error) string {
 	return err.Error()
 }
 
-func (h *Handler) performTimelineExport(job *jobs.Job, clips []ffmpeg.TimelineExportClip, req *validator.TimelineExportRequest) {
+func (h *Handler) performTimelineExport(job *jobs.Job, progress *jobProgress, clips []ffmpeg.TimelineExportClip, req *validator.TimelineExportRequest) {
 	start := time.Now()
 	sampler := metrics.NewSampler()
 	h.jobManager.AddLog(job.ID, "Timeline export started")
@@ -648,6 +883,7 @@ func (h *Handler) performTimelineExport(job *jobs.Job, clips []ffmpeg.TimelineEx
 		FFmpegPath:   h.cfg.FFmpegPath,
 		FFprobePath:  h.cfg.FFprobePath,
 		VideoCodec:   req.VideoCodec,
+		VideoProfile: req.VideoProfile,
 		AudioCodec:   req.AudioCodec,
 		VideoBitrate: req.VideoBitrate,
 		AudioBitrate: req.AudioBitrate,
@@ -667,6 +903,7 @@ func (h *Handler) performTimelineExport(job *jobs.Job, clips []ffmpeg.TimelineEx
 		Normalize:    req.Normalize,
 		Bass:         req.Bass,
 		Treble:       req.Treble,
+		Cleanup:      h.cleanupOptions(req.Cleanup),
 		PresetMode:   h.cfg.PresetMode,
 		Mode:         req.Mode,
 		HWEncoder:    h.cfg.ResolvedHWEncoder,
@@ -686,6 +923,44 @@ func (h *Handler) performTimelineExport(job *jobs.Job, clips []ffmpeg.TimelineEx
 		opts.Brightness = nil
 		opts.Contrast = nil
 		opts.HWEncoder = ""
+	} else if req.HWCodec != nil {
+		enc := h.pickEncoder(job, *req.HWCodec)
+		opts.VideoCodec = &enc
+	}
+
+	music, err := h.musicBed(req.Music)
+	if err == nil {
+		opts.Grade, err = h.colorGrade(req.ColorParams)
+	}
+	var meta *ffmpeg.Metadata
+	if err == nil {
+		meta, err = h.outputMetadata(req.MetadataParams)
+	}
+	if err != nil {
+		sampler.Stop()
+		h.failJob(job, err)
+		return
+	}
+	opts.Music = music
+	if req.AutoChapters {
+		if meta == nil {
+			meta = &ffmpeg.Metadata{}
+		}
+		titles := make([]string, len(req.Clips))
+		for i, rc := range req.Clips {
+			titles[i] = strings.TrimSpace(rc.Chapter)
+		}
+		meta.Chapters = ffmpeg.ClipChapters(opts, titles)
+	}
+	if meta != nil {
+		meta.FastStart = opts.FastStart
+	}
+
+	var loudness *metrics.LoudnessRecord
+	if req.Normalize || req.LoudnessParams.IsSet() {
+		opts.Normalize = true
+		opts.Loudness = loudnessTarget(req.LoudnessParams)
+		opts.OnLoudness = h.loudnessRecorder(job, &loudness)
 	}
 
 	strategy := "stream_copy"
@@ -699,25 +974,36 @@ func (h *Handler) performTimelineExport(job *jobs.Job, clips []ffmpeg.TimelineEx
 	defer cancel()
 	h.jobManager.SetCancelFunc(job.ID, cancel)
 
-	progressHandler := func(current, _, outTimeMs float64) {
-		h.jobManager.SetProgress(job.ID, current, outTimeMs)
-	}
 	stageHandler := func(stage string) {
 		h.jobManager.SetStage(job.ID, stage)
 		h.jobManager.AddLog(job.ID, "→ "+stage)
 	}
 
-	exportErr := ffmpeg.TimelineExport(ctx, opts, progressHandler, stageHandler)
+	encodeProgress, metaProgress := metadataProgress(progress, meta)
+	exportErr := ffmpeg.TimelineExport(ctx, opts, encodeProgress, stageHandler)
+	if software, ok := h.hwFallback(job, ffmpeg.VideoEncoderFor(req.OutputFormat, opts.VideoCodec, opts.HWEncoder), exportErr); ok {
+		opts.HWEncoder, opts.VideoCodec = "", &software
+		exportErr = ffmpeg.TimelineExport(ctx, opts, encodeProgress, stageHandler)
+	}
+	if exportErr == nil {
+		exportErr = h.writeMetadata(ctx, job.ID, outputPath, meta, metaProgress)
+	}
 	elapsed := time.Since(start).Seconds()
 	avgCPU, peakRAM := sampler.Stop()
 
 	var inMB, outMB, totalDur float64
+	var quality *metrics.QualityRecord
 	for _, cl := range clips {
 		inMB += fileSizeMB(cl.FilePath)
-		totalDur += cl.Duration
+		totalDur += cl.OutputDuration()
 	}
 	if exportErr == nil {
 		outMB = fileSizeMB(outputPath)
+		h.verifyLoudness(job, outputPath, loudness)
+		if req.QualityMetrics {
+			quality = h.timelineQuality(job, clips, opts, strategy, outputPath)
+		}
+		exportErr = h.exportQC(job, outputPath, req.QC)
 	}
 	speedRatio := 0.0
 	if elapsed > 0 && totalDur > 0 {
@@ -734,20 +1020,25 @@ func (h *Handler) performTimelineExport(job *jobs.Job, clips []ffmpeg.TimelineEx
 		InputSizeMB:       inMB,
 		OutputSizeMB:      outMB,
 		SpeedRatio:        speedRatio,
-		FFmpegSpeed:       -1,
-		FFmpegFPS:         -1,
+		FFmpegSpeed:       progress.speed,
+		FFmpegFPS:         progress.fps,
 		AvgCPUPercent:     avgCPU,
 		PeakRAMMB:         peakRAM,
 		OutputFormat:      req.OutputFormat,
 		Strategy:          strategy,
 		Success:           exportErr == nil,
 		Error:             errStr(exportErr),
+		ErrorCode:         errCode(exportErr),
 		GPUUsed:           snap.GPU != nil,
+		Resolution:        job.Workload.Resolution,
+		Encoder:           job.Workload.Encoder,
+		MediaSecs:         job.Workload.MediaSecs,
+		Loudness:          loudness,
+		Quality:           quality,
 	})
 
 	if exportErr != nil {
-		h.jobManager.AddLog(job.ID, "Error: "+exportErr.Error())
-		h.jobManager.SetError(job.ID, exportErr.Error())
+		h.failJob(job, exportErr)
 		return
 	}
 	h.jobManager.AddLog(job.ID, fmt.Sprintf("Completed in %.1fs (strategy: %s)", elapsed, strategy))
@@ -819,6 +1110,7 @@ func (h *Handler) DeleteFile(c *fiber.Ctx) error {
 
 	// Remove from storage
 	h.storage.Delete(fileID)
+	h.analyses.forget(fileID)
 
 	return c.Status(http.StatusOK).JSON(fiber.Map{
 		"success": true,
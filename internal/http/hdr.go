@@ -0,0 +1,54 @@
+package http
+
+import (
+	"fmt"
+	"strings"
+
+	"ffmeditor/internal/ffmpeg"
+	"ffmeditor/internal/jobs"
+	"ffmeditor/internal/storage"
+)
+
+// hdrHandling resolves a conversion's HDR mode for the source info. It
+// returns nil for SDR sources and mode "off", and also in mode "auto" when
+// ffmpeg lacks the tone mapping filters, leaving the colours as they are;
+// mode "tonemap" fails instead.
+func (h *Handler) hdrHandling(info *storage.MediaInfo, mode, algorithm *string) (*ffmpeg.HDRHandling, error) {
+	m := "auto"
+	if mode != nil {
+		m = *mode
+	}
+	if info == nil || info.HDR == "" || m == "off" {
+		return nil, nil
+	}
+	if m == "preserve" {
+		return &ffmpeg.HDRHandling{Preserve: true}, nil
+	}
+	if err := h.requireFeatures("tonemap"); err != nil {
+		if m == "auto" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	hdr := &ffmpeg.HDRHandling{}
+	if algorithm != nil {
+		hdr.Algorithm = *algorithm
+	}
+	return hdr, nil
+}
+
+// logHDR notes in the job log what happens to an HDR source's colours.
+func (h *Handler) logHDR(job *jobs.Job, info *storage.MediaInfo, mode *string, hdr *ffmpeg.HDRHandling) {
+	if info == nil || info.HDR == "" {
+		return
+	}
+	format := strings.ToUpper(info.HDR)
+	switch {
+	case hdr != nil && hdr.Preserve:
+		h.jobManager.AddLog(job.ID, fmt.Sprintf("Keeping %s HDR", format))
+	case hdr != nil:
+		h.jobManager.AddLog(job.ID, fmt.Sprintf("Tone mapping %s HDR to SDR", format))
+	case mode == nil || *mode == "auto":
+		h.jobManager.AddLog(job.ID, fmt.Sprintf("%s HDR source left as is: this server's ffmpeg cannot tone map (zscale, tonemap)", format))
+	}
+}
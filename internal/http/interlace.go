@@ -0,0 +1,110 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"ffmeditor/internal/ffmpeg"
+	"ffmeditor/internal/jobs"
+	"ffmeditor/internal/validator"
+)
+
+// GetFileInterlace classifies the first frames of a file with idet, alongside
+// the field order its container declares.
+func (h *Handler) GetFileInterlace(c *fiber.Ctx) error {
+	uf := h.storage.Get(c.Params("id"))
+	if uf == nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "File not found"})
+	}
+	if uf.MediaInfo == nil || !uf.MediaInfo.HasVideo {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "File has no video"})
+	}
+	if err := h.requireFeatures("interlace_detection"); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	r, err := ffmpeg.DetectInterlace(ctx, h.cfg.FFmpegPath, []string{"-i", uf.StoragePath})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	declared := ""
+	for _, st := range uf.MediaInfo.Streams {
+		if st.Type == "video" && !st.AttachedPic {
+			declared = st.FieldOrder
+			break
+		}
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"file_id":              uf.ID,
+		"interlaced":           r.Interlaced(),
+		"field_order":          r.FieldOrder(),
+		"declared_field_order": declared,
+		"frames":               r,
+	})
+}
+
+// deinterlace resolves a deinterlace mode, or returns nil for none. "auto"
+// detects interlacing first and uses bwdif, or yadif when bwdif is missing.
+func (h *Handler) deinterlace(job *jobs.Job, mode *string) (*ffmpeg.Deinterlace, error) {
+	if mode == nil {
+		return nil, nil
+	}
+	if *mode != "auto" {
+		if err := h.requireFeatures(*mode); err != nil {
+			return nil, err
+		}
+		return &ffmpeg.Deinterlace{Filter: *mode}, nil
+	}
+	if err := h.requireFeatures("interlace_detection"); err != nil {
+		return nil, err
+	}
+	d := &ffmpeg.Deinterlace{Filter: "bwdif", Auto: true}
+	if h.requireFeatures("bwdif") != nil {
+		if err := h.requireFeatures("yadif"); err != nil {
+			return nil, err
+		}
+		d.Filter = "yadif"
+	}
+	if job != nil {
+		d.OnDetect = func(r ffmpeg.InterlaceReport) {
+			verdict := "progressive, not deinterlacing"
+			if r.Interlaced() {
+				verdict = fmt.Sprintf("interlaced (%s), deinterlacing with %s", r.FieldOrder(), d.Filter)
+			}
+			h.jobManager.AddLog(job.ID, fmt.Sprintf("Interlace detection: %d TFF, %d BFF, %d progressive frames: %s",
+				r.TFF, r.BFF, r.Progressive, verdict))
+		}
+	}
+	return d, nil
+}
+
+// frameRate converts a request's frame rate and mode. Blending and motion
+// interpolation need their filters.
+func (h *Handler) frameRate(fps *validator.FrameRate, mode *string) (*string, string, error) {
+	if fps == nil {
+		return nil, "", nil
+	}
+	rate, _, err := fps.Rational()
+	if err != nil {
+		return nil, "", err
+	}
+	m := ""
+	if mode != nil {
+		m = *mode
+	}
+	switch m {
+	case "blend":
+		err = h.requireFeatures("frame_blending")
+	case "interpolate":
+		err = h.requireFeatures("motion_interpolation")
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return &rate, m, nil
+}
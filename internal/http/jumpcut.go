@@ -0,0 +1,183 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"ffmeditor/internal/ffmpeg"
+	"ffmeditor/internal/jobs"
+	"ffmeditor/internal/storage"
+	"ffmeditor/internal/validator"
+)
+
+// JumpCut detects pauses in a file and returns a timeline with them removed,
+// ready for POST /timeline/export. With export set it instead starts a job
+// that detects and exports the jump-cut version directly.
+func (h *Handler) JumpCut(c *fiber.Ctx) error {
+	var req validator.JumpCutRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := req.Validate(); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.requireFeatures("jump_cut"); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	uf := h.storage.Get(req.FileID)
+	if uf == nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "File not found"})
+	}
+	if uf.MediaInfo == nil || uf.MediaInfo.Duration == nil || *uf.MediaInfo.Duration <= 0 {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "File duration unknown"})
+	}
+	if !uf.MediaInfo.HasAudio {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "File has no audio stream"})
+	}
+
+	if req.Export {
+		job := h.jobManager.CreateJob(uf.ID, uf.OriginalName, req.OutputFormat)
+		// The cut timeline is not known yet; the whole file is an upper bound.
+		h.jobManager.SetWorkload(job.ID, workload("timeline_export", req.OutputFormat, uf.MediaInfo,
+			encoderLabel(req.OutputFormat, nil, h.cfg.ResolvedHWEncoder), *uf.MediaInfo.Duration))
+		reqCopy := req
+		if err := h.jobManager.Enqueue(job, func() {
+			h.performJumpCut(job, uf, &reqCopy)
+		}); err != nil {
+			return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(http.StatusOK).JSON(fiber.Map{
+			"job_id": job.ID,
+			"status": job.Status,
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	opts := jumpCutOptions(&req)
+	duration := *uf.MediaInfo.Duration
+	silences, err := ffmpeg.DetectSilence(ctx, h.cfg.FFmpegPath, uf.StoragePath, duration, opts, nil)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	timeline := jumpCutTimeline(uf, ffmpeg.JumpCutRanges(silences, duration, opts), &req)
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"file_id":         uf.ID,
+		"silences":        silences,
+		"duration":        duration,
+		"output_duration": timelineDuration(timeline),
+		"timeline":        timeline,
+	})
+}
+
+// silencePassWeight is the share of a jump-cut job's progress spent detecting
+// silence; the export takes the rest.
+const silencePassWeight = 0.2
+
+func (h *Handler) performJumpCut(job *jobs.Job, uf *storage.UploadedFile, req *validator.JumpCutRequest) {
+	opts := jumpCutOptions(req)
+	duration := *uf.MediaInfo.Duration
+	h.jobManager.SetStage(job.ID, "detecting silence")
+	h.jobManager.AddLog(job.ID, fmt.Sprintf("Detecting silence (threshold %.0f dB, min %.2fs, padding %.2fs)",
+		opts.ThresholdDB, opts.MinSilence, opts.Padding))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	h.jobManager.SetCancelFunc(job.ID, cancel)
+
+	progress := h.trackProgress(job)
+	silences, err := ffmpeg.DetectSilence(ctx, h.cfg.FFmpegPath, uf.StoragePath, duration, opts, progress.scaled(0, silencePassWeight))
+	if err != nil {
+		h.failJob(job, err)
+		return
+	}
+	timeline := jumpCutTimeline(uf, ffmpeg.JumpCutRanges(silences, duration, opts), req)
+	if len(timeline.Clips) == 0 {
+		h.jobManager.AddLog(job.ID, "Error: no audible segments found")
+		h.jobManager.SetError(job.ID, "no audible segments found; try a lower threshold_db")
+		return
+	}
+	if err := timeline.Validate(); err != nil {
+		h.failJob(job, err)
+		return
+	}
+	kept := timelineDuration(timeline)
+	h.jobManager.AddLog(job.ID, fmt.Sprintf("Found %d pauses; keeping %d segments (%.1fs of %.1fs)",
+		len(silences), len(timeline.Clips), kept, duration))
+
+	clips := make([]ffmpeg.TimelineExportClip, 0, len(timeline.Clips))
+	for _, tc := range timeline.Clips {
+		clips = append(clips, ffmpeg.TimelineExportClip{
+			FileID:      uf.ID,
+			FilePath:    uf.StoragePath,
+			SourceStart: tc.SourceStart,
+			Duration:    tc.Duration,
+			HasVideo:    uf.MediaInfo.HasVideo,
+			HasAudio:    uf.MediaInfo.HasAudio,
+		})
+	}
+	h.performTimelineExport(job, progress.after(silencePassWeight), clips, timeline)
+}
+
+func jumpCutOptions(req *validator.JumpCutRequest) ffmpeg.SilenceOptions {
+	opts := ffmpeg.DefaultSilenceOptions
+	if req.ThresholdDB != nil {
+		opts.ThresholdDB = *req.ThresholdDB
+	}
+	if req.MinSilence != nil {
+		opts.MinSilence = *req.MinSilence
+	}
+	if req.Padding != nil {
+		opts.Padding = *req.Padding
+	}
+	if req.MinKeep != nil {
+		opts.MinKeep = *req.MinKeep
+	}
+	return opts
+}
+
+// jumpCutTimeline turns kept ranges into a timeline export request. Jump cuts
+// land between keyframes, so the default mode is precise.
+func jumpCutTimeline(uf *storage.UploadedFile, keep []ffmpeg.TimeRange, req *validator.JumpCutRequest) *validator.TimelineExportRequest {
+	format := strings.ToLower(req.OutputFormat)
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(uf.StoragePath)), ".")
+		if !validator.AllowedOutputFormats[format] {
+			format = "mp4"
+		}
+	}
+	mode := req.Mode
+	if mode == "" {
+		mode = "precise"
+	}
+	clips := make([]validator.TimelineClip, 0, len(keep))
+	for _, r := range keep {
+		clips = append(clips, validator.TimelineClip{
+			FileID:      uf.ID,
+			SourceStart: r.Start,
+			Duration:    r.Duration(),
+		})
+	}
+	return &validator.TimelineExportRequest{
+		Clips:        clips,
+		OutputFormat: format,
+		Mode:         mode,
+	}
+}
+
+func timelineDuration(req *validator.TimelineExportRequest) float64 {
+	total := 0.0
+	for _, c := range req.Clips {
+		total += c.Duration
+	}
+	return total
+}
@@ -0,0 +1,37 @@
+package http
+
+import (
+	"ffmeditor/internal/ffmpeg"
+	"ffmeditor/internal/validator"
+)
+
+// clipAnimation converts a timeline clip's keyframes, or returns nil.
+func clipAnimation(p *validator.AnimationParams) *ffmpeg.ClipAnimation {
+	if p == nil {
+		return nil
+	}
+	conv := func(ks []validator.Keyframe) ffmpeg.Keyframes {
+		var out ffmpeg.Keyframes
+		for _, k := range ks {
+			out = append(out, ffmpeg.Keyframe{Time: k.Time, Value: k.Value, Ease: k.Ease})
+		}
+		return out
+	}
+	return &ffmpeg.ClipAnimation{
+		Volume:     conv(p.Volume),
+		Brightness: conv(p.Brightness),
+		Contrast:   conv(p.Contrast),
+		Saturation: conv(p.Saturation),
+		Zoom:       conv(p.Zoom),
+		FocusX:     conv(p.FocusX),
+		FocusY:     conv(p.FocusY),
+	}
+}
+
+// animationFeatures lists the optional features a clip's keyframes use.
+func animationFeatures(rc validator.TimelineClip) []string {
+	if rc.Keyframes != nil && len(rc.Keyframes.Zoom) > 0 {
+		return []string{"keyframed_zoom"}
+	}
+	return nil
+}
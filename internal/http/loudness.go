@@ -0,0 +1,70 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"ffmeditor/internal/ffmpeg"
+	"ffmeditor/internal/jobs"
+	"ffmeditor/internal/metrics"
+	"ffmeditor/internal/validator"
+)
+
+// loudnessTarget resolves the request's preset and overrides into a target.
+func loudnessTarget(p validator.LoudnessParams) *ffmpeg.LoudnessTarget {
+	preset := ""
+	if p.LoudnessPreset != nil {
+		preset = *p.LoudnessPreset
+	}
+	t := ffmpeg.ResolveLoudnessTarget(preset, p.LoudnessI, p.LoudnessTP, p.LoudnessLRA)
+	return &t
+}
+
+// loudnessRecorder returns an OnLoudness callback that logs the first-pass
+// measurement and stores it in rec.
+func (h *Handler) loudnessRecorder(job *jobs.Job, rec **metrics.LoudnessRecord) func(ffmpeg.LoudnessTarget, ffmpeg.LoudnessMeasurement) {
+	return func(t ffmpeg.LoudnessTarget, m ffmpeg.LoudnessMeasurement) {
+		h.jobManager.AddLog(job.ID, fmt.Sprintf(
+			"Loudness measured: I=%.1f LUFS, TP=%.1f dBTP, LRA=%.1f LU (target I=%.1f, TP=%.1f, LRA=%.1f)",
+			m.InputI, m.InputTP, m.InputLRA, t.I, t.TP, t.LRA))
+		*rec = &metrics.LoudnessRecord{
+			TargetI:   t.I,
+			TargetTP:  t.TP,
+			TargetLRA: t.LRA,
+			InputI:    finitePtr(m.InputI),
+			InputTP:   finitePtr(m.InputTP),
+			InputLRA:  finitePtr(m.InputLRA),
+		}
+	}
+}
+
+// verifyLoudness measures the finished output and fills the Output* fields of rec.
+// Failures are logged but do not fail the job.
+func (h *Handler) verifyLoudness(job *jobs.Job, outputPath string, rec *metrics.LoudnessRecord) {
+	if rec == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	target := ffmpeg.LoudnessTarget{I: rec.TargetI, TP: rec.TargetTP, LRA: rec.TargetLRA}
+	m, err := ffmpeg.MeasureLoudness(ctx, h.cfg.FFmpegPath, outputPath, target)
+	if err != nil {
+		h.jobManager.AddLog(job.ID, fmt.Sprintf("Loudness verification failed: %v", err))
+		return
+	}
+	rec.OutputI = finitePtr(m.InputI)
+	rec.OutputTP = finitePtr(m.InputTP)
+	rec.OutputLRA = finitePtr(m.InputLRA)
+	h.jobManager.AddLog(job.ID, fmt.Sprintf("Output loudness: I=%.1f LUFS, TP=%.1f dBTP, LRA=%.1f LU",
+		m.InputI, m.InputTP, m.InputLRA))
+}
+
+// finitePtr returns nil for NaN/±Inf so the value can be JSON-encoded.
+func finitePtr(v float64) *float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
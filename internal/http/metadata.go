@@ -0,0 +1,205 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"ffmeditor/internal/ffmpeg"
+	"ffmeditor/internal/storage"
+	"ffmeditor/internal/validator"
+)
+
+// maxCoverBytes bounds a cover image upload.
+const maxCoverBytes = 10 << 20
+
+// coverTypes maps the cover image types to their extensions.
+var coverTypes = map[string]string{"image/jpeg": "jpg", "image/png": "png"}
+
+// GetFileMetadata lists an uploaded file's global tags and chapters.
+func (h *Handler) GetFileMetadata(c *fiber.Ctx) error {
+	uf := h.storage.Get(c.Params("id"))
+	if uf == nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "File not found"})
+	}
+	tags := map[string]string{}
+	chapters := []storage.Chapter{}
+	if uf.MediaInfo != nil {
+		if uf.MediaInfo.Tags != nil {
+			tags = uf.MediaInfo.Tags
+		}
+		if uf.MediaInfo.Chapters != nil {
+			chapters = uf.MediaInfo.Chapters
+		}
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"file_id":  uf.ID,
+		"tags":     tags,
+		"chapters": chapters,
+	})
+}
+
+// storageChapters converts probed chapters to the copies kept with an upload.
+func storageChapters(chapters []ffmpeg.Chapter) []storage.Chapter {
+	out := make([]storage.Chapter, len(chapters))
+	for i, ch := range chapters {
+		out[i] = storage.Chapter(ch)
+	}
+	return out
+}
+
+// UploadCover stores a JPEG or PNG for cover_id in convert and timeline
+// requests.
+func (h *Handler) UploadCover(c *fiber.Ctx) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "No file uploaded"})
+	}
+	if file.Size > maxCoverBytes {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("Cover too large (max %d MB)", maxCoverBytes>>20)})
+	}
+	src, err := file.Open()
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to read file"})
+	}
+	defer src.Close()
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to read file"})
+	}
+	mime := http.DetectContentType(data)
+	ext, ok := coverTypes[mime]
+	if !ok {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Covers must be JPEG or PNG images"})
+	}
+
+	id := uuid.New().String()
+	path := h.storage.GetStoragePath(id, ext)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save file"})
+	}
+	asset := &storage.Asset{
+		ID:           id,
+		Kind:         "cover",
+		OriginalName: validator.SanitizeFilename(file.Filename),
+		StoragePath:  path,
+		UploadedAt:   time.Now(),
+		MIMEType:     mime,
+	}
+	h.storage.StoreAsset(asset)
+	return c.Status(http.StatusOK).JSON(coverJSON(asset))
+}
+
+// ListCovers lists the uploaded cover images.
+func (h *Handler) ListCovers(c *fiber.Ctx) error {
+	covers := []fiber.Map{}
+	for _, a := range h.storage.Assets("cover") {
+		covers = append(covers, coverJSON(a))
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{"covers": covers})
+}
+
+// DeleteCover removes an uploaded cover image.
+func (h *Handler) DeleteCover(c *fiber.Ctx) error {
+	a := h.storage.GetAsset("cover", c.Params("id"))
+	if a == nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Cover not found"})
+	}
+	os.Remove(a.StoragePath)
+	h.storage.DeleteAsset(a.ID)
+	return c.Status(http.StatusOK).JSON(fiber.Map{"success": true})
+}
+
+func coverJSON(a *storage.Asset) fiber.Map {
+	return fiber.Map{
+		"cover_id":      a.ID,
+		"original_name": a.OriginalName,
+		"uploaded_at":   a.UploadedAt,
+		"type":          a.MIMEType,
+	}
+}
+
+// outputMetadata resolves a request's tags, cover and chapters, or returns
+// nil when it sets none. It fails when the cover does not exist.
+func (h *Handler) outputMetadata(p validator.MetadataParams) (*ffmpeg.Metadata, error) {
+	m := &ffmpeg.Metadata{}
+	if t := p.Metadata; t != nil {
+		m.Tags = map[string]string{}
+		for key, v := range map[string]*string{
+			"title": t.Title, "artist": t.Artist, "album": t.Album, "comment": t.Comment, "date": t.Date,
+		} {
+			if v != nil {
+				m.Tags[key] = *v
+			}
+		}
+	}
+	if p.CoverID != nil {
+		a := h.storage.GetAsset("cover", *p.CoverID)
+		if a == nil {
+			return nil, fmt.Errorf("cover %s not found", *p.CoverID)
+		}
+		m.CoverPath = a.StoragePath
+	}
+	for _, ch := range p.Chapters {
+		out := ffmpeg.Chapter{Start: ch.Start, Title: strings.TrimSpace(ch.Title)}
+		if ch.End != nil {
+			out.End = *ch.End
+		}
+		m.Chapters = append(m.Chapters, out)
+	}
+	if !m.IsSet() {
+		return nil, nil
+	}
+	return m, nil
+}
+
+// metadataPassWeight is the share of a job's progress given to writing
+// metadata after the encode.
+const metadataPassWeight = 0.05
+
+// metadataProgress splits progress between the encode and the metadata pass
+// that follows it when m writes anything. The metadata pass only copies
+// streams, so its speed and fps stay out of the operation record.
+func metadataProgress(progress *jobProgress, m *ffmpeg.Metadata) (encode, write ffmpeg.ProgressHandler) {
+	if !m.IsSet() {
+		return progress.handle, nil
+	}
+	meta := progress.scaled(1-metadataPassWeight, metadataPassWeight)
+	return progress.scaled(0, 1-metadataPassWeight), func(ev ffmpeg.ProgressEvent) {
+		ev.Speed, ev.FPS = 0, 0
+		meta(ev)
+	}
+}
+
+// writeMetadata writes m to a finished output, if there is anything to write.
+// The output is removed when that fails.
+func (h *Handler) writeMetadata(ctx context.Context, jobID, path string, m *ffmpeg.Metadata, ph ffmpeg.ProgressHandler) error {
+	if !m.IsSet() {
+		return nil
+	}
+	h.jobManager.SetStage(jobID, "writing metadata")
+	var parts []string
+	if len(m.Tags) > 0 {
+		parts = append(parts, fmt.Sprintf("%d tags", len(m.Tags)))
+	}
+	if m.CoverPath != "" {
+		parts = append(parts, "cover art")
+	}
+	if len(m.Chapters) > 0 {
+		parts = append(parts, fmt.Sprintf("%d chapters", len(m.Chapters)))
+	}
+	h.jobManager.AddLog(jobID, fmt.Sprintf("Writing %s to %s", strings.Join(parts, ", "), filepath.Base(path)))
+	if err := ffmpeg.WriteMetadata(ctx, h.cfg.FFmpegPath, h.cfg.FFprobePath, path, m, ph); err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package http
+
+import (
+	"fmt"
+
+	"ffmeditor/internal/ffmpeg"
+	"ffmeditor/internal/validator"
+)
+
+// musicBed resolves a timeline's music track, or returns nil when there is
+// none. It fails when the file is gone or has no audio.
+func (h *Handler) musicBed(p *validator.MusicParams) (*ffmpeg.MusicBed, error) {
+	if p == nil {
+		return nil, nil
+	}
+	uf := h.storage.Get(p.FileID)
+	if uf == nil {
+		return nil, fmt.Errorf("music file %s not found", p.FileID)
+	}
+	if uf.MediaInfo != nil && !uf.MediaInfo.HasAudio {
+		return nil, fmt.Errorf("music file %s has no audio stream", p.FileID)
+	}
+	bed := ffmpeg.ResolveMusicBed(uf.StoragePath, p.Gain, p.Threshold, p.Ratio, p.Attack, p.Release, p.Loop)
+	return &bed, nil
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"ffmeditor/internal/ffmpeg"
+	"ffmeditor/internal/jobs"
+)
+
+// jobProgress forwards ffmpeg progress events to a job and remembers the last
+// reported speed and fps for the operation record. ffmpeg's speed and fps are
+// running averages over the pass, so the last values describe the whole pass.
+type jobProgress struct {
+	h     *Handler
+	jobID string
+	speed float64
+	fps   float64
+	// from and span place the tracked passes in the job's progress.
+	from float64
+	span float64
+}
+
+func (h *Handler) trackProgress(job *jobs.Job) *jobProgress {
+	return &jobProgress{h: h, jobID: job.ID, speed: -1, fps: -1, span: 1}
+}
+
+// after returns a tracker for the passes that follow a pre-pass which took
+// the first from of p's progress.
+func (p *jobProgress) after(from float64) *jobProgress {
+	return &jobProgress{h: p.h, jobID: p.jobID, speed: -1, fps: -1,
+		from: p.from + from*p.span, span: (1 - from) * p.span}
+}
+
+// handle is an ffmpeg.ProgressHandler. Updating the progress also refreshes
+// the job's ETA.
+func (p *jobProgress) handle(ev ffmpeg.ProgressEvent) {
+	ev.Progress = p.from + ev.Progress*p.span
+	p.report(ev)
+}
+
+func (p *jobProgress) report(ev ffmpeg.ProgressEvent) {
+	p.h.jobManager.SetStats(p.jobID, jobs.Stats{
+		Frame:       ev.Frame,
+		FPS:         ev.FPS,
+		BitrateKbps: ev.BitrateKbps,
+		TotalSize:   ev.TotalSize,
+		Speed:       ev.Speed,
+		DupFrames:   ev.DupFrames,
+		DropFrames:  ev.DropFrames,
+	})
+	// After the stats: the running ETA falls back on the current speed.
+	p.h.jobManager.SetProgress(p.jobID, ev.Progress, ev.OutTimeMs)
+	if ev.Speed > 0 {
+		p.speed = ev.Speed
+		p.fps = ev.FPS
+	}
+}
+
+// scaled returns a handler that maps a pass's progress into
+// [from, from+span] of p's progress.
+func (p *jobProgress) scaled(from, span float64) ffmpeg.ProgressHandler {
+	return func(ev ffmpeg.ProgressEvent) {
+		ev.Progress = from + ev.Progress*span
+		p.handle(ev)
+	}
+}
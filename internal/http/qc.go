@@ -0,0 +1,194 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"ffmeditor/internal/ffmpeg"
+	"ffmeditor/internal/jobs"
+	"ffmeditor/internal/metrics"
+	"ffmeditor/internal/storage"
+	"ffmeditor/internal/validator"
+)
+
+// StartQC starts a QC job over an uploaded file or a completed job's output.
+// The report is attached to the QC job; the job itself only fails when the
+// analysis cannot run.
+func (h *Handler) StartQC(c *fiber.Ctx) error {
+	var req validator.QCRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := req.Validate(); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.requireFeatures("qc"); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var fileID, name, inputPath string
+	var info *storage.MediaInfo
+	var mediaSecs float64
+	if req.FileID != "" {
+		uf := h.storage.Get(req.FileID)
+		if uf == nil {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "File not found"})
+		}
+		fileID, name, inputPath = uf.ID, uf.OriginalName, uf.StoragePath
+		info = uf.MediaInfo
+		if info != nil && info.Duration != nil {
+			mediaSecs = *info.Duration
+		}
+	} else {
+		src := h.jobManager.GetJob(req.JobID)
+		if src == nil {
+			return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "Job not found"})
+		}
+		if src.Status != jobs.StatusCompleted || src.OutputPath == "" {
+			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Job has no completed output"})
+		}
+		fileID, name, inputPath = src.FileID, src.OutputFilename, src.OutputPath
+		mediaSecs = src.Workload.MediaSecs
+	}
+
+	job := h.jobManager.CreateJob(fileID, name, "qc")
+	h.jobManager.SetWorkload(job.ID, workload("qc", "qc", info, "", mediaSecs))
+	params := req.QCParams
+	if err := h.jobManager.Enqueue(job, func() {
+		h.performQC(job, inputPath, &params)
+	}); err != nil {
+		return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"job_id": job.ID,
+		"status": job.Status,
+	})
+}
+
+func (h *Handler) performQC(job *jobs.Job, inputPath string, params *validator.QCParams) {
+	start := time.Now()
+	sampler := metrics.NewSampler()
+	h.jobManager.AddLog(job.ID, "QC started")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+	defer cancel()
+	h.jobManager.SetCancelFunc(job.ID, cancel)
+
+	progress := h.trackProgress(job)
+	report, qcErr := h.runQC(ctx, job, inputPath, params, progress.handle)
+	elapsed := time.Since(start).Seconds()
+	avgCPU, peakRAM := sampler.Stop()
+
+	speedRatio := 0.0
+	if qcErr == nil && elapsed > 0 {
+		speedRatio = report.Duration / elapsed
+	}
+	snap := metrics.Current()
+	h.opStore.Record(metrics.OperationRecord{
+		OperationID:       job.ID,
+		Operation:         "qc",
+		OriginalName:      job.OriginalName,
+		ProcessingTimeSec: elapsed,
+		InputSizeMB:       fileSizeMB(inputPath),
+		SpeedRatio:        speedRatio,
+		FFmpegSpeed:       progress.speed,
+		FFmpegFPS:         progress.fps,
+		AvgCPUPercent:     avgCPU,
+		PeakRAMMB:         peakRAM,
+		OutputFormat:      "qc",
+		Strategy:          "analysis",
+		Success:           qcErr == nil,
+		Error:             errStr(qcErr),
+		ErrorCode:         errCode(qcErr),
+		GPUUsed:           snap.GPU != nil,
+		Resolution:        job.Workload.Resolution,
+		Encoder:           job.Workload.Encoder,
+		MediaSecs:         job.Workload.MediaSecs,
+	})
+
+	if qcErr != nil {
+		h.failJob(job, qcErr)
+		return
+	}
+	h.jobManager.AddLog(job.ID, fmt.Sprintf("Completed in %.1fs", elapsed))
+	h.jobManager.SetCompleted(job.ID, "")
+}
+
+// exportQC runs the export's QC checks on its finished output. It returns an
+// error only when the checks asked to fail the export and did not pass; the
+// output is then removed.
+func (h *Handler) exportQC(job *jobs.Job, outputPath string, params *validator.QCParams) error {
+	if params == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	h.jobManager.SetStage(job.ID, "qc")
+
+	report, err := h.runQC(ctx, job, outputPath, params, nil)
+	if err != nil {
+		h.jobManager.AddLog(job.ID, fmt.Sprintf("QC could not run: %v", err))
+		if params.FailOnIssues {
+			os.Remove(outputPath)
+			return fmt.Errorf("qc: %w", err)
+		}
+		return nil
+	}
+	if !report.Passed && params.FailOnIssues {
+		os.Remove(outputPath)
+		return fmt.Errorf("%s", report.Summary())
+	}
+	return nil
+}
+
+// runQC probes inputPath, runs the detectors and attaches the report to job.
+func (h *Handler) runQC(ctx context.Context, job *jobs.Job, inputPath string, params *validator.QCParams, ph ffmpeg.ProgressHandler) (*ffmpeg.QCReport, error) {
+	info, err := ffmpeg.GetMediaInfo(ctx, h.cfg.FFprobePath, inputPath)
+	if err != nil {
+		return nil, err
+	}
+	opts := qcOptions(params, info)
+	h.jobManager.SetStage(job.ID, "qc")
+	report, err := ffmpeg.RunQC(ctx, h.cfg.FFmpegPath, inputPath, opts, ph)
+	if err != nil {
+		return nil, err
+	}
+	h.jobManager.SetQCReport(job.ID, report)
+	h.jobManager.AddLog(job.ID, report.Summary())
+	return report, nil
+}
+
+func qcOptions(p *validator.QCParams, info *ffmpeg.MediaInfo) ffmpeg.QCOptions {
+	opts := ffmpeg.QCOptions{
+		HasVideo:   info.HasVideo,
+		HasAudio:   info.HasAudio,
+		Channels:   info.AudioChannels,
+		SampleRate: info.AudioSampleRate,
+	}
+	if info.Duration != nil {
+		opts.Duration = *info.Duration
+	}
+	set := func(dst *float64, v *float64) {
+		if v != nil {
+			*dst = *v
+		}
+	}
+	set(&opts.BlackMinSec, p.BlackMinDuration)
+	set(&opts.BlackPixelThreshold, p.BlackPixelThreshold)
+	set(&opts.FreezeNoiseDB, p.FreezeNoiseDB)
+	set(&opts.FreezeMinSec, p.FreezeMinDuration)
+	set(&opts.SilenceDB, p.SilenceDB)
+	set(&opts.SilenceMinSec, p.SilenceMinDuration)
+	set(&opts.Thresholds.MaxBlackSec, p.MaxBlackSecs)
+	set(&opts.Thresholds.MaxFreezeSec, p.MaxFreezeSecs)
+	set(&opts.Thresholds.MaxSilenceSec, p.MaxSilenceSecs)
+	if p.MaxClippedSamples != nil {
+		opts.Thresholds.MaxClippedSamples = *p.MaxClippedSamples
+	}
+	return opts
+}
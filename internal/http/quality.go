@@ -0,0 +1,164 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ffmeditor/internal/ffmpeg"
+	"ffmeditor/internal/jobs"
+	"ffmeditor/internal/metrics"
+	"ffmeditor/internal/storage"
+	"ffmeditor/internal/validator"
+)
+
+// qualitySettings labels a scored output with the encoder settings that
+// produced it, so /metrics/summary can group scores by preset.
+type qualitySettings struct {
+	VideoCodec string // the encoder actually used
+	Preset     *string
+	CRF        *int
+}
+
+// measureQuality scores outputPath against the reference spans. Failures are
+// logged and return nil; they never fail the job.
+func (h *Handler) measureQuality(job *jobs.Job, outputPath string, refs []ffmpeg.QualityReference, s qualitySettings) *metrics.QualityRecord {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	h.jobManager.SetStage(job.ID, "quality metrics")
+
+	info, err := ffmpeg.GetMediaInfo(ctx, h.cfg.FFprobePath, outputPath)
+	if err != nil {
+		h.jobManager.AddLog(job.ID, fmt.Sprintf("Quality metrics skipped: %v", err))
+		return nil
+	}
+	opts := ffmpeg.QualityOptions{
+		FFmpegPath:    h.cfg.FFmpegPath,
+		DistortedPath: outputPath,
+		References:    refs,
+		VMAF:          h.cfg.VMAFAvailable,
+	}
+	fmt.Sscanf(info.Resolution, "%dx%d", &opts.Width, &opts.Height)
+	if info.Duration != nil {
+		opts.Duration = *info.Duration
+	}
+
+	scores, err := ffmpeg.MeasureQuality(ctx, opts, nil)
+	if err != nil {
+		h.jobManager.AddLog(job.ID, fmt.Sprintf("Quality metrics failed: %v", err))
+		return nil
+	}
+	rec := &metrics.QualityRecord{
+		VideoCodec: s.VideoCodec,
+		Preset:     h.cfg.PresetMode,
+		CRF:        s.CRF,
+		PSNR:       scores.PSNR,
+		SSIM:       scores.SSIM,
+		VMAF:       scores.VMAF,
+	}
+	if s.Preset != nil {
+		rec.Preset = *s.Preset
+	}
+	h.jobManager.AddLog(job.ID, "Quality: "+formatScores(scores))
+	return rec
+}
+
+func formatScores(s *ffmpeg.QualityScores) string {
+	out := ""
+	add := func(name, format string, v *float64) {
+		if v == nil {
+			return
+		}
+		if out != "" {
+			out += ", "
+		}
+		out += name + "=" + fmt.Sprintf(format, *v)
+	}
+	add("PSNR", "%.2f dB", s.PSNR)
+	add("SSIM", "%.4f", s.SSIM)
+	add("VMAF", "%.2f", s.VMAF)
+	return out
+}
+
+// convertQuality scores a convert output. Outputs whose frames no longer line
+// up with the source (speed or frame-rate changes, no video, stream copy,
+// stabilization, grading, padding) are skipped.
+func (h *Handler) convertQuality(job *jobs.Job, uf *storage.UploadedFile, req *validator.ConvertRequest, opts ffmpeg.ConvertOptions, outputPath string) *metrics.QualityRecord {
+	switch {
+	case opts.RemoveVideo || uf.MediaInfo == nil || !uf.MediaInfo.HasVideo:
+		return nil
+	case opts.Speed != nil && *opts.Speed != 1, opts.FPS != nil:
+		h.jobManager.AddLog(job.ID, "Quality metrics skipped: speed/fps changes break frame alignment")
+		return nil
+	case opts.VideoCodec != nil && *opts.VideoCodec == "copy":
+		return nil
+	case opts.Stabilize != nil || opts.Grade != nil || opts.Brightness != nil || opts.Contrast != nil:
+		h.jobManager.AddLog(job.ID, "Quality metrics skipped: stabilization and colour changes alter the frames on purpose")
+		return nil
+	case letterboxed(opts.KeepAspect, opts.ResizeWidth, opts.ResizeHeight):
+		h.jobManager.AddLog(job.ID, "Quality metrics skipped: padded or cropped frames do not line up with the source")
+		return nil
+	}
+	ref := ffmpeg.QualityReference{Path: uf.StoragePath}
+	if req.TrimStart != nil {
+		ref.Start = *req.TrimStart
+	}
+	if req.TrimDuration != nil {
+		ref.Duration = *req.TrimDuration
+	}
+	return h.measureQuality(job, outputPath, []ffmpeg.QualityReference{ref}, qualitySettings{
+		VideoCodec: ffmpeg.ConvertVideoEncoder(req.OutputFormat, opts.VideoCodec),
+		Preset:     opts.Preset,
+		CRF:        opts.CRF,
+	})
+}
+
+// timelineQuality scores a re-encoded timeline export against its clips.
+func (h *Handler) timelineQuality(job *jobs.Job, clips []ffmpeg.TimelineExportClip, opts ffmpeg.TimelineExportOptions, strategy, outputPath string) *metrics.QualityRecord {
+	if strategy != "reencode" || opts.RemoveVideo {
+		return nil
+	}
+	if opts.Speed != nil && *opts.Speed != 1 {
+		h.jobManager.AddLog(job.ID, "Quality metrics skipped: speed changes break frame alignment")
+		return nil
+	}
+	if letterboxed(opts.KeepAspect, opts.ResizeWidth, opts.ResizeHeight) {
+		h.jobManager.AddLog(job.ID, "Quality metrics skipped: padded frames do not line up with the source")
+		return nil
+	}
+	if opts.Grade != nil || opts.Brightness != nil || opts.Contrast != nil {
+		h.jobManager.AddLog(job.ID, "Quality metrics skipped: colour changes alter the frames on purpose")
+		return nil
+	}
+	refs := make([]ffmpeg.QualityReference, 0, len(clips))
+	for _, cl := range clips {
+		if !cl.HasVideo {
+			h.jobManager.AddLog(job.ID, "Quality metrics skipped: timeline has clips without video")
+			return nil
+		}
+		if cl.Reverse || cl.Freeze != nil || len(cl.Ramp) > 0 {
+			h.jobManager.AddLog(job.ID, "Quality metrics skipped: reversed, frozen or ramped clips break frame alignment")
+			return nil
+		}
+		if cl.Animate.IsSet() || cl.Stabilize != nil || cl.Grade != nil {
+			h.jobManager.AddLog(job.ID, "Quality metrics skipped: keyframed, stabilized or graded clips alter the frames on purpose")
+			return nil
+		}
+		refs = append(refs, ffmpeg.QualityReference{Path: cl.FilePath, Start: cl.SourceStart, Duration: cl.Duration})
+	}
+	codec := ffmpeg.VideoEncoderFor(strings.TrimPrefix(filepath.Ext(opts.OutputPath), "."), opts.VideoCodec, opts.HWEncoder)
+	return h.measureQuality(job, outputPath, refs, qualitySettings{
+		VideoCodec: codec,
+		Preset:     opts.Preset,
+		CRF:        opts.CRF,
+	})
+}
+
+// letterboxed reports whether a resize keeping the aspect ratio pads (or, for
+// cover, crops) the picture into both dimensions. The reference is only
+// scaled, so its frames would not line up with the output's.
+func letterboxed(keepAspect bool, width, height *int) bool {
+	return keepAspect && width != nil && height != nil
+}
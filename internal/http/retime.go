@@ -0,0 +1,31 @@
+package http
+
+import (
+	"ffmeditor/internal/ffmpeg"
+	"ffmeditor/internal/validator"
+)
+
+// clipRetime converts a timeline clip's speed ramp and freeze frame.
+func clipRetime(rc validator.TimelineClip) ([]ffmpeg.SpeedKeyframe, *ffmpeg.FreezeFrame) {
+	var ramp []ffmpeg.SpeedKeyframe
+	for _, k := range rc.SpeedRamp {
+		ramp = append(ramp, ffmpeg.SpeedKeyframe{Time: k.Time, Speed: k.Speed})
+	}
+	var freeze *ffmpeg.FreezeFrame
+	if rc.Freeze != nil {
+		freeze = &ffmpeg.FreezeFrame{At: rc.Freeze.At, Hold: rc.Freeze.Hold}
+	}
+	return ramp, freeze
+}
+
+// retimeFeatures lists the optional features a clip's retiming uses.
+func retimeFeatures(rc validator.TimelineClip) []string {
+	var features []string
+	if rc.Reverse {
+		features = append(features, "reverse")
+	}
+	if rc.Freeze != nil {
+		features = append(features, "freeze_frame")
+	}
+	return features
+}
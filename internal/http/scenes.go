@@ -0,0 +1,299 @@
+package http
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"ffmeditor/internal/ffmpeg"
+	"ffmeditor/internal/jobs"
+	"ffmeditor/internal/metrics"
+	"ffmeditor/internal/storage"
+	"ffmeditor/internal/validator"
+)
+
+// sceneResult is the cached response body of GET /files/:id/scenes.
+type sceneResult struct {
+	Threshold float64                          `json:"threshold"`
+	MinScene  float64                          `json:"min_scene"`
+	Cuts      []ffmpeg.SceneCut                `json:"cuts"`
+	Scenes    []ffmpeg.TimeRange               `json:"scenes"`
+	Timeline  *validator.TimelineExportRequest `json:"timeline"`
+}
+
+// GetFileScenes returns detected shot changes with their scores, the scenes
+// between them, and a timeline with one clip per scene. Results are cached per
+// file and parameter set.
+func (h *Handler) GetFileScenes(c *fiber.Ctx) error {
+	fileID := c.Params("id")
+	uf := h.storage.Get(fileID)
+	if uf == nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "File not found"})
+	}
+	if uf.MediaInfo == nil || !uf.MediaInfo.HasVideo {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "File has no video stream"})
+	}
+	if err := h.requireFeatures("scene_detection"); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	threshold := c.QueryFloat("threshold", ffmpeg.DefaultSceneThreshold)
+	minScene := c.QueryFloat("min_scene", 1.0)
+	if err := validator.ValidateSceneParams(&threshold, &minScene); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if cached, ok := h.analyses.sceneResult(fileID, threshold, minScene); ok {
+		return c.Status(http.StatusOK).JSON(fiber.Map{"file_id": fileID, "cached": true, "result": cached})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	res, err := h.detectScenes(ctx, uf, threshold, minScene, nil)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	h.analyses.storeSceneResult(fileID, threshold, minScene, res)
+
+	return c.Status(http.StatusOK).JSON(fiber.Map{"file_id": fileID, "cached": false, "result": res})
+}
+
+func (h *Handler) detectScenes(ctx context.Context, uf *storage.UploadedFile, threshold, minScene float64, ph ffmpeg.ProgressHandler) (*sceneResult, error) {
+	duration := 0.0
+	if uf.MediaInfo != nil && uf.MediaInfo.Duration != nil {
+		duration = *uf.MediaInfo.Duration
+	}
+	cuts, err := ffmpeg.DetectScenes(ctx, h.cfg.FFmpegPath, uf.StoragePath, threshold, duration, ph)
+	if err != nil {
+		return nil, err
+	}
+	scenes := ffmpeg.ScenesFromCuts(cuts, duration, minScene)
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(uf.StoragePath)), ".")
+	if !validator.AllowedOutputFormats[format] {
+		format = "mp4"
+	}
+	timeline := &validator.TimelineExportRequest{OutputFormat: format, Mode: "precise"}
+	for _, s := range scenes {
+		timeline.Clips = append(timeline.Clips, validator.TimelineClip{
+			FileID:      uf.ID,
+			SourceStart: s.Start,
+			Duration:    s.Duration(),
+		})
+	}
+	return &sceneResult{
+		Threshold: threshold,
+		MinScene:  minScene,
+		Cuts:      cuts,
+		Scenes:    scenes,
+		Timeline:  timeline,
+	}, nil
+}
+
+// SplitScenes starts a job that detects scenes and exports each one as its own
+// file, zipped into a single download.
+func (h *Handler) SplitScenes(c *fiber.Ctx) error {
+	var req validator.SceneSplitRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := req.Validate(); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := h.requireFeatures("scene_detection"); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	uf := h.storage.Get(req.FileID)
+	if uf == nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "File not found"})
+	}
+	if uf.MediaInfo == nil || uf.MediaInfo.Duration == nil || !uf.MediaInfo.HasVideo {
+		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "File has no video stream"})
+	}
+
+	job := h.jobManager.CreateJob(uf.ID, uf.OriginalName, "zip")
+	h.jobManager.SetWorkload(job.ID, workload("scene_split", req.OutputFormat, uf.MediaInfo,
+		encoderLabel(req.OutputFormat, nil, h.cfg.ResolvedHWEncoder), *uf.MediaInfo.Duration))
+	reqCopy := req
+	if err := h.jobManager.Enqueue(job, func() {
+		h.performSceneSplit(job, uf, &reqCopy)
+	}); err != nil {
+		return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"job_id": job.ID,
+		"status": job.Status,
+	})
+}
+
+func (h *Handler) performSceneSplit(job *jobs.Job, uf *storage.UploadedFile, req *validator.SceneSplitRequest) {
+	start := time.Now()
+	sampler := metrics.NewSampler()
+	h.jobManager.AddLog(job.ID, "Scene split started")
+
+	outputName := fmt.Sprintf("%s_scenes.zip", job.ID[:8])
+	outputPath := filepath.Join(h.cfg.OutputDir, outputName)
+	h.jobManager.SetOutputPath(job.ID, outputPath)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+	defer cancel()
+	h.jobManager.SetCancelFunc(job.ID, cancel)
+
+	threshold := ffmpeg.DefaultSceneThreshold
+	if req.Threshold != nil {
+		threshold = *req.Threshold
+	}
+	minScene := 1.0
+	if req.MinScene != nil {
+		minScene = *req.MinScene
+	}
+	strategy := "reencode"
+	if req.Mode == "fast" {
+		strategy = "stream_copy"
+	}
+	h.jobManager.SetStrategy(job.ID, strategy)
+
+	// Detection is a fast decode-only pass: 20% of progress, export the rest.
+	h.jobManager.SetStage(job.ID, "detecting scenes")
+	progress := h.trackProgress(job)
+	res, splitErr := h.detectScenes(ctx, uf, threshold, minScene, progress.scaled(0, 0.2))
+	if splitErr == nil {
+		h.analyses.storeSceneResult(uf.ID, threshold, minScene, res)
+		h.jobManager.AddLog(job.ID, fmt.Sprintf("Detected %d cuts, %d scenes (threshold %.2f)", len(res.Cuts), len(res.Scenes), threshold))
+		splitErr = h.exportScenes(ctx, job, progress, uf, res.Scenes, req, outputPath)
+	}
+	elapsed := time.Since(start).Seconds()
+	avgCPU, peakRAM := sampler.Stop()
+
+	outMB := 0.0
+	if splitErr == nil {
+		outMB = fileSizeMB(outputPath)
+	}
+	speedRatio := 0.0
+	if elapsed > 0 && uf.MediaInfo.Duration != nil {
+		speedRatio = *uf.MediaInfo.Duration / elapsed
+	}
+	snap := metrics.Current()
+	h.opStore.Record(metrics.OperationRecord{
+		OperationID:       job.ID,
+		Operation:         "scene_split",
+		OriginalName:      uf.OriginalName,
+		OutputFilename:    outputName,
+		ProcessingTimeSec: elapsed,
+		InputSizeMB:       fileSizeMB(uf.StoragePath),
+		OutputSizeMB:      outMB,
+		SpeedRatio:        speedRatio,
+		FFmpegSpeed:       progress.speed,
+		FFmpegFPS:         progress.fps,
+		AvgCPUPercent:     avgCPU,
+		PeakRAMMB:         peakRAM,
+		OutputFormat:      req.OutputFormat,
+		Strategy:          strategy,
+		Success:           splitErr == nil,
+		Error:             errStr(splitErr),
+		ErrorCode:         errCode(splitErr),
+		GPUUsed:           snap.GPU != nil,
+		Resolution:        job.Workload.Resolution,
+		Encoder:           job.Workload.Encoder,
+		MediaSecs:         job.Workload.MediaSecs,
+	})
+
+	if splitErr != nil {
+		h.failJob(job, splitErr)
+		return
+	}
+	h.jobManager.AddLog(job.ID, fmt.Sprintf("Completed in %.1fs", elapsed))
+	h.jobManager.SetCompleted(job.ID, outputName)
+}
+
+// exportScenes renders each scene through the timeline exporter into a temp
+// dir, then zips the results to outputPath.
+func (h *Handler) exportScenes(ctx context.Context, job *jobs.Job, progress *jobProgress, uf *storage.UploadedFile, scenes []ffmpeg.TimeRange, req *validator.SceneSplitRequest, outputPath string) error {
+	tmpDir, err := os.MkdirTemp("", "ffm_scenes_*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	mode := req.Mode
+	if mode == "" {
+		mode = "precise"
+	}
+	format := strings.ToLower(req.OutputFormat)
+	hwEncoder := h.cfg.ResolvedHWEncoder // cleared for the remaining scenes if it fails
+	paths := make([]string, 0, len(scenes))
+	for i, scene := range scenes {
+		h.jobManager.SetStage(job.ID, fmt.Sprintf("exporting scene %d/%d", i+1, len(scenes)))
+		path := filepath.Join(tmpDir, fmt.Sprintf("scene_%03d.%s", i+1, format))
+		opts := ffmpeg.TimelineExportOptions{
+			Clips: []ffmpeg.TimelineExportClip{{
+				FileID:      uf.ID,
+				FilePath:    uf.StoragePath,
+				SourceStart: scene.Start,
+				Duration:    scene.Duration(),
+				HasVideo:    uf.MediaInfo.HasVideo,
+				HasAudio:    uf.MediaInfo.HasAudio,
+			}},
+			OutputPath:  path,
+			FFmpegPath:  h.cfg.FFmpegPath,
+			FFprobePath: h.cfg.FFprobePath,
+			PresetMode:  h.cfg.PresetMode,
+			Mode:        mode,
+			HWEncoder:   hwEncoder,
+		}
+		if isAudioOnlyOutputFormat(format) {
+			opts.RemoveVideo = true
+			opts.HWEncoder = ""
+		}
+		base := 0.2 + 0.75*float64(i)/float64(len(scenes))
+		span := 0.75 / float64(len(scenes))
+		err := ffmpeg.TimelineExport(ctx, opts, progress.scaled(base, span), nil)
+		if _, ok := h.hwFallback(job, opts.HWEncoder, err); ok {
+			hwEncoder, opts.HWEncoder = "", ""
+			err = ffmpeg.TimelineExport(ctx, opts, progress.scaled(base, span), nil)
+		}
+		if err != nil {
+			return fmt.Errorf("scene %d: %w", i+1, err)
+		}
+		paths = append(paths, path)
+	}
+
+	h.jobManager.SetStage(job.ID, "archiving")
+	return writeZip(outputPath, paths)
+}
+
+// writeZip stores files (already compressed media) uncompressed in a zip.
+func writeZip(zipPath string, files []string) error {
+	out, err := os.Create(zipPath)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+	for _, path := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: filepath.Base(path), Method: zip.Store})
+		if err != nil {
+			return fmt.Errorf("failed to add %s to archive: %w", filepath.Base(path), err)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to add %s to archive: %w", filepath.Base(path), err)
+		}
+	}
+	return zw.Close()
+}
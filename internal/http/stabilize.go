@@ -0,0 +1,24 @@
+package http
+
+import (
+	"ffmeditor/internal/ffmpeg"
+	"ffmeditor/internal/validator"
+)
+
+// stabilization resolves a request's stabilization settings, or returns nil
+// when there are none. Without libvidstab it falls back to deshake, and it
+// fails when ffmpeg has neither.
+func (h *Handler) stabilization(p *validator.StabilizeParams) (*ffmpeg.Stabilization, error) {
+	if p == nil {
+		return nil, nil
+	}
+	deshake := false
+	if err := h.cfg.Capabilities.RequireFeature("stabilization"); err != nil {
+		if h.cfg.Capabilities.RequireFeature("deshake") != nil {
+			return nil, err
+		}
+		deshake = true
+	}
+	s := ffmpeg.ResolveStabilization(p.Shakiness, p.Smoothing, p.Zoom, deshake)
+	return &s, nil
+}
@@ -0,0 +1,66 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"ffmeditor/internal/ffmpeg"
+	"ffmeditor/internal/storage"
+	"ffmeditor/internal/validator"
+)
+
+// GetFileStreams lists the streams of an uploaded file, with the indexes,
+// languages and dispositions a convert's stream selection refers to.
+func (h *Handler) GetFileStreams(c *fiber.Ctx) error {
+	uf := h.storage.Get(c.Params("id"))
+	if uf == nil {
+		return c.Status(http.StatusNotFound).JSON(fiber.Map{"error": "File not found"})
+	}
+	streams := []storage.StreamInfo{}
+	if uf.MediaInfo != nil && uf.MediaInfo.Streams != nil {
+		streams = uf.MediaInfo.Streams
+	}
+	return c.Status(http.StatusOK).JSON(fiber.Map{
+		"file_id": uf.ID,
+		"streams": streams,
+	})
+}
+
+// storageStreams and ffmpegStreams convert probed streams to and from the
+// copies kept with an upload.
+func storageStreams(streams []ffmpeg.StreamInfo) []storage.StreamInfo {
+	out := make([]storage.StreamInfo, len(streams))
+	for i, st := range streams {
+		out[i] = storage.StreamInfo(st)
+	}
+	return out
+}
+
+func ffmpegStreams(streams []storage.StreamInfo) []ffmpeg.StreamInfo {
+	out := make([]ffmpeg.StreamInfo, len(streams))
+	for i, st := range streams {
+		out[i] = ffmpeg.StreamInfo(st)
+	}
+	return out
+}
+
+// streamSelection converts a request's stream parameters for ffmpeg.
+func streamSelection(p *validator.StreamParams) *ffmpeg.StreamSelection {
+	if p == nil {
+		return nil
+	}
+	sel := &ffmpeg.StreamSelection{AllAudio: p.AllAudio}
+	for _, s := range p.Include {
+		sel.Include = append(sel.Include, ffmpeg.StreamSelector{Index: s.Index, Type: s.Type, Language: s.Language})
+	}
+	for _, s := range p.Exclude {
+		sel.Exclude = append(sel.Exclude, ffmpeg.StreamSelector{Index: s.Index, Type: s.Type, Language: s.Language})
+	}
+	for _, m := range p.Metadata {
+		sel.Metadata = append(sel.Metadata, ffmpeg.StreamMetadata{
+			Index: m.Index, Language: m.Language, Title: m.Title, Default: m.Default, Forced: m.Forced,
+		})
+	}
+	return sel
+}
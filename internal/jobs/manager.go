@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"os"
+	"sort"
 	"sync"
 	"time"
 
@@ -22,31 +23,76 @@ const (
 )
 
 type Job struct {
-	ID             string     `json:"id"`
-	FileID         string     `json:"file_id"`
-	OriginalName   string     `json:"original_name"`
-	OutputFormat   string     `json:"output_format"`
-	Status         JobStatus  `json:"status"`
-	Stage          string     `json:"stage,omitempty"`
-	Progress       float64    `json:"progress"`
-	OutTimeMs      float64    `json:"out_time_ms"`
-	Strategy       string     `json:"strategy,omitempty"` // "stream_copy" | "reencode"
-	ElapsedSecs    float64    `json:"elapsed_secs,omitempty"`
-	OutputFilename string     `json:"output_filename"`
-	OutputPath     string     `json:"-"`
-	Error          string     `json:"error,omitempty"`
-	CreatedAt      time.Time  `json:"created_at"`
-	StartedAt      *time.Time `json:"started_at,omitempty"`
-	CompletedAt    *time.Time `json:"completed_at,omitempty"`
-	Logs           []string   `json:"logs"`
-	LogRingBuffer  []string   `json:"-"`
-	MaxLogLines    int        `json:"-"`
+	ID             string      `json:"id"`
+	FileID         string      `json:"file_id"`
+	OriginalName   string      `json:"original_name"`
+	OutputFormat   string      `json:"output_format"`
+	Status         JobStatus   `json:"status"`
+	Stage          string      `json:"stage,omitempty"`
+	Progress       float64     `json:"progress"`
+	OutTimeMs      float64     `json:"out_time_ms"`
+	Stats          *Stats      `json:"stats,omitempty"`    // live encoder stats of the running ffmpeg pass
+	ETASecs        *float64    `json:"eta_secs,omitempty"` // time until completion; queue wait included for pending jobs
+	QueueETASecs   *float64    `json:"queue_eta_secs,omitempty"`
+	Strategy       string      `json:"strategy,omitempty"` // "stream_copy" | "reencode"
+	ElapsedSecs    float64     `json:"elapsed_secs,omitempty"`
+	OutputFilename string      `json:"output_filename"`
+	OutputPath     string      `json:"-"`
+	Error          string      `json:"error,omitempty"`
+	ErrorCode      string      `json:"error_code,omitempty"` // stable failure class, e.g. "hw_encoder_init"
+	ErrorHint      string      `json:"error_hint,omitempty"`
+	QC             interface{} `json:"qc,omitempty"` // QC report, set by QC jobs and exports with QC checks
+	CreatedAt      time.Time   `json:"created_at"`
+	StartedAt      *time.Time  `json:"started_at,omitempty"`
+	CompletedAt    *time.Time  `json:"completed_at,omitempty"`
+	Logs           []string    `json:"logs"`
+	LogRingBuffer  []string    `json:"-"`
+	MaxLogLines    int         `json:"-"`
+
+	// Workload describes the job's input for run-time estimation.
+	Workload Workload `json:"-"`
+	// runSecs is the Estimator's run time for Workload, worked out when the
+	// workload or strategy is set; runKnown is false without one.
+	runSecs  float64
+	runKnown bool
 
 	// cancelFn cancels the job's context; set by the worker goroutine.
 	cancelFn context.CancelFunc
 	startWall time.Time
 }
 
+// Workload is what a job will process. The Estimator uses it to predict run
+// time from past operations.
+type Workload struct {
+	Operation    string  // "convert", "timeline_export", ...
+	OutputFormat string  // container of the output files
+	Resolution   string  // source resolution, e.g. "1920x1080"
+	Encoder      string  // video encoder, or "audio" for audio-only outputs
+	MediaSecs    float64 // duration of media to process
+}
+
+// Estimator predicts how long a job will run once started, in seconds. ok is
+// false when there is nothing to base an estimate on.
+type Estimator func(job *Job) (secs float64, ok bool)
+
+// etaSmoothing is the weight of the newest sample in the running ETA.
+const etaSmoothing = 0.3
+
+// etaMinProgress is the progress below which the progress-rate ETA is too
+// noisy and ffmpeg's speed is used instead.
+const etaMinProgress = 0.05
+
+// Stats are the live encoder counters from ffmpeg's progress stream.
+type Stats struct {
+	Frame       int64   `json:"frame"`
+	FPS         float64 `json:"fps"`
+	BitrateKbps float64 `json:"bitrate_kbps"`
+	TotalSize   int64   `json:"total_size"`
+	Speed       float64 `json:"speed"`
+	DupFrames   int64   `json:"dup_frames"`
+	DropFrames  int64   `json:"drop_frames"`
+}
+
 type jobTask struct {
 	job *Job
 	run func()
@@ -62,6 +108,7 @@ type Manager struct {
 	wg          sync.WaitGroup
 	handlers    map[JobStatus][]JobHandler
 	persistPath string // path to jobs.json for completed job persistence
+	estimator   Estimator
 }
 
 type JobHandler func(*Job)
@@ -86,6 +133,14 @@ func (m *Manager) WithPersistence(path string) *Manager {
 	return m
 }
 
+// SetEstimator sets the run-time estimator used for queued jobs. It applies
+// to the workloads set after it.
+func (m *Manager) SetEstimator(e Estimator) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.estimator = e
+}
+
 // loadCompleted restores completed jobs from disk on startup.
 func (m *Manager) loadCompleted() {
 	if m.persistPath == "" {
@@ -209,7 +264,11 @@ func (m *Manager) GetJob(id string) *Job {
 	if !ok {
 		return nil
 	}
-	return cloneJob(job)
+	clone := cloneJob(job)
+	if clone.Status == StatusPending {
+		m.fillQueueETAs(map[string]*Job{id: clone})
+	}
+	return clone
 }
 
 func (m *Manager) SetProgress(jobID string, progress, outTimeMs float64) {
@@ -218,9 +277,152 @@ func (m *Manager) SetProgress(jobID string, progress, outTimeMs float64) {
 	if job, exists := m.jobs[jobID]; exists {
 		job.Progress = progress
 		job.OutTimeMs = outTimeMs
+		job.updateETA()
 	}
 }
 
+// updateETA folds a new remaining-time sample into the running ETA. Early on
+// it is the remaining media divided by ffmpeg's speed; after that it is the
+// elapsed time scaled by the remaining share of progress, which also covers
+// multi-pass jobs.
+func (job *Job) updateETA() {
+	if job.Status != StatusProcessing || job.startWall.IsZero() {
+		return
+	}
+	p := job.Progress
+	var sample float64
+	switch {
+	case p >= etaMinProgress && p < 1:
+		sample = time.Since(job.startWall).Seconds() * (1 - p) / p
+	case p < etaMinProgress && job.Stats != nil && job.Stats.Speed > 0 && job.Workload.MediaSecs > 0:
+		sample = job.Workload.MediaSecs * (1 - p) / job.Stats.Speed
+	case p >= 1:
+		sample = 0
+	default:
+		return
+	}
+	if job.ETASecs != nil {
+		sample = etaSmoothing*sample + (1-etaSmoothing)*(*job.ETASecs)
+	}
+	job.ETASecs = &sample
+}
+
+// fillQueueETAs estimates when the pending jobs among clones will start and
+// finish by placing the running jobs, then all pending jobs in the order they
+// were created, on the worker slots. It uses the run times estimated when the
+// workloads were set, so it does not call the Estimator. Must be called with
+// m.mu held.
+func (m *Manager) fillQueueETAs(clones map[string]*Job) {
+	slots := make([]float64, m.workers)
+	n := 0
+	var pending []*Job
+	for _, job := range m.jobs {
+		switch job.Status {
+		case StatusProcessing:
+			remaining, ok := remainingSecs(job)
+			if !ok {
+				return
+			}
+			if n < len(slots) {
+				slots[n] = remaining
+				n++
+			}
+		case StatusPending:
+			pending = append(pending, job)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		if !pending[i].CreatedAt.Equal(pending[j].CreatedAt) {
+			return pending[i].CreatedAt.Before(pending[j].CreatedAt)
+		}
+		return pending[i].ID < pending[j].ID
+	})
+
+	earliest := func() int {
+		best := 0
+		for i := range slots {
+			if slots[i] < slots[best] {
+				best = i
+			}
+		}
+		return best
+	}
+	for _, job := range pending {
+		// Nothing behind a job without an estimate can be placed either.
+		if !job.runKnown {
+			return
+		}
+		slot := earliest()
+		if clone := clones[job.ID]; clone != nil {
+			wait := slots[slot]
+			total := wait + job.runSecs
+			clone.QueueETASecs = &wait
+			clone.ETASecs = &total
+		}
+		slots[slot] += job.runSecs
+	}
+}
+
+// remainingSecs is a running job's ETA, or its estimated run time minus the
+// time it has run so far when ffmpeg has not reported progress yet.
+func remainingSecs(job *Job) (float64, bool) {
+	if job.ETASecs != nil {
+		return *job.ETASecs, true
+	}
+	if !job.runKnown {
+		return 0, false
+	}
+	secs := job.runSecs
+	if !job.startWall.IsZero() {
+		secs -= time.Since(job.startWall).Seconds()
+	}
+	if secs < 0 {
+		secs = 0
+	}
+	return secs, true
+}
+
+// estimateRun refreshes the job's estimated run time. The Estimator reads
+// the operation history, so it runs on a copy of the job without m.mu held.
+func (m *Manager) estimateRun(jobID string) {
+	m.mu.RLock()
+	job, exists := m.jobs[jobID]
+	estimator := m.estimator
+	var probe *Job
+	if exists {
+		probe = cloneJob(job)
+	}
+	m.mu.RUnlock()
+	if !exists || estimator == nil {
+		return
+	}
+	secs, ok := estimator(probe)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if job, exists := m.jobs[jobID]; exists {
+		job.runSecs, job.runKnown = secs, ok
+	}
+}
+
+// SetStats replaces the job's live encoder stats.
+func (m *Manager) SetStats(jobID string, stats Stats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if job, exists := m.jobs[jobID]; exists {
+		job.Stats = &stats
+	}
+}
+
+// SetWorkload records what the job will process and estimates its run time.
+func (m *Manager) SetWorkload(jobID string, w Workload) {
+	m.mu.Lock()
+	if job, exists := m.jobs[jobID]; exists {
+		job.Workload = w
+	}
+	m.mu.Unlock()
+	m.estimateRun(jobID)
+}
+
 func (m *Manager) SetStage(jobID, stage string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -229,12 +431,15 @@ func (m *Manager) SetStage(jobID, stage string) {
 	}
 }
 
+// SetStrategy records how the job encodes; the run-time estimate depends on
+// it.
 func (m *Manager) SetStrategy(jobID, strategy string) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
 	if job, exists := m.jobs[jobID]; exists {
 		job.Strategy = strategy
 	}
+	m.mu.Unlock()
+	m.estimateRun(jobID)
 }
 
 // SetCancelFunc stores the context cancel function so Cancel() can abort the job.
@@ -260,6 +465,7 @@ func (m *Manager) Cancel(jobID string) bool {
 	}
 	fn := job.cancelFn
 	job.Status = StatusCanceled
+	job.ETASecs = nil
 	now := time.Now()
 	job.CompletedAt = &now
 	m.mu.Unlock()
@@ -276,6 +482,7 @@ func (m *Manager) SetError(jobID, errMsg string) {
 	if exists {
 		job.Error = errMsg
 		job.Status = StatusFailed
+		job.ETASecs = nil
 		now := time.Now()
 		job.CompletedAt = &now
 		if !job.startWall.IsZero() {
@@ -289,6 +496,17 @@ func (m *Manager) SetError(jobID, errMsg string) {
 	}
 }
 
+// SetErrorCode records the class of a failure and a hint for the user. Call it
+// before SetError so status handlers see it.
+func (m *Manager) SetErrorCode(jobID, code, hint string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if job, exists := m.jobs[jobID]; exists {
+		job.ErrorCode = code
+		job.ErrorHint = hint
+	}
+}
+
 func (m *Manager) SetCompleted(jobID, outputFilename string) {
 	m.mu.Lock()
 	job, exists := m.jobs[jobID]
@@ -296,6 +514,7 @@ func (m *Manager) SetCompleted(jobID, outputFilename string) {
 		job.Status = StatusCompleted
 		job.OutputFilename = outputFilename
 		job.Progress = 1.0
+		job.ETASecs = nil
 		job.Stage = "done"
 		now := time.Now()
 		job.CompletedAt = &now
@@ -331,6 +550,7 @@ func (m *Manager) AllJobs() map[string]*Job {
 	for k, v := range m.jobs {
 		result[k] = cloneJob(v)
 	}
+	m.fillQueueETAs(result)
 	return result
 }
 
@@ -376,6 +596,15 @@ func (m *Manager) scheduleCleanup(jobID, outputPath string, delay time.Duration)
 	})
 }
 
+// SetQCReport attaches a QC report to the job.
+func (m *Manager) SetQCReport(jobID string, report interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if job, exists := m.jobs[jobID]; exists {
+		job.QC = report
+	}
+}
+
 func (m *Manager) SetOutputPath(jobID, outputPath string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -393,6 +622,14 @@ func cloneJob(job *Job) *Job {
 	if job.Logs != nil {
 		clone.Logs = append([]string(nil), job.Logs...)
 	}
+	if job.Stats != nil {
+		stats := *job.Stats
+		clone.Stats = &stats
+	}
+	if job.ETASecs != nil {
+		eta := *job.ETASecs
+		clone.ETASecs = &eta
+	}
 	if job.LogRingBuffer != nil {
 		clone.LogRingBuffer = append([]string(nil), job.LogRingBuffer...)
 	}
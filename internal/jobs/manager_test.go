@@ -0,0 +1,132 @@
+package jobs
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+// addJob adds a job with the given status, creation offset and workload
+// duration, which the test estimator reads as its run time.
+func addJob(m *Manager, id string, status JobStatus, created time.Duration, runSecs float64) *Job {
+	job := &Job{
+		ID:        id,
+		Status:    status,
+		CreatedAt: time.Unix(0, 0).Add(created),
+	}
+	m.jobs[id] = job
+	m.SetWorkload(id, Workload{MediaSecs: runSecs})
+	return job
+}
+
+func workloadEstimator(job *Job) (float64, bool) {
+	if job.Workload.MediaSecs <= 0 {
+		return 0, false
+	}
+	return job.Workload.MediaSecs, true
+}
+
+func TestUpdateETA(t *testing.T) {
+	job := &Job{Status: StatusProcessing, startWall: time.Now().Add(-10 * time.Second), Progress: 0.5}
+	job.updateETA()
+	if job.ETASecs == nil || math.Abs(*job.ETASecs-10) > 0.5 {
+		t.Fatalf("ETA %v, want the 10s already spent again", value(job.ETASecs))
+	}
+
+	// Each sample only moves the running ETA part of the way.
+	prev := 20.0
+	job.ETASecs = &prev
+	job.updateETA()
+	if math.Abs(*job.ETASecs-17) > 0.5 {
+		t.Errorf("smoothed ETA %v, want 17", *job.ETASecs)
+	}
+
+	// Too early for the progress rate: the remaining media at ffmpeg's speed.
+	job = &Job{Status: StatusProcessing, startWall: time.Now(), Progress: 0.01,
+		Stats: &Stats{Speed: 2}, Workload: Workload{MediaSecs: 100}}
+	job.updateETA()
+	if job.ETASecs == nil || math.Abs(*job.ETASecs-49.5) > 0.01 {
+		t.Errorf("early ETA %v, want 49.5", value(job.ETASecs))
+	}
+
+	job = &Job{Status: StatusPending, Progress: 0.5}
+	job.updateETA()
+	if job.ETASecs != nil {
+		t.Error("ETA for a job that has not started")
+	}
+}
+
+func TestFillQueueETA(t *testing.T) {
+	m := NewManager(2)
+	m.SetEstimator(workloadEstimator)
+	eta := 10.0
+	addJob(m, "running-a", StatusProcessing, 0, 0).ETASecs = &eta
+	// No progress yet: its estimated run time stands in for the ETA.
+	addJob(m, "running-b", StatusProcessing, time.Second, 30)
+	addJob(m, "pending-1", StatusPending, 2*time.Second, 5)
+	addJob(m, "pending-2", StatusPending, 3*time.Second, 20)
+	addJob(m, "pending-3", StatusPending, 4*time.Second, 7)
+	addJob(m, "done", StatusCompleted, 0, 1000)
+
+	// Slots start at 10 and 30; pending-1 and pending-2 both take the first,
+	// ending at 35, so pending-3 starts when the second frees up at 30.
+	job := m.GetJob("pending-3")
+	if job.QueueETASecs == nil || *job.QueueETASecs != 30 {
+		t.Fatalf("queue ETA %v, want 30", value(job.QueueETASecs))
+	}
+	if job.ETASecs == nil || *job.ETASecs != 37 {
+		t.Errorf("ETA %v, want 37", value(job.ETASecs))
+	}
+
+	job = m.GetJob("pending-1")
+	if job.QueueETASecs == nil || *job.QueueETASecs != 10 {
+		t.Errorf("first pending job's queue ETA %v, want 10", value(job.QueueETASecs))
+	}
+	if job := m.GetJob("running-b"); job.QueueETASecs != nil {
+		t.Error("running job got a queue ETA")
+	}
+}
+
+func TestFillQueueETAWithoutEstimate(t *testing.T) {
+	m := NewManager(1)
+	addJob(m, "pending-1", StatusPending, 0, 5)
+	if job := m.GetJob("pending-1"); job.QueueETASecs != nil || job.ETASecs != nil {
+		t.Error("ETA without an estimator")
+	}
+
+	m = NewManager(1)
+	m.SetEstimator(workloadEstimator)
+	addJob(m, "unknown", StatusPending, 0, 0)
+	addJob(m, "pending-2", StatusPending, time.Second, 5)
+	if job := m.GetJob("pending-2"); job.QueueETASecs != nil {
+		t.Error("ETA behind a job that cannot be estimated")
+	}
+}
+
+func TestAllJobsEstimatesOnce(t *testing.T) {
+	m := NewManager(1)
+	calls := 0
+	m.SetEstimator(func(job *Job) (float64, bool) {
+		calls++
+		return workloadEstimator(job)
+	})
+	for i := 0; i < 5; i++ {
+		addJob(m, fmt.Sprintf("pending-%d", i), StatusPending, time.Duration(i)*time.Second, 10)
+	}
+	calls = 0
+	all := m.AllJobs()
+	if calls != 0 {
+		t.Errorf("AllJobs called the estimator %d times", calls)
+	}
+	if eta := all["pending-4"].QueueETASecs; eta == nil || *eta != 40 {
+		t.Errorf("last job's queue ETA %v, want 40", value(eta))
+	}
+}
+
+func value(p *float64) interface{} {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
@@ -2,8 +2,10 @@ package metrics
 
 import (
 	"encoding/json"
+	"fmt"
 	"math"
 	"os"
+	"sort"
 	"sync"
 	"time"
 )
@@ -26,8 +28,42 @@ type OperationRecord struct {
 	Strategy          string    `json:"strategy"`
 	Success           bool      `json:"success"`
 	Error             string    `json:"error,omitempty"`
+	ErrorCode         string    `json:"error_code,omitempty"`
 	GPUUsed           bool      `json:"gpu_used"`
+	Resolution        string    `json:"resolution,omitempty"` // source resolution, e.g. "1920x1080"
+	Encoder           string    `json:"encoder,omitempty"`
+	MediaSecs         float64   `json:"media_secs,omitempty"`
 	RecordedAt        time.Time `json:"recorded_at"`
+	// Loudness is set when the operation ran two-pass loudness normalization.
+	Loudness *LoudnessRecord `json:"loudness,omitempty"`
+	// Quality is set when the output was scored against its source.
+	Quality *QualityRecord `json:"quality,omitempty"`
+}
+
+// QualityRecord holds objective quality scores of an output against its
+// source, with the encoder settings that produced it.
+type QualityRecord struct {
+	VideoCodec string   `json:"video_codec"`
+	Preset     string   `json:"preset"`
+	CRF        *int     `json:"crf,omitempty"`
+	PSNR       *float64 `json:"psnr,omitempty"`
+	SSIM       *float64 `json:"ssim,omitempty"`
+	VMAF       *float64 `json:"vmaf,omitempty"`
+}
+
+// LoudnessRecord holds the target, first-pass measurement and verified output
+// loudness of a normalized operation. Measured values are nil when ffmpeg
+// reported them as non-finite (e.g. silent input).
+type LoudnessRecord struct {
+	TargetI   float64  `json:"target_i"`
+	TargetTP  float64  `json:"target_tp"`
+	TargetLRA float64  `json:"target_lra"`
+	InputI    *float64 `json:"input_i"`
+	InputTP   *float64 `json:"input_tp"`
+	InputLRA  *float64 `json:"input_lra"`
+	OutputI   *float64 `json:"output_i,omitempty"`
+	OutputTP  *float64 `json:"output_tp,omitempty"`
+	OutputLRA *float64 `json:"output_lra,omitempty"`
 }
 
 // OperationsSummary aggregates all recorded operations.
@@ -44,6 +80,45 @@ type OperationsSummary struct {
 	PeakRAMMB            float64 `json:"peak_ram_mb"`
 	AvgCompressionPct    float64 `json:"avg_compression_pct"`
 	GPUAvailable         bool    `json:"gpu_available"`
+	// Quality averages over operations that were scored; nil when none were.
+	AvgPSNR          *float64       `json:"avg_psnr,omitempty"`
+	AvgSSIM          *float64       `json:"avg_ssim,omitempty"`
+	AvgVMAF          *float64       `json:"avg_vmaf,omitempty"`
+	QualityByEncoder []QualityGroup `json:"quality_by_encoder"`
+}
+
+// QualityGroup aggregates scored operations sharing codec, preset and CRF.
+type QualityGroup struct {
+	VideoCodec        string   `json:"video_codec"`
+	Preset            string   `json:"preset"`
+	CRF               *int     `json:"crf,omitempty"`
+	Operations        int      `json:"operations"`
+	AvgPSNR           *float64 `json:"avg_psnr,omitempty"`
+	AvgSSIM           *float64 `json:"avg_ssim,omitempty"`
+	AvgVMAF           *float64 `json:"avg_vmaf,omitempty"`
+	AvgCompressionPct float64  `json:"avg_compression_pct"`
+	AvgSpeedRatio     float64  `json:"avg_speed_ratio"`
+}
+
+// mean accumulates an optional average.
+type mean struct {
+	sum float64
+	n   int
+}
+
+func (m *mean) add(v *float64) {
+	if v != nil {
+		m.sum += *v
+		m.n++
+	}
+}
+
+func (m mean) value() *float64 {
+	if m.n == 0 {
+		return nil
+	}
+	v := m.sum / float64(m.n)
+	return &v
 }
 
 // OperationStore persists operation records to a JSON file.
@@ -85,9 +160,11 @@ func (s *OperationStore) Summary() OperationsSummary {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	sum := OperationsSummary{FastestOperationSec: math.MaxFloat64}
+	sum := OperationsSummary{FastestOperationSec: math.MaxFloat64, QualityByEncoder: []QualityGroup{}}
 	var totalTime, totalSpeed, totalCPU, totalCompression float64
 	var speedN, cpuN, compressionN int
+	var psnr, ssim, vmaf mean
+	groups := map[string]*qualityAcc{}
 
 	for _, r := range s.records {
 		sum.TotalOperations++
@@ -123,7 +200,33 @@ func (s *OperationStore) Summary() OperationsSummary {
 		if r.GPUUsed {
 			sum.GPUAvailable = true
 		}
+		if q := r.Quality; q != nil && r.Success {
+			psnr.add(q.PSNR)
+			ssim.add(q.SSIM)
+			vmaf.add(q.VMAF)
+			key := fmt.Sprintf("%s|%s|%v", q.VideoCodec, q.Preset, intValue(q.CRF))
+			g := groups[key]
+			if g == nil {
+				g = &qualityAcc{group: QualityGroup{VideoCodec: q.VideoCodec, Preset: q.Preset, CRF: q.CRF}}
+				groups[key] = g
+			}
+			g.add(r)
+		}
+	}
+	sum.AvgPSNR, sum.AvgSSIM, sum.AvgVMAF = psnr.value(), ssim.value(), vmaf.value()
+	for _, g := range groups {
+		sum.QualityByEncoder = append(sum.QualityByEncoder, g.result())
 	}
+	sort.Slice(sum.QualityByEncoder, func(i, j int) bool {
+		a, b := sum.QualityByEncoder[i], sum.QualityByEncoder[j]
+		if a.VideoCodec != b.VideoCodec {
+			return a.VideoCodec < b.VideoCodec
+		}
+		if a.Preset != b.Preset {
+			return a.Preset < b.Preset
+		}
+		return intValue(a.CRF) < intValue(b.CRF)
+	})
 
 	if sum.TotalOperations > 0 {
 		sum.AvgProcessingTimeSec = totalTime / float64(sum.TotalOperations)
@@ -144,6 +247,105 @@ func (s *OperationStore) Summary() OperationsSummary {
 	return sum
 }
 
+// qualityAcc accumulates one QualityGroup.
+type qualityAcc struct {
+	group                 QualityGroup
+	psnr, ssim, vmaf      mean
+	compression, speedSum float64
+	compressionN, speedN  int
+}
+
+func (a *qualityAcc) add(r OperationRecord) {
+	a.group.Operations++
+	a.psnr.add(r.Quality.PSNR)
+	a.ssim.add(r.Quality.SSIM)
+	a.vmaf.add(r.Quality.VMAF)
+	if r.InputSizeMB > 0 && r.OutputSizeMB > 0 {
+		a.compression += (1 - r.OutputSizeMB/r.InputSizeMB) * 100
+		a.compressionN++
+	}
+	if r.SpeedRatio > 0 {
+		a.speedSum += r.SpeedRatio
+		a.speedN++
+	}
+}
+
+func (a *qualityAcc) result() QualityGroup {
+	g := a.group
+	g.AvgPSNR, g.AvgSSIM, g.AvgVMAF = a.psnr.value(), a.ssim.value(), a.vmaf.value()
+	if a.compressionN > 0 {
+		g.AvgCompressionPct = a.compression / float64(a.compressionN)
+	}
+	if a.speedN > 0 {
+		g.AvgSpeedRatio = a.speedSum / float64(a.speedN)
+	}
+	return g
+}
+
+// ThroughputKey selects the records SpeedRatio averages. Empty fields match
+// any record.
+type ThroughputKey struct {
+	Operation    string
+	Strategy     string
+	OutputFormat string
+	Resolution   string
+	Encoder      string
+}
+
+func (k ThroughputKey) matches(r OperationRecord) bool {
+	match := func(want, got string) bool { return want == "" || want == got }
+	return match(k.Operation, r.Operation) &&
+		match(k.Strategy, r.Strategy) &&
+		match(k.OutputFormat, r.OutputFormat) &&
+		match(k.Resolution, r.Resolution) &&
+		match(k.Encoder, r.Encoder)
+}
+
+// throughputSamples is how many of the latest matching records SpeedRatio
+// averages, so estimates follow changes in load and settings.
+const throughputSamples = 20
+
+// SpeedRatio returns the average media-seconds processed per wall-clock second
+// of recent successful operations matching key. When nothing matches, the key
+// is relaxed (encoder, resolution, output format, strategy in that order) down
+// to the operation alone; ok is false when that also has no history.
+func (s *OperationStore) SpeedRatio(key ThroughputKey) (ratio float64, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := []ThroughputKey{key}
+	for _, clear := range []func(*ThroughputKey){
+		func(k *ThroughputKey) { k.Encoder = "" },
+		func(k *ThroughputKey) { k.Resolution = "" },
+		func(k *ThroughputKey) { k.OutputFormat = "" },
+		func(k *ThroughputKey) { k.Strategy = "" },
+	} {
+		clear(&key)
+		keys = append(keys, key)
+	}
+	for _, k := range keys {
+		var m mean
+		for i := len(s.records) - 1; i >= 0 && m.n < throughputSamples; i-- {
+			r := s.records[i]
+			if r.Success && r.SpeedRatio > 0 && k.matches(r) {
+				m.add(&r.SpeedRatio)
+			}
+		}
+		if v := m.value(); v != nil {
+			return *v, true
+		}
+	}
+	return 0, false
+}
+
+// intValue returns *p, or -1 for nil so unset CRFs sort first.
+func intValue(p *int) int {
+	if p == nil {
+		return -1
+	}
+	return *p
+}
+
 func (s *OperationStore) load() {
 	data, err := os.ReadFile(s.filePath)
 	if err != nil {
@@ -0,0 +1,60 @@
+package metrics_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"ffmeditor/internal/metrics"
+)
+
+func newStore(t *testing.T, records ...metrics.OperationRecord) *metrics.OperationStore {
+	t.Helper()
+	s := metrics.NewOperationStore(filepath.Join(t.TempDir(), "operations.json"))
+	for _, r := range records {
+		s.Record(r)
+	}
+	return s
+}
+
+func TestSpeedRatioRelaxesKey(t *testing.T) {
+	s := newStore(t,
+		metrics.OperationRecord{Operation: "convert", Strategy: "reencode", OutputFormat: "mp4", Resolution: "1920x1080", Encoder: "libx264", SpeedRatio: 2, Success: true},
+		metrics.OperationRecord{Operation: "convert", Strategy: "reencode", OutputFormat: "webm", Resolution: "1280x720", Encoder: "libvpx-vp9", SpeedRatio: 4, Success: true},
+		metrics.OperationRecord{Operation: "convert", Strategy: "reencode", OutputFormat: "mp4", Resolution: "1920x1080", Encoder: "libx264", SpeedRatio: 100, Success: false},
+		metrics.OperationRecord{Operation: "timeline_export", Strategy: "stream_copy", OutputFormat: "mp4", SpeedRatio: 50, Success: true},
+	)
+	cases := []struct {
+		name string
+		key  metrics.ThroughputKey
+		want float64
+	}{
+		{"exact", metrics.ThroughputKey{Operation: "convert", Strategy: "reencode", OutputFormat: "mp4", Resolution: "1920x1080", Encoder: "libx264"}, 2},
+		{"encoder relaxed", metrics.ThroughputKey{Operation: "convert", Strategy: "reencode", OutputFormat: "mp4", Resolution: "1920x1080", Encoder: "h264_nvenc"}, 2},
+		{"resolution relaxed", metrics.ThroughputKey{Operation: "convert", Strategy: "reencode", OutputFormat: "webm", Resolution: "3840x2160", Encoder: "libsvtav1"}, 4},
+		{"operation only", metrics.ThroughputKey{Operation: "convert", Strategy: "reencode", OutputFormat: "mkv", Encoder: "libx265"}, 3},
+		{"empty strategy", metrics.ThroughputKey{Operation: "timeline_export", OutputFormat: "mp4"}, 50},
+	}
+	for _, tc := range cases {
+		got, ok := s.SpeedRatio(tc.key)
+		if !ok || got != tc.want {
+			t.Errorf("%s: got %v, %v; want %v", tc.name, got, ok, tc.want)
+		}
+	}
+	if _, ok := s.SpeedRatio(metrics.ThroughputKey{Operation: "merge"}); ok {
+		t.Error("operation without history matched")
+	}
+}
+
+func TestSpeedRatioUsesLatestRecords(t *testing.T) {
+	var records []metrics.OperationRecord
+	for i := 0; i < 10; i++ {
+		records = append(records, metrics.OperationRecord{Operation: "convert", SpeedRatio: 1, Success: true})
+	}
+	for i := 0; i < 20; i++ {
+		records = append(records, metrics.OperationRecord{Operation: "convert", SpeedRatio: 3, Success: true})
+	}
+	s := newStore(t, records...)
+	if got, ok := s.SpeedRatio(metrics.ThroughputKey{Operation: "convert"}); !ok || got != 3 {
+		t.Errorf("got %v, %v; want the last 20 records' 3", got, ok)
+	}
+}
@@ -3,17 +3,54 @@ package storage
 import (
 	"fmt"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 )
 
 type MediaInfo struct {
-	Duration   *float64 // in seconds
-	HasVideo   bool
-	HasAudio   bool
-	VideoCodec string
-	AudioCodec string
-	Resolution string
+	Duration        *float64 // in seconds
+	HasVideo        bool
+	HasAudio        bool
+	VideoCodec      string
+	AudioCodec      string
+	Resolution      string
+	AudioChannels   int
+	AudioSampleRate int
+	HDR             string // see ffmpeg.MediaInfo.HDR
+	Streams         []StreamInfo
+	Tags            map[string]string // see ffmpeg.MediaInfo.Tags
+	Chapters        []Chapter
+}
+
+// Chapter is a named range of a file, in seconds, as ffmpeg.Chapter
+// describes it.
+type Chapter struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Title string  `json:"title,omitempty"`
+}
+
+// StreamInfo is one stream of a file, as ffmpeg.StreamInfo describes it.
+type StreamInfo struct {
+	Index          int    `json:"index"`
+	Type           string `json:"type"`
+	Codec          string `json:"codec"`
+	Language       string `json:"language,omitempty"`
+	Title          string `json:"title,omitempty"`
+	Width          int    `json:"width,omitempty"`
+	Height         int    `json:"height,omitempty"`
+	Channels       int    `json:"channels,omitempty"`
+	ChannelLayout  string `json:"channel_layout,omitempty"`
+	SampleRate     int    `json:"sample_rate,omitempty"`
+	PixelFormat    string `json:"pix_fmt,omitempty"`
+	ColorSpace     string `json:"color_space,omitempty"`
+	ColorTransfer  string `json:"color_transfer,omitempty"`
+	ColorPrimaries string `json:"color_primaries,omitempty"`
+	FieldOrder     string `json:"field_order,omitempty"`
+	Default        bool   `json:"default"`
+	Forced         bool   `json:"forced"`
+	AttachedPic    bool   `json:"attached_pic,omitempty"`
 }
 
 type UploadedFile struct {
@@ -24,15 +61,37 @@ type UploadedFile struct {
 	UploadedAt   time.Time
 }
 
+// Asset is an uploaded file that edits use rather than edit, such as a LUT.
+type Asset struct {
+	ID           string
+	Kind         string // "lut" or "cover"
+	OriginalName string
+	StoragePath  string
+	UploadedAt   time.Time
+	// LUT describes a "lut" asset.
+	LUT *LUTInfo
+	// MIMEType is a "cover" asset's image type.
+	MIMEType string
+}
+
+// LUTInfo is what an uploaded .cube LUT declares, as ffmpeg.CubeLUT
+// describes it.
+type LUTInfo struct {
+	Title string `json:"title,omitempty"`
+	Size  int    `json:"size"` // points per axis
+}
+
 type Storage struct {
 	mu      sync.RWMutex
 	files   map[string]*UploadedFile
+	assets  map[string]*Asset
 	baseDir string
 }
 
 func NewStorage(baseDir string) *Storage {
 	return &Storage{
 		files:   make(map[string]*UploadedFile),
+		assets:  make(map[string]*Asset),
 		baseDir: baseDir,
 	}
 }
@@ -55,6 +114,42 @@ func (s *Storage) Delete(id string) {
 	delete(s.files, id)
 }
 
+func (s *Storage) StoreAsset(a *Asset) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.assets[a.ID] = a
+}
+
+// GetAsset returns the asset of kind with id, or nil.
+func (s *Storage) GetAsset(kind, id string) *Asset {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if a := s.assets[id]; a != nil && a.Kind == kind {
+		return a
+	}
+	return nil
+}
+
+func (s *Storage) DeleteAsset(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.assets, id)
+}
+
+// Assets returns the assets of kind, oldest first.
+func (s *Storage) Assets(kind string) []*Asset {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []*Asset
+	for _, a := range s.assets {
+		if a.Kind == kind {
+			out = append(out, a)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UploadedAt.Before(out[j].UploadedAt) })
+	return out
+}
+
 func (s *Storage) GetStoragePath(id, ext string) string {
 	return filepath.Join(s.baseDir, fmt.Sprintf("%s.%s", id, ext))
 }
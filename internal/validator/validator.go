@@ -1,50 +1,546 @@
 package validator
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 )
 
 var (
-	AllowedInputFormats  = map[string]bool{"mp4": true, "mkv": true, "mov": true, "webm": true, "mp3": true, "aac": true, "wav": true, "flac": true, "ogg": true, "avi": true, "m4a": true}
-	AllowedOutputFormats = map[string]bool{"mp4": true, "mkv": true, "mov": true, "webm": true, "mp3": true, "aac": true, "wav": true, "flac": true, "ogg": true, "m4a": true, "avi": true}
-	AllowedVideoCodecs   = map[string]bool{"copy": true, "libx264": true, "libx265": true, "libvpx-vp9": true}
-	AllowedAudioCodecs   = map[string]bool{"copy": true, "aac": true, "libmp3lame": true, "libopus": true, "flac": true}
+	AllowedInputFormats  = map[string]bool{"mp4": true, "mkv": true, "mov": true, "webm": true, "mp3": true, "aac": true, "wav": true, "flac": true, "ogg": true, "avi": true, "m4a": true, "mxf": true}
+	AllowedOutputFormats = map[string]bool{"mp4": true, "mkv": true, "mov": true, "webm": true, "mp3": true, "aac": true, "wav": true, "flac": true, "ogg": true, "m4a": true, "avi": true, "mxf": true}
+	AllowedVideoCodecs   = map[string]bool{"copy": true, "libx264": true, "libx265": true, "libvpx-vp9": true, "libsvtav1": true, "libaom-av1": true, "prores_ks": true, "dnxhd": true, "ffv1": true}
+	AllowedAudioCodecs   = map[string]bool{"copy": true, "aac": true, "libmp3lame": true, "libopus": true, "flac": true, "pcm_s16le": true, "pcm_s24le": true}
 	AllowedPresets       = map[string]bool{"ultrafast": true, "superfast": true, "veryfast": true, "faster": true, "fast": true, "medium": true, "slow": true, "slower": true, "veryslow": true}
 	AllowedPresetModes   = map[string]bool{"low_cpu": true, "balanced": true, "quality": true}
 	AllowedFitModes      = map[string]bool{"contain": true, "cover": true}
+	// AllowedLoudnessPresets mirrors ffmpeg.LoudnessPresets.
+	AllowedLoudnessPresets = map[string]bool{"podcast": true, "streaming": true, "broadcast": true}
+	// AllowedHWCodecs mirrors ffmpeg.HWCodecs.
+	AllowedHWCodecs = map[string]bool{"h264": true, "hevc": true, "av1": true}
+	// AllowedCleanupPresets mirrors ffmpeg.CleanupPresets.
+	AllowedCleanupPresets = map[string]bool{"voice": true, "podcast": true, "noisy": true}
+	// CleanupLevels mirrors ffmpeg.CleanupLevels; "off" disables a stage.
+	CleanupLevels = map[string]map[string]bool{
+		"denoise":    {"off": true, "light": true, "medium": true, "strong": true},
+		"gate":       {"off": true, "light": true, "medium": true, "strong": true},
+		"deess":      {"off": true, "light": true, "medium": true, "strong": true},
+		"compressor": {"off": true, "gentle": true, "voice": true, "heavy": true},
+	}
+	// AllowedDenoiseModels mirrors ffmpeg.DenoiseModels.
+	AllowedDenoiseModels = map[string]bool{"fft": true, "rnnoise": true}
+	// AllowedCurvesPresets mirrors ffmpeg.CurvesPresets.
+	AllowedCurvesPresets = map[string]bool{
+		"color_negative": true, "cross_process": true, "darker": true, "increase_contrast": true, "lighter": true,
+		"linear_contrast": true, "medium_contrast": true, "negative": true, "strong_contrast": true, "vintage": true,
+	}
+	// AllowedHDRModes mirrors ffmpeg.HDRModes.
+	AllowedHDRModes = map[string]bool{"auto": true, "tonemap": true, "preserve": true, "off": true}
+	// AllowedToneMapAlgorithms mirrors ffmpeg.ToneMapAlgorithms.
+	AllowedToneMapAlgorithms = map[string]bool{"hable": true, "mobius": true, "reinhard": true, "clip": true, "gamma": true, "linear": true}
+	// HDRCodecs mirrors ffmpeg.HDRCodecs.
+	HDRCodecs = map[string]bool{"libx265": true, "libsvtav1": true, "libaom-av1": true}
+	// AllowedFrameRateModes mirrors ffmpeg.FrameRateModes.
+	AllowedFrameRateModes = map[string]bool{"drop": true, "blend": true, "interpolate": true}
+	// AllowedDeinterlaceModes are ffmpeg.Deinterlacers plus "auto", which
+	// detects interlacing first.
+	AllowedDeinterlaceModes = map[string]bool{"auto": true, "bwdif": true, "yadif": true}
+	// AllowedChannelModes mirrors ffmpeg.ChannelModes.
+	AllowedChannelModes = map[string]bool{"mono": true, "stereo": true, "5.1": true, "left": true, "right": true, "swap": true}
+	// CRFRanges is each software encoder's crf scale.
+	CRFRanges = map[string][2]int{
+		"libx264": {0, 51}, "libx265": {0, 51}, "libvpx-vp9": {0, 63}, "libsvtav1": {0, 63}, "libaom-av1": {0, 63},
+	}
+	// hwCRFRange is the quality scale hardware encoders share (-cq, -qp,
+	// -global_quality).
+	hwCRFRange = [2]int{1, 51}
+	// hwAccels mirrors ffmpeg.HWAccels.
+	hwAccels = []string{"nvenc", "qsv", "vaapi", "amf", "videotoolbox"}
+	// AV1Formats are the containers AV1 output may use.
+	AV1Formats = map[string]bool{"mp4": true, "mkv": true, "webm": true}
+	// webmVideoCodecs and webmAudioCodecs are what WebM can hold, besides
+	// hardware AV1 encoders.
+	webmVideoCodecs = map[string]bool{"copy": true, "libvpx-vp9": true, "libsvtav1": true, "libaom-av1": true}
+	webmAudioCodecs = map[string]bool{"copy": true, "libopus": true}
+	// VideoProfiles mirrors ffmpeg.IntermediateProfiles: the video_profile
+	// values of ProRes and DNxHR.
+	VideoProfiles = map[string]map[string]bool{
+		"prores_ks": {"proxy": true, "lt": true, "standard": true, "hq": true, "4444": true, "4444xq": true},
+		"dnxhd":     {"lb": true, "sq": true, "hq": true, "hqx": true, "444": true},
+	}
+	// intermediateFormats are the containers of each intermediate codec.
+	intermediateFormats = map[string]map[string]bool{
+		"prores_ks": {"mov": true, "mkv": true, "mxf": true},
+		"dnxhd":     {"mov": true, "mkv": true, "mxf": true},
+		"ffv1":      {"mkv": true},
+	}
+	// pcmFormats are the containers that take PCM audio.
+	pcmFormats = map[string]bool{"mov": true, "mkv": true, "mxf": true, "avi": true, "wav": true}
+	// audioOnlyFormats mirrors ffmpeg's audio-only output formats.
+	audioOnlyFormats = map[string]bool{"mp3": true, "aac": true, "m4a": true, "wav": true, "flac": true, "ogg": true}
+	// coverArtFormats mirrors ffmpeg.CoverArtFormats.
+	coverArtFormats = map[string]bool{"mp3": true, "m4a": true, "flac": true}
+	// chapterFormats mirrors ffmpeg.ChapterFormats.
+	chapterFormats = map[string]bool{"mp4": true, "mkv": true}
 )
 
+// unavailable holds the "field:value" entries Restrict removed, so validation
+// can say a value is missing from the ffmpeg build rather than unsupported.
+var unavailable = map[string]bool{}
+
+// Restrict removes the video codecs, audio codecs and output formats this
+// server's ffmpeg build cannot produce. It is called once at startup.
+func Restrict(videoCodec, audioCodec, outputFormat func(string) bool) {
+	restrict("video_codec", AllowedVideoCodecs, videoCodec)
+	restrict("audio_codec", AllowedAudioCodecs, audioCodec)
+	restrict("output_format", AllowedOutputFormats, outputFormat)
+}
+
+func restrict(field string, allowed map[string]bool, available func(string) bool) {
+	for value := range allowed {
+		if !available(value) {
+			delete(allowed, value)
+			unavailable[field+":"+value] = true
+		}
+	}
+}
+
+// AllowVideoCodecs adds encoders verified at startup (hardware encoders) to
+// AllowedVideoCodecs.
+func AllowVideoCodecs(names ...string) {
+	for _, name := range names {
+		AllowedVideoCodecs[name] = true
+		delete(unavailable, "video_codec:"+name)
+	}
+}
+
+// validateHWCodec checks hw_codec, which picks the encoder itself and so
+// excludes video_codec.
+func validateHWCodec(hwCodec, videoCodec *string) error {
+	if hwCodec == nil {
+		return nil
+	}
+	if !AllowedHWCodecs[*hwCodec] {
+		return fmt.Errorf("hw_codec not allowed: %s", *hwCodec)
+	}
+	if videoCodec != nil && *videoCodec != "" {
+		return fmt.Errorf("set either hw_codec or video_codec, not both")
+	}
+	return nil
+}
+
+// isHWEncoder reports whether name is a hardware encoder such as "hevc_nvenc".
+func isHWEncoder(name string) bool {
+	for _, accel := range hwAccels {
+		if strings.HasSuffix(name, "_"+accel) {
+			return true
+		}
+	}
+	return false
+}
+
+// hwSoftwareEncoders mirrors ffmpeg.SoftwareEncoderFor: the encoder hw_codec
+// falls back to.
+var hwSoftwareEncoders = map[string]string{"h264": "libx264", "hevc": "libx265", "av1": "libsvtav1"}
+
+// videoEncoder names the encoder a request will use: video_codec, else the
+// format's default. For hw_codec, whose encoder is picked at run time, it is
+// the software fallback.
+func videoEncoder(format string, videoCodec, hwCodec *string) string {
+	switch {
+	case hwCodec != nil:
+		return hwSoftwareEncoders[*hwCodec]
+	case videoCodec != nil && *videoCodec != "":
+		return *videoCodec
+	case format == "webm":
+		return "libvpx-vp9"
+	}
+	return "libx264"
+}
+
+// validateCodecs checks crf against the encoder's own scale, video_profile
+// against the encoder, and that the output container can hold the chosen
+// codecs.
+func validateCodecs(format string, videoCodec, hwCodec, profile, audioCodec *string, crf *int) error {
+	format = strings.ToLower(format)
+	enc := videoEncoder(format, videoCodec, hwCodec)
+	isAV1 := strings.Contains(enc, "av1")
+	_, isIntermediate := intermediateFormats[enc]
+	isPCM := audioCodec != nil && strings.HasPrefix(*audioCodec, "pcm_")
+	if profile != nil {
+		profiles, ok := VideoProfiles[enc]
+		if !ok {
+			return fmt.Errorf("video_profile requires video_codec prores_ks or dnxhd")
+		}
+		if !profiles[*profile] {
+			return fmt.Errorf("video_profile not allowed for %s: %s", enc, *profile)
+		}
+	}
+	switch {
+	case audioOnlyFormats[format]:
+		// No video is written; only crf is checked.
+	case isAV1 && !AV1Formats[format]:
+		return fmt.Errorf("AV1 output requires mp4, mkv or webm, not %s", format)
+	case isIntermediate && !intermediateFormats[enc][format]:
+		return fmt.Errorf("%s output requires %s, not %s", enc, formatList(intermediateFormats[enc]), format)
+	case format == "mxf" && enc != "copy" && !isIntermediate:
+		return fmt.Errorf("mxf output requires video_codec prores_ks or dnxhd")
+	case format == "mxf" && audioCodec != nil && *audioCodec != "copy" && !isPCM:
+		return fmt.Errorf("mxf output requires PCM audio (pcm_s16le or pcm_s24le)")
+	case isPCM && !pcmFormats[format]:
+		return fmt.Errorf("audio_codec %s is not supported for %s; use mov, mkv or mxf", *audioCodec, format)
+	case format == "webm" && !isAV1 && !webmVideoCodecs[enc]:
+		return fmt.Errorf("video_codec %s is not supported for webm; use libvpx-vp9 or an AV1 encoder", enc)
+	case format == "webm" && audioCodec != nil && !webmAudioCodecs[*audioCodec]:
+		return fmt.Errorf("audio_codec %s is not supported for webm; use libopus", *audioCodec)
+	case format == "mov" && audioCodec != nil && *audioCodec == "libopus":
+		return fmt.Errorf("audio_codec libopus is not supported for mov; use aac")
+	}
+	if crf == nil || enc == "copy" {
+		return nil
+	}
+	if enc == "ffv1" {
+		return fmt.Errorf("crf does not apply to ffv1, which is lossless")
+	}
+	if isIntermediate {
+		return fmt.Errorf("crf does not apply to %s; its quality is set by video_profile", enc)
+	}
+	r, ok := CRFRanges[enc]
+	if hwCodec != nil || isHWEncoder(enc) {
+		r, ok, enc = hwCRFRange, true, "hardware encoders"
+	}
+	if ok && (*crf < r[0] || *crf > r[1]) {
+		return fmt.Errorf("crf must be between %d and %d for %s", r[0], r[1], enc)
+	}
+	return nil
+}
+
+// formatList joins a set of formats as "mov, mkv or mxf".
+func formatList(set map[string]bool) string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// validateChannels checks a channel operation and stereo pan for format.
+func validateChannels(mode *string, pan *float64, format string) error {
+	if mode != nil && !AllowedChannelModes[*mode] {
+		return fmt.Errorf("channels not allowed: %s", *mode)
+	}
+	if pan != nil && (*pan < -1 || *pan > 1) {
+		return fmt.Errorf("pan must be between -1 and 1")
+	}
+	if pan != nil && mode != nil && (*mode == "mono" || *mode == "5.1") {
+		return fmt.Errorf("pan applies to stereo output, not channels %s", *mode)
+	}
+	if mode != nil && *mode == "5.1" && strings.EqualFold(format, "mp3") {
+		return fmt.Errorf("mp3 cannot hold 5.1 audio")
+	}
+	return nil
+}
+
+// checkAllowed validates value against allowed; field names it in the error.
+func checkAllowed(field, value string, allowed map[string]bool) error {
+	if allowed[value] {
+		return nil
+	}
+	if unavailable[field+":"+value] {
+		return fmt.Errorf("%s %s is not available in this server's ffmpeg build", field, value)
+	}
+	return fmt.Errorf("%s not allowed: %s", field, value)
+}
+
+// LoudnessParams configures two-pass EBU R128 normalization. Any field implies
+// normalize; explicit values override the preset.
+type LoudnessParams struct {
+	LoudnessPreset *string  `json:"loudness_preset"`
+	LoudnessI      *float64 `json:"loudness_i"`
+	LoudnessTP     *float64 `json:"loudness_tp"`
+	LoudnessLRA    *float64 `json:"loudness_lra"`
+}
+
+// IsSet reports whether any loudness field was supplied.
+func (p LoudnessParams) IsSet() bool {
+	return p.LoudnessPreset != nil || p.LoudnessI != nil || p.LoudnessTP != nil || p.LoudnessLRA != nil
+}
+
+func (p LoudnessParams) validate() error {
+	if p.LoudnessPreset != nil && !AllowedLoudnessPresets[*p.LoudnessPreset] {
+		return fmt.Errorf("loudness_preset not allowed: %s", *p.LoudnessPreset)
+	}
+	if p.LoudnessI != nil && (*p.LoudnessI < -70 || *p.LoudnessI > -5) {
+		return fmt.Errorf("loudness_i must be between -70 and -5 LUFS")
+	}
+	if p.LoudnessTP != nil && (*p.LoudnessTP < -9 || *p.LoudnessTP > 0) {
+		return fmt.Errorf("loudness_tp must be between -9 and 0 dBTP")
+	}
+	if p.LoudnessLRA != nil && (*p.LoudnessLRA < 1 || *p.LoudnessLRA > 20) {
+		return fmt.Errorf("loudness_lra must be between 1 and 20 LU")
+	}
+	return nil
+}
+
+// ColorParams grades the video on top of brightness and contrast.
+type ColorParams struct {
+	Saturation  *float64 `json:"saturation"`  // 0-3, 1 = unchanged
+	Gamma       *float64 `json:"gamma"`       // 0.1-10, 1 = unchanged
+	Hue         *float64 `json:"hue"`         // degrees
+	Temperature *int     `json:"temperature"` // K, 6500 = neutral
+	Curves      *string  `json:"curves"`      // see AllowedCurvesPresets
+	LUTID       *string  `json:"lut_id"`      // an uploaded .cube LUT
+}
+
+func (p ColorParams) validate() error {
+	if p.Saturation != nil && (*p.Saturation < 0 || *p.Saturation > 3) {
+		return fmt.Errorf("saturation must be between 0 and 3")
+	}
+	if p.Gamma != nil && (*p.Gamma < 0.1 || *p.Gamma > 10) {
+		return fmt.Errorf("gamma must be between 0.1 and 10")
+	}
+	if p.Hue != nil && (*p.Hue < -180 || *p.Hue > 180) {
+		return fmt.Errorf("hue must be between -180 and 180 degrees")
+	}
+	if p.Temperature != nil && (*p.Temperature < 1000 || *p.Temperature > 40000) {
+		return fmt.Errorf("temperature must be between 1000 and 40000 K")
+	}
+	if p.Curves != nil && !AllowedCurvesPresets[*p.Curves] {
+		return fmt.Errorf("curves not allowed: %s", *p.Curves)
+	}
+	if p.LUTID != nil && *p.LUTID == "" {
+		return fmt.Errorf("lut_id must not be empty")
+	}
+	return nil
+}
+
+// MaxChapters bounds a request's chapters.
+const MaxChapters = 256
+
+// MetadataTags sets the output's global tags, see ffmpeg.MetadataTags.
+type MetadataTags struct {
+	Title   *string `json:"title"`
+	Artist  *string `json:"artist"`
+	Album   *string `json:"album"`
+	Comment *string `json:"comment"`
+	Date    *string `json:"date"` // YYYY, YYYY-MM or YYYY-MM-DD
+}
+
+var dateRe = regexp.MustCompile(`^\d{4}(-\d{2}(-\d{2})?)?$`)
+
+func (t *MetadataTags) Validate() error {
+	for _, f := range []struct {
+		name string
+		v    *string
+	}{{"title", t.Title}, {"artist", t.Artist}, {"album", t.Album}, {"comment", t.Comment}} {
+		if f.v != nil && len(*f.v) > 1000 {
+			return fmt.Errorf("%s must be at most 1000 characters", f.name)
+		}
+	}
+	if t.Date != nil && *t.Date != "" && !dateRe.MatchString(*t.Date) {
+		return fmt.Errorf("date must be YYYY, YYYY-MM or YYYY-MM-DD: %s", *t.Date)
+	}
+	return nil
+}
+
+// ChapterParams is one chapter; an end left out runs to the next chapter or
+// the end of the output.
+type ChapterParams struct {
+	Start float64  `json:"start"`
+	End   *float64 `json:"end"`
+	Title string   `json:"title"`
+}
+
+// MetadataParams sets the output's tags, cover art and chapters.
+type MetadataParams struct {
+	Metadata *MetadataTags   `json:"metadata"`
+	CoverID  *string         `json:"cover_id"` // an uploaded cover image
+	Chapters []ChapterParams `json:"chapters"`
+}
+
+func (p MetadataParams) validate(format string) error {
+	format = strings.ToLower(format)
+	if p.Metadata != nil {
+		if err := p.Metadata.Validate(); err != nil {
+			return fmt.Errorf("metadata: %w", err)
+		}
+	}
+	if p.CoverID != nil {
+		if *p.CoverID == "" {
+			return fmt.Errorf("cover_id must not be empty")
+		}
+		if !coverArtFormats[format] {
+			return fmt.Errorf("cover_id needs output_format %s", formatList(coverArtFormats))
+		}
+	}
+	return validateChapters(p.Chapters, format)
+}
+
+func validateChapters(chapters []ChapterParams, format string) error {
+	if len(chapters) == 0 {
+		return nil
+	}
+	if !chapterFormats[format] {
+		return fmt.Errorf("chapters need output_format %s", formatList(chapterFormats))
+	}
+	if len(chapters) > MaxChapters {
+		return fmt.Errorf("at most %d chapters are allowed", MaxChapters)
+	}
+	for i, ch := range chapters {
+		switch {
+		case ch.Start < 0:
+			return fmt.Errorf("chapters[%d].start cannot be negative", i)
+		case i > 0 && ch.Start <= chapters[i-1].Start:
+			return fmt.Errorf("chapters[%d].start must be after the previous chapter's", i)
+		case ch.End != nil && *ch.End <= ch.Start:
+			return fmt.Errorf("chapters[%d].end must be after its start", i)
+		case ch.End != nil && i+1 < len(chapters) && *ch.End > chapters[i+1].Start:
+			return fmt.Errorf("chapters[%d] overlaps the next chapter", i)
+		case strings.TrimSpace(ch.Title) == "":
+			return fmt.Errorf("chapters[%d].title is required", i)
+		case len(ch.Title) > 200:
+			return fmt.Errorf("chapters[%d].title must be at most 200 characters", i)
+		}
+	}
+	return nil
+}
+
 type ConvertRequest struct {
-	FileID        string   `json:"file_id"`
-	OutputFormat  string   `json:"output_format"`
-	VideoCodec    *string  `json:"video_codec"`
-	AudioCodec    *string  `json:"audio_codec"`
-	VideoBitrate  *string  `json:"video_bitrate"`
-	AudioBitrate  *string  `json:"audio_bitrate"`
-	CRF           *int     `json:"crf"`
-	Preset        *string  `json:"preset"`
-	FPS           *int     `json:"fps"`
-	RemoveAudio   bool     `json:"remove_audio"`
-	RemoveVideo   bool     `json:"remove_video"`
-	TrimStart     *float64 `json:"trim_start"`
-	TrimDuration  *float64 `json:"trim_duration"`
-	ResizeWidth   *int     `json:"resize_width"`
-	ResizeHeight  *int     `json:"resize_height"`
-	KeepAspect    bool     `json:"keep_aspect"`
-	FitMode       *string  `json:"fit_mode"`
-	FastStart     bool     `json:"fast_start"`
-	StripMetadata bool     `json:"strip_metadata"`
-	Brightness    *float64 `json:"brightness"`
-	Contrast      *float64 `json:"contrast"`
-	Volume        *float64 `json:"volume"`
-	Speed         *float64 `json:"speed"`
-	FadeIn        *float64 `json:"fade_in"`
-	FadeOut       *float64 `json:"fade_out"`
-	Normalize     bool     `json:"normalize"`
-	Bass          *float64 `json:"bass"`
-	Treble        *float64 `json:"treble"`
+	FileID        string           `json:"file_id"`
+	OutputFormat  string           `json:"output_format"`
+	VideoCodec    *string          `json:"video_codec"`
+	HWCodec       *string          `json:"hw_codec"`      // "h264" | "hevc" | "av1": hardware encoder if verified, else software
+	VideoProfile  *string          `json:"video_profile"` // ProRes or DNxHR profile, see VideoProfiles
+	AudioCodec    *string          `json:"audio_codec"`
+	VideoBitrate  *string          `json:"video_bitrate"`
+	AudioBitrate  *string          `json:"audio_bitrate"`
+	CRF           *int             `json:"crf"`
+	Preset        *string          `json:"preset"`
+	FPS           *FrameRate       `json:"fps"`
+	FPSMode       *string          `json:"fps_mode"`    // see AllowedFrameRateModes
+	Deinterlace   *string          `json:"deinterlace"` // see AllowedDeinterlaceModes
+	RemoveAudio   bool             `json:"remove_audio"`
+	RemoveVideo   bool             `json:"remove_video"`
+	TrimStart     *float64         `json:"trim_start"`
+	TrimDuration  *float64         `json:"trim_duration"`
+	ResizeWidth   *int             `json:"resize_width"`
+	ResizeHeight  *int             `json:"resize_height"`
+	KeepAspect    bool             `json:"keep_aspect"`
+	FitMode       *string          `json:"fit_mode"`
+	FastStart     bool             `json:"fast_start"`
+	StripMetadata bool             `json:"strip_metadata"`
+	Brightness    *float64         `json:"brightness"`
+	Contrast      *float64         `json:"contrast"`
+	Volume        *float64         `json:"volume"`
+	Speed         *float64         `json:"speed"`
+	FadeIn        *float64         `json:"fade_in"`
+	FadeOut       *float64         `json:"fade_out"`
+	Normalize     bool             `json:"normalize"`
+	Bass          *float64         `json:"bass"`
+	Treble        *float64         `json:"treble"`
+	Channels      *string          `json:"channels"` // see AllowedChannelModes
+	Pan           *float64         `json:"pan"`      // stereo balance, -1 (left) to 1 (right)
+	Cleanup       *CleanupParams   `json:"cleanup"`
+	Stabilize     *StabilizeParams `json:"stabilize"`
+	HDR           *string          `json:"hdr"`     // see AllowedHDRModes; nil is "auto"
+	ToneMap       *string          `json:"tonemap"` // tone curve, see AllowedToneMapAlgorithms
+	QC            *QCParams        `json:"qc"`
+	// QualityMetrics scores the output against the source (PSNR, SSIM, VMAF).
+	QualityMetrics bool `json:"quality_metrics"`
+	// Streams picks the input streams to keep and labels them; nil keeps
+	// ffmpeg's default of one video and one audio stream.
+	Streams *StreamParams `json:"streams"`
+	LoudnessParams
+	ColorParams
+	MetadataParams
+}
+
+// FrameRate is a frame rate given as a JSON number (25, 29.97) or string
+// ("30000/1001", "23.976"). The NTSC rates written as decimals stand for
+// their exact 1000/1001 fractions.
+type FrameRate string
+
+// UnmarshalJSON accepts a number or a string.
+func (f *FrameRate) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*f = FrameRate(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return fmt.Errorf("fps must be a number or a fraction such as \"30000/1001\"")
+	}
+	*f = FrameRate(n)
+	return nil
+}
+
+// ntscRates are the rates whose NTSC variants run at 1000/1001 of the speed.
+var ntscRates = []int{24, 30, 48, 60, 120}
+
+// Rational returns the rate as a reduced fraction ("30000/1001") and its value.
+func (f FrameRate) Rational() (string, float64, error) {
+	s := strings.TrimSpace(string(f))
+	if num, den, ok := strings.Cut(s, "/"); ok {
+		n, errN := strconv.Atoi(num)
+		d, errD := strconv.Atoi(den)
+		if errN != nil || errD != nil || n <= 0 || d <= 0 {
+			return "", 0, fmt.Errorf("fps %q is not a valid fraction", s)
+		}
+		g := gcd(n, d)
+		return fmt.Sprintf("%d/%d", n/g, d/g), float64(n) / float64(d), nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v <= 0 || math.IsInf(v, 0) {
+		return "", 0, fmt.Errorf("fps %q is not a valid frame rate", s)
+	}
+	for _, r := range ntscRates {
+		if ntsc := float64(r*1000) / 1001; math.Abs(v-ntsc) < 0.005 {
+			return fmt.Sprintf("%d/1001", r*1000), ntsc, nil
+		}
+	}
+	// Any other decimal is kept to a thousandth of a frame per second.
+	n := int(math.Round(v * 1000))
+	g := gcd(n, 1000)
+	return fmt.Sprintf("%d/%d", n/g, 1000/g), float64(n) / 1000, nil
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// validateFrameRate checks fps (1-120) and fps_mode, which needs fps.
+func validateFrameRate(fps *FrameRate, mode *string) error {
+	if mode != nil {
+		if fps == nil {
+			return fmt.Errorf("fps_mode needs fps")
+		}
+		if err := checkAllowed("fps_mode", *mode, AllowedFrameRateModes); err != nil {
+			return err
+		}
+	}
+	if fps == nil {
+		return nil
+	}
+	_, v, err := fps.Rational()
+	if err != nil {
+		return err
+	}
+	if v < 1 || v > 120 {
+		return fmt.Errorf("fps must be between 1 and 120")
+	}
+	return nil
 }
 
 func (r *ConvertRequest) Validate() error {
@@ -54,26 +550,38 @@ func (r *ConvertRequest) Validate() error {
 	if r.OutputFormat == "" {
 		return fmt.Errorf("output_format is required")
 	}
-	if !AllowedOutputFormats[strings.ToLower(r.OutputFormat)] {
-		return fmt.Errorf("output_format not allowed: %s", r.OutputFormat)
+	if err := checkAllowed("output_format", strings.ToLower(r.OutputFormat), AllowedOutputFormats); err != nil {
+		return err
 	}
-	if r.VideoCodec != nil && !AllowedVideoCodecs[*r.VideoCodec] {
-		return fmt.Errorf("video_codec not allowed: %s", *r.VideoCodec)
+	if r.VideoCodec != nil {
+		if err := checkAllowed("video_codec", *r.VideoCodec, AllowedVideoCodecs); err != nil {
+			return err
+		}
 	}
-	if r.AudioCodec != nil && !AllowedAudioCodecs[*r.AudioCodec] {
-		return fmt.Errorf("audio_codec not allowed: %s", *r.AudioCodec)
+	if err := validateHWCodec(r.HWCodec, r.VideoCodec); err != nil {
+		return err
 	}
-	if strings.ToLower(r.OutputFormat) == "mov" && r.AudioCodec != nil && *r.AudioCodec == "libopus" {
-		return fmt.Errorf("audio_codec libopus is not supported for mov; use aac")
+	if r.AudioCodec != nil {
+		if err := checkAllowed("audio_codec", *r.AudioCodec, AllowedAudioCodecs); err != nil {
+			return err
+		}
 	}
-	if r.CRF != nil && (*r.CRF < 18 || *r.CRF > 35) {
-		return fmt.Errorf("crf must be between 18 and 35")
+	if err := validateCodecs(r.OutputFormat, r.VideoCodec, r.HWCodec, r.VideoProfile, r.AudioCodec, r.CRF); err != nil {
+		return err
 	}
 	if r.Preset != nil && !AllowedPresets[*r.Preset] {
 		return fmt.Errorf("preset not allowed: %s", *r.Preset)
 	}
-	if r.FPS != nil && (*r.FPS < 1 || *r.FPS > 60) {
-		return fmt.Errorf("fps must be between 1 and 60")
+	if err := validateFrameRate(r.FPS, r.FPSMode); err != nil {
+		return err
+	}
+	if r.Deinterlace != nil {
+		if err := checkAllowed("deinterlace", *r.Deinterlace, AllowedDeinterlaceModes); err != nil {
+			return err
+		}
+	}
+	if (r.FPS != nil || r.Deinterlace != nil) && r.VideoCodec != nil && *r.VideoCodec == "copy" {
+		return fmt.Errorf("fps and deinterlace re-encode the video and cannot use video_codec copy")
 	}
 	if r.TrimStart != nil && *r.TrimStart < 0 {
 		return fmt.Errorf("trim_start cannot be negative")
@@ -105,14 +613,292 @@ func (r *ConvertRequest) Validate() error {
 	if r.Treble != nil && (*r.Treble < -20 || *r.Treble > 20) {
 		return fmt.Errorf("treble must be between -20 and 20 dB")
 	}
+	if err := validateChannels(r.Channels, r.Pan, r.OutputFormat); err != nil {
+		return err
+	}
+	if r.Cleanup != nil {
+		if err := r.Cleanup.Validate(); err != nil {
+			return fmt.Errorf("cleanup: %w", err)
+		}
+	}
+	if r.Stabilize != nil {
+		if r.RemoveVideo || audioOnlyFormats[strings.ToLower(r.OutputFormat)] {
+			return fmt.Errorf("stabilize needs video output")
+		}
+		if r.VideoCodec != nil && *r.VideoCodec == "copy" {
+			return fmt.Errorf("stabilize re-encodes the video and cannot use video_codec copy")
+		}
+		if err := r.Stabilize.Validate(); err != nil {
+			return fmt.Errorf("stabilize: %w", err)
+		}
+	}
+	if err := r.ColorParams.validate(); err != nil {
+		return err
+	}
+	if err := validateHDR(r.HDR, r.ToneMap, r.VideoCodec); err != nil {
+		return err
+	}
+	if err := r.LoudnessParams.validate(); err != nil {
+		return err
+	}
+	if r.QC != nil {
+		if err := r.QC.Validate(); err != nil {
+			return fmt.Errorf("qc: %w", err)
+		}
+	}
+	if r.Streams != nil {
+		if err := r.Streams.Validate(); err != nil {
+			return fmt.Errorf("streams: %w", err)
+		}
+	}
+	if err := r.MetadataParams.validate(r.OutputFormat); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateHDR checks the HDR mode and tone curve. Tone mapping re-encodes,
+// and preserving HDR needs an encoder that can carry it.
+func validateHDR(mode, toneMap, videoCodec *string) error {
+	m := "auto"
+	if mode != nil {
+		if err := checkAllowed("hdr", *mode, AllowedHDRModes); err != nil {
+			return err
+		}
+		m = *mode
+	}
+	if toneMap != nil {
+		if err := checkAllowed("tonemap", *toneMap, AllowedToneMapAlgorithms); err != nil {
+			return err
+		}
+		if m == "preserve" || m == "off" {
+			return fmt.Errorf("tonemap cannot be used with hdr %s", m)
+		}
+	}
+	if m == "auto" || m == "off" {
+		return nil
+	}
+	if videoCodec != nil && *videoCodec == "copy" {
+		return fmt.Errorf("hdr %s re-encodes the video and cannot use video_codec copy", m)
+	}
+	if m == "preserve" && (videoCodec == nil || !HDRCodecs[*videoCodec]) {
+		return fmt.Errorf("hdr preserve needs video_codec %s", formatList(HDRCodecs))
+	}
+	return nil
+}
+
+// AllowedStreamTypes are the stream types a selector may name.
+var AllowedStreamTypes = map[string]bool{"video": true, "audio": true, "subtitle": true}
+
+var languageRe = regexp.MustCompile(`^[a-z]{3}$`)
+
+// StreamSelector matches input streams (see GET /files/:id/streams); every
+// field that is set must match.
+type StreamSelector struct {
+	Index    *int   `json:"index"`
+	Type     string `json:"type"`
+	Language string `json:"language"` // ISO 639-2, e.g. "eng"
+}
+
+func (s StreamSelector) validate() error {
+	if s.Index == nil && s.Type == "" && s.Language == "" {
+		return fmt.Errorf("a selector needs index, type or language")
+	}
+	if s.Index != nil && *s.Index < 0 {
+		return fmt.Errorf("index cannot be negative")
+	}
+	if s.Type != "" && !AllowedStreamTypes[s.Type] {
+		return fmt.Errorf("type not allowed: %s", s.Type)
+	}
+	if s.Language != "" && !languageRe.MatchString(s.Language) {
+		return fmt.Errorf("language must be a three-letter ISO 639-2 code: %s", s.Language)
+	}
+	return nil
+}
+
+// StreamMetadata sets the language, title and dispositions of the output
+// stream made from input stream Index.
+type StreamMetadata struct {
+	Index    int     `json:"index"`
+	Language *string `json:"language"`
+	Title    *string `json:"title"`
+	Default  *bool   `json:"default"`
+	Forced   *bool   `json:"forced"`
+}
+
+// StreamParams selects output streams. Include replaces the default
+// selection, AllAudio adds every audio stream, and Exclude drops streams
+// from the result.
+type StreamParams struct {
+	Include  []StreamSelector `json:"include"`
+	Exclude  []StreamSelector `json:"exclude"`
+	AllAudio bool             `json:"all_audio"`
+	Metadata []StreamMetadata `json:"metadata"`
+}
+
+func (p *StreamParams) Validate() error {
+	for _, s := range append(append([]StreamSelector{}, p.Include...), p.Exclude...) {
+		if err := s.validate(); err != nil {
+			return err
+		}
+	}
+	seen := map[int]bool{}
+	for _, m := range p.Metadata {
+		if m.Index < 0 {
+			return fmt.Errorf("metadata index cannot be negative")
+		}
+		if seen[m.Index] {
+			return fmt.Errorf("metadata for stream %d is given twice", m.Index)
+		}
+		seen[m.Index] = true
+		if m.Language != nil && !languageRe.MatchString(*m.Language) {
+			return fmt.Errorf("language must be a three-letter ISO 639-2 code: %s", *m.Language)
+		}
+		if m.Title != nil && len(*m.Title) > 200 {
+			return fmt.Errorf("title must be at most 200 characters")
+		}
+	}
 	return nil
 }
 
 // TimelineClip is one segment in an EDL-style export request.
 type TimelineClip struct {
-	FileID      string  `json:"file_id"`
-	SourceStart float64 `json:"source_start"`
-	Duration    float64 `json:"duration"`
+	FileID      string           `json:"file_id"`
+	SourceStart float64          `json:"source_start"`
+	Duration    float64          `json:"duration"`
+	Channels    *string          `json:"channels"` // see ConvertRequest.Channels
+	Pan         *float64         `json:"pan"`
+	Stabilize   *StabilizeParams `json:"stabilize"`
+	Reverse     bool             `json:"reverse"` // at most MaxReverseDuration seconds
+	Freeze      *FreezeParams    `json:"freeze"`
+	SpeedRamp   []SpeedKeyframe  `json:"speed_ramp"`
+	Keyframes   *AnimationParams `json:"keyframes"`
+	// Chapter names the clip's chapter when the export has auto_chapters.
+	Chapter string `json:"chapter"`
+	// ColorParams grades the clip before the timeline's own grade.
+	ColorParams
+}
+
+// MaxReverseDuration mirrors ffmpeg.MaxReverseDuration.
+const MaxReverseDuration = 30.0
+
+// MaxSpeedKeyframes bounds a clip's speed ramp.
+const MaxSpeedKeyframes = 16
+
+// SpeedKeyframe sets a clip's speed Time seconds into the clip; the speed
+// changes linearly between keyframes.
+type SpeedKeyframe struct {
+	Time  float64 `json:"time"`
+	Speed float64 `json:"speed"` // 0.1-10
+}
+
+// FreezeParams holds the frame At seconds into the played clip for Hold
+// seconds.
+type FreezeParams struct {
+	At   float64 `json:"at"`
+	Hold float64 `json:"hold"`
+}
+
+// validateRetime checks a clip's reverse, speed ramp and freeze against its
+// duration.
+func (c *TimelineClip) validateRetime() error {
+	if c.Reverse && c.Duration > MaxReverseDuration {
+		return fmt.Errorf("reverse is limited to clips of %.0f seconds", MaxReverseDuration)
+	}
+	if len(c.SpeedRamp) > MaxSpeedKeyframes {
+		return fmt.Errorf("speed_ramp has more than %d keyframes", MaxSpeedKeyframes)
+	}
+	for i, k := range c.SpeedRamp {
+		if k.Time < 0 || k.Time > c.Duration {
+			return fmt.Errorf("speed_ramp[%d].time must be within the clip (0-%g s)", i, c.Duration)
+		}
+		if i > 0 && k.Time <= c.SpeedRamp[i-1].Time {
+			return fmt.Errorf("speed_ramp times must increase")
+		}
+		if k.Speed < 0.1 || k.Speed > 10 {
+			return fmt.Errorf("speed_ramp[%d].speed must be between 0.1 and 10", i)
+		}
+	}
+	if c.Freeze != nil {
+		if c.Freeze.At < 0 {
+			return fmt.Errorf("freeze.at cannot be negative")
+		}
+		if c.Freeze.Hold <= 0 || c.Freeze.Hold > 30 {
+			return fmt.Errorf("freeze.hold must be between 0 and 30 seconds")
+		}
+	}
+	return nil
+}
+
+// MaxKeyframes bounds each animated property of a clip.
+const MaxKeyframes = 32
+
+// AllowedEasings mirrors ffmpeg.Easings.
+var AllowedEasings = map[string]bool{"linear": true, "ease_in": true, "ease_out": true, "ease_in_out": true, "hold": true}
+
+// Keyframe sets a property to Value at Time seconds into the clip; Ease
+// shapes the change to the next keyframe.
+type Keyframe struct {
+	Time  float64 `json:"time"`
+	Value float64 `json:"value"`
+	Ease  string  `json:"ease"`
+}
+
+// AnimationParams keyframes a timeline clip's properties.
+type AnimationParams struct {
+	Volume     []Keyframe `json:"volume"`     // 0-4
+	Brightness []Keyframe `json:"brightness"` // -1-1
+	Contrast   []Keyframe `json:"contrast"`   // 0-3
+	Saturation []Keyframe `json:"saturation"` // 0-3
+	Zoom       []Keyframe `json:"zoom"`       // 1-4
+	FocusX     []Keyframe `json:"focus_x"`    // 0-1
+	FocusY     []Keyframe `json:"focus_y"`    // 0-1
+}
+
+// Validate checks every property against the clip's duration.
+func (p *AnimationParams) Validate(duration float64) error {
+	for _, prop := range []struct {
+		name     string
+		k        []Keyframe
+		min, max float64
+	}{
+		{"volume", p.Volume, 0, 4},
+		{"brightness", p.Brightness, -1, 1},
+		{"contrast", p.Contrast, 0, 3},
+		{"saturation", p.Saturation, 0, 3},
+		{"zoom", p.Zoom, 1, 4},
+		{"focus_x", p.FocusX, 0, 1},
+		{"focus_y", p.FocusY, 0, 1},
+	} {
+		if len(prop.k) > MaxKeyframes {
+			return fmt.Errorf("%s has more than %d keyframes", prop.name, MaxKeyframes)
+		}
+		for i, k := range prop.k {
+			if k.Time < 0 || k.Time > duration {
+				return fmt.Errorf("%s[%d].time must be within the clip (0-%g s)", prop.name, i, duration)
+			}
+			if i > 0 && k.Time <= prop.k[i-1].Time {
+				return fmt.Errorf("%s times must increase", prop.name)
+			}
+			if k.Value < prop.min || k.Value > prop.max {
+				return fmt.Errorf("%s[%d].value must be between %g and %g", prop.name, i, prop.min, prop.max)
+			}
+			if k.Ease != "" {
+				if err := checkAllowed("ease", k.Ease, AllowedEasings); err != nil {
+					return fmt.Errorf("%s[%d]: %w", prop.name, i, err)
+				}
+			}
+		}
+	}
+	if (len(p.FocusX) > 0 || len(p.FocusY) > 0) && len(p.Zoom) == 0 {
+		return fmt.Errorf("focus_x and focus_y need zoom")
+	}
+	return nil
+}
+
+// HasVideo reports whether any video property is animated.
+func (p *AnimationParams) HasVideo() bool {
+	return len(p.Brightness)+len(p.Contrast)+len(p.Saturation)+len(p.Zoom) > 0
 }
 
 // TimelineExportRequest drives POST /timeline/export.
@@ -121,6 +907,8 @@ type TimelineExportRequest struct {
 	Clips        []TimelineClip `json:"clips"`
 	OutputFormat string         `json:"output_format"`
 	VideoCodec   *string        `json:"video_codec"`
+	HWCodec      *string        `json:"hw_codec"`      // see ConvertRequest.HWCodec
+	VideoProfile *string        `json:"video_profile"` // see ConvertRequest.VideoProfile
 	AudioCodec   *string        `json:"audio_codec"`
 	VideoBitrate *string        `json:"video_bitrate"`
 	AudioBitrate *string        `json:"audio_bitrate"`
@@ -140,7 +928,18 @@ type TimelineExportRequest struct {
 	Normalize    bool           `json:"normalize"`
 	Bass         *float64       `json:"bass"`
 	Treble       *float64       `json:"treble"`
+	Cleanup      *CleanupParams `json:"cleanup"`
+	Music        *MusicParams   `json:"music"`
 	Mode         string         `json:"mode"`
+	QC           *QCParams      `json:"qc"`
+	// QualityMetrics scores the output against the source (PSNR, SSIM, VMAF).
+	QualityMetrics bool `json:"quality_metrics"`
+	// AutoChapters starts a chapter at every clip, named by the clip's
+	// chapter field.
+	AutoChapters bool `json:"auto_chapters"`
+	LoudnessParams
+	ColorParams
+	MetadataParams
 }
 
 func (r *TimelineExportRequest) Validate() error {
@@ -150,8 +949,8 @@ func (r *TimelineExportRequest) Validate() error {
 	if r.OutputFormat == "" {
 		return fmt.Errorf("output_format is required")
 	}
-	if !AllowedOutputFormats[strings.ToLower(r.OutputFormat)] {
-		return fmt.Errorf("output_format not allowed: %s", r.OutputFormat)
+	if err := checkAllowed("output_format", strings.ToLower(r.OutputFormat), AllowedOutputFormats); err != nil {
+		return err
 	}
 	for i, clip := range r.Clips {
 		if clip.FileID == "" {
@@ -163,22 +962,54 @@ func (r *TimelineExportRequest) Validate() error {
 		if clip.Duration <= 0 {
 			return fmt.Errorf("clip[%d].duration must be positive", i)
 		}
+		if err := validateChannels(clip.Channels, clip.Pan, r.OutputFormat); err != nil {
+			return fmt.Errorf("clip[%d]: %w", i, err)
+		}
+		if err := clip.ColorParams.validate(); err != nil {
+			return fmt.Errorf("clip[%d]: %w", i, err)
+		}
+		if err := clip.validateRetime(); err != nil {
+			return fmt.Errorf("clip[%d]: %w", i, err)
+		}
+		if clip.Keyframes != nil {
+			if err := clip.Keyframes.Validate(clip.Duration); err != nil {
+				return fmt.Errorf("clip[%d].keyframes: %w", i, err)
+			}
+		}
+		if clip.Chapter != "" && !r.AutoChapters {
+			return fmt.Errorf("clip[%d].chapter needs auto_chapters", i)
+		}
+		if len(clip.Chapter) > 200 {
+			return fmt.Errorf("clip[%d].chapter must be at most 200 characters", i)
+		}
+		if clip.Stabilize != nil {
+			if audioOnlyFormats[strings.ToLower(r.OutputFormat)] {
+				return fmt.Errorf("clip[%d]: stabilize needs video output", i)
+			}
+			if err := clip.Stabilize.Validate(); err != nil {
+				return fmt.Errorf("clip[%d].stabilize: %w", i, err)
+			}
+		}
 	}
-	if r.VideoCodec != nil && !AllowedVideoCodecs[*r.VideoCodec] {
-		return fmt.Errorf("video_codec not allowed: %s", *r.VideoCodec)
+	if r.VideoCodec != nil {
+		if err := checkAllowed("video_codec", *r.VideoCodec, AllowedVideoCodecs); err != nil {
+			return err
+		}
 	}
-	if r.AudioCodec != nil && !AllowedAudioCodecs[*r.AudioCodec] {
-		return fmt.Errorf("audio_codec not allowed: %s", *r.AudioCodec)
+	if err := validateHWCodec(r.HWCodec, r.VideoCodec); err != nil {
+		return err
 	}
-	if strings.ToLower(r.OutputFormat) == "mov" && r.AudioCodec != nil && *r.AudioCodec == "libopus" {
-		return fmt.Errorf("audio_codec libopus is not supported for mov; use aac")
+	if r.AudioCodec != nil {
+		if err := checkAllowed("audio_codec", *r.AudioCodec, AllowedAudioCodecs); err != nil {
+			return err
+		}
+	}
+	if err := validateCodecs(r.OutputFormat, r.VideoCodec, r.HWCodec, r.VideoProfile, r.AudioCodec, r.CRF); err != nil {
+		return err
 	}
 	if r.Preset != nil && !AllowedPresets[*r.Preset] {
 		return fmt.Errorf("preset not allowed: %s", *r.Preset)
 	}
-	if r.CRF != nil && (*r.CRF < 18 || *r.CRF > 35) {
-		return fmt.Errorf("crf must be between 18 and 35")
-	}
 	if r.Mode != "" && r.Mode != "fast" && r.Mode != "precise" {
 		return fmt.Errorf("mode must be 'fast' or 'precise'")
 	}
@@ -197,9 +1028,301 @@ func (r *TimelineExportRequest) Validate() error {
 	if r.Treble != nil && (*r.Treble < -20 || *r.Treble > 20) {
 		return fmt.Errorf("treble must be between -20 and 20 dB")
 	}
+	if r.Cleanup != nil {
+		if err := r.Cleanup.Validate(); err != nil {
+			return fmt.Errorf("cleanup: %w", err)
+		}
+	}
+	if r.Music != nil {
+		if r.RemoveAudio {
+			return fmt.Errorf("music cannot be combined with remove_audio")
+		}
+		if err := r.Music.Validate(); err != nil {
+			return fmt.Errorf("music: %w", err)
+		}
+	}
+	if err := r.ColorParams.validate(); err != nil {
+		return err
+	}
+	if err := r.LoudnessParams.validate(); err != nil {
+		return err
+	}
+	if r.QC != nil {
+		if err := r.QC.Validate(); err != nil {
+			return fmt.Errorf("qc: %w", err)
+		}
+	}
+	if err := r.MetadataParams.validate(r.OutputFormat); err != nil {
+		return err
+	}
+	if r.AutoChapters {
+		if len(r.Chapters) > 0 {
+			return fmt.Errorf("auto_chapters cannot be combined with chapters")
+		}
+		if !chapterFormats[strings.ToLower(r.OutputFormat)] {
+			return fmt.Errorf("auto_chapters needs output_format %s", formatList(chapterFormats))
+		}
+		if len(r.Clips) > MaxChapters {
+			return fmt.Errorf("auto_chapters allows at most %d clips", MaxChapters)
+		}
+	}
 	return nil
 }
 
+// StabilizeParams enables video stabilization; fields left out take the
+// defaults (shakiness 5, smoothing 10, zoom 0).
+type StabilizeParams struct {
+	Shakiness *int     `json:"shakiness"` // 1-10
+	Smoothing *int     `json:"smoothing"` // frames on each side
+	Zoom      *float64 `json:"zoom"`      // percent, beyond the optimal zoom
+}
+
+// Validate checks the stabilization settings.
+func (p *StabilizeParams) Validate() error {
+	if p.Shakiness != nil && (*p.Shakiness < 1 || *p.Shakiness > 10) {
+		return fmt.Errorf("shakiness must be between 1 and 10")
+	}
+	if p.Smoothing != nil && (*p.Smoothing < 1 || *p.Smoothing > 100) {
+		return fmt.Errorf("smoothing must be between 1 and 100 frames")
+	}
+	if p.Zoom != nil && (*p.Zoom < -20 || *p.Zoom > 50) {
+		return fmt.Errorf("zoom must be between -20 and 50 percent")
+	}
+	return nil
+}
+
+// MusicParams lays an uploaded music track under a timeline's audio. The
+// music ducks by sidechain compression whenever the timeline audio is above
+// Threshold.
+type MusicParams struct {
+	FileID    string   `json:"file_id"`
+	Gain      *float64 `json:"gain"`      // music level before ducking, dB
+	Threshold *float64 `json:"threshold"` // dB
+	Ratio     *float64 `json:"ratio"`
+	Attack    *float64 `json:"attack"`  // ms
+	Release   *float64 `json:"release"` // ms
+	Loop      bool     `json:"loop"`
+}
+
+// Validate checks the music settings against sidechaincompress's ranges.
+func (p *MusicParams) Validate() error {
+	if p.FileID == "" {
+		return fmt.Errorf("file_id is required")
+	}
+	if p.Gain != nil && (*p.Gain < -40 || *p.Gain > 6) {
+		return fmt.Errorf("gain must be between -40 and 6 dB")
+	}
+	if p.Threshold != nil && (*p.Threshold < -60 || *p.Threshold > 0) {
+		return fmt.Errorf("threshold must be between -60 and 0 dB")
+	}
+	if p.Ratio != nil && (*p.Ratio < 1 || *p.Ratio > 20) {
+		return fmt.Errorf("ratio must be between 1 and 20")
+	}
+	if p.Attack != nil && (*p.Attack < 0.01 || *p.Attack > 2000) {
+		return fmt.Errorf("attack must be between 0.01 and 2000 ms")
+	}
+	if p.Release != nil && (*p.Release < 0.01 || *p.Release > 9000) {
+		return fmt.Errorf("release must be between 0.01 and 9000 ms")
+	}
+	return nil
+}
+
+// JumpCutRequest drives POST /timeline/jump-cut. Without Export it only returns
+// the generated timeline; with Export it also starts the jump-cut export.
+type JumpCutRequest struct {
+	FileID       string   `json:"file_id"`
+	ThresholdDB  *float64 `json:"threshold_db"`
+	MinSilence   *float64 `json:"min_silence"`
+	Padding      *float64 `json:"padding"`
+	MinKeep      *float64 `json:"min_keep"`
+	Export       bool     `json:"export"`
+	OutputFormat string   `json:"output_format"`
+	Mode         string   `json:"mode"`
+}
+
+func (r *JumpCutRequest) Validate() error {
+	if r.FileID == "" {
+		return fmt.Errorf("file_id is required")
+	}
+	if r.ThresholdDB != nil && (*r.ThresholdDB < -90 || *r.ThresholdDB > -10) {
+		return fmt.Errorf("threshold_db must be between -90 and -10")
+	}
+	if r.MinSilence != nil && (*r.MinSilence < 0.1 || *r.MinSilence > 10) {
+		return fmt.Errorf("min_silence must be between 0.1 and 10 seconds")
+	}
+	if r.Padding != nil && (*r.Padding < 0 || *r.Padding > 2) {
+		return fmt.Errorf("padding must be between 0 and 2 seconds")
+	}
+	if r.MinKeep != nil && (*r.MinKeep < 0 || *r.MinKeep > 10) {
+		return fmt.Errorf("min_keep must be between 0 and 10 seconds")
+	}
+	if r.OutputFormat != "" {
+		if err := checkAllowed("output_format", strings.ToLower(r.OutputFormat), AllowedOutputFormats); err != nil {
+			return err
+		}
+		if err := validateCodecs(r.OutputFormat, nil, nil, nil, nil, nil); err != nil {
+			return err
+		}
+	}
+	if r.Export && r.OutputFormat == "" {
+		return fmt.Errorf("output_format is required when export is set")
+	}
+	if r.Mode != "" && r.Mode != "fast" && r.Mode != "precise" {
+		return fmt.Errorf("mode must be 'fast' or 'precise'")
+	}
+	return nil
+}
+
+// SceneSplitRequest drives POST /scenes/split: one output per detected scene,
+// delivered as a zip archive.
+type SceneSplitRequest struct {
+	FileID       string   `json:"file_id"`
+	Threshold    *float64 `json:"threshold"`
+	MinScene     *float64 `json:"min_scene"`
+	OutputFormat string   `json:"output_format"`
+	Mode         string   `json:"mode"`
+}
+
+func (r *SceneSplitRequest) Validate() error {
+	if r.FileID == "" {
+		return fmt.Errorf("file_id is required")
+	}
+	if err := ValidateSceneParams(r.Threshold, r.MinScene); err != nil {
+		return err
+	}
+	if r.OutputFormat == "" {
+		return fmt.Errorf("output_format is required")
+	}
+	if err := checkAllowed("output_format", strings.ToLower(r.OutputFormat), AllowedOutputFormats); err != nil {
+		return err
+	}
+	// Default codecs only, which rules out MXF.
+	if err := validateCodecs(r.OutputFormat, nil, nil, nil, nil, nil); err != nil {
+		return err
+	}
+	if r.Mode != "" && r.Mode != "fast" && r.Mode != "precise" {
+		return fmt.Errorf("mode must be 'fast' or 'precise'")
+	}
+	return nil
+}
+
+// ValidateSceneParams checks scene-detection parameters shared by
+// GET /files/:id/scenes and POST /scenes/split.
+func ValidateSceneParams(threshold, minScene *float64) error {
+	if threshold != nil && (*threshold < 0.05 || *threshold > 0.95) {
+		return fmt.Errorf("threshold must be between 0.05 and 0.95")
+	}
+	if minScene != nil && (*minScene < 0 || *minScene > 60) {
+		return fmt.Errorf("min_scene must be between 0 and 60 seconds")
+	}
+	return nil
+}
+
+// CleanupParams configures the voice cleanup chain: a preset and/or explicit
+// stages, which override the preset's. HighPass and Limiter of 0 and a level
+// of "off" disable a stage.
+type CleanupParams struct {
+	Preset       *string  `json:"preset"`        // see AllowedCleanupPresets
+	HighPass     *float64 `json:"highpass"`      // cutoff, Hz
+	Denoise      *string  `json:"denoise"`       // see CleanupLevels
+	DenoiseModel *string  `json:"denoise_model"` // "fft" (default) | "rnnoise"
+	Gate         *string  `json:"gate"`
+	DeEss        *string  `json:"deess"`
+	Compressor   *string  `json:"compressor"`
+	Limiter      *float64 `json:"limiter"` // ceiling, dBFS
+}
+
+// Validate checks the preset, levels and ranges, and that some stage is on.
+func (p *CleanupParams) Validate() error {
+	if p.Preset != nil && !AllowedCleanupPresets[*p.Preset] {
+		return fmt.Errorf("preset not allowed: %s", *p.Preset)
+	}
+	if p.HighPass != nil && *p.HighPass != 0 && (*p.HighPass < 20 || *p.HighPass > 300) {
+		return fmt.Errorf("highpass must be 0 (off) or between 20 and 300 Hz")
+	}
+	for _, stage := range []struct {
+		name  string
+		value *string
+	}{{"denoise", p.Denoise}, {"gate", p.Gate}, {"deess", p.DeEss}, {"compressor", p.Compressor}} {
+		if stage.value != nil && !CleanupLevels[stage.name][*stage.value] {
+			return fmt.Errorf("%s not allowed: %s", stage.name, *stage.value)
+		}
+	}
+	if p.DenoiseModel != nil && !AllowedDenoiseModels[*p.DenoiseModel] {
+		return fmt.Errorf("denoise_model not allowed: %s", *p.DenoiseModel)
+	}
+	if p.Limiter != nil && (*p.Limiter < -12 || *p.Limiter > 0) {
+		return fmt.Errorf("limiter must be between -12 and 0 dBFS (0 is off)")
+	}
+	if p.Preset == nil && p.HighPass == nil && p.Denoise == nil && p.Gate == nil &&
+		p.DeEss == nil && p.Compressor == nil && p.Limiter == nil {
+		return fmt.Errorf("a preset or at least one stage is required")
+	}
+	return nil
+}
+
+// QCParams tunes the QC detectors and thresholds. On exports, FailOnIssues
+// turns a failed report into a failed job.
+type QCParams struct {
+	BlackMinDuration    *float64 `json:"black_min_duration"`
+	BlackPixelThreshold *float64 `json:"black_pixel_threshold"`
+	FreezeNoiseDB       *float64 `json:"freeze_noise_db"`
+	FreezeMinDuration   *float64 `json:"freeze_min_duration"`
+	SilenceDB           *float64 `json:"silence_db"`
+	SilenceMinDuration  *float64 `json:"silence_min_duration"`
+	MaxBlackSecs        *float64 `json:"max_black_secs"`
+	MaxFreezeSecs       *float64 `json:"max_freeze_secs"`
+	MaxSilenceSecs      *float64 `json:"max_silence_secs"`
+	MaxClippedSamples   *int64   `json:"max_clipped_samples"`
+	FailOnIssues        bool     `json:"fail_on_issues"`
+}
+
+// Validate checks detector settings and thresholds.
+func (p *QCParams) Validate() error {
+	if p.BlackMinDuration != nil && (*p.BlackMinDuration < 0.04 || *p.BlackMinDuration > 60) {
+		return fmt.Errorf("black_min_duration must be between 0.04 and 60 seconds")
+	}
+	if p.BlackPixelThreshold != nil && (*p.BlackPixelThreshold <= 0 || *p.BlackPixelThreshold > 1) {
+		return fmt.Errorf("black_pixel_threshold must be between 0 and 1")
+	}
+	if p.FreezeNoiseDB != nil && (*p.FreezeNoiseDB < -90 || *p.FreezeNoiseDB > -10) {
+		return fmt.Errorf("freeze_noise_db must be between -90 and -10")
+	}
+	if p.FreezeMinDuration != nil && (*p.FreezeMinDuration < 0.1 || *p.FreezeMinDuration > 60) {
+		return fmt.Errorf("freeze_min_duration must be between 0.1 and 60 seconds")
+	}
+	if p.SilenceDB != nil && (*p.SilenceDB < -90 || *p.SilenceDB > -10) {
+		return fmt.Errorf("silence_db must be between -90 and -10")
+	}
+	if p.SilenceMinDuration != nil && (*p.SilenceMinDuration < 0.1 || *p.SilenceMinDuration > 60) {
+		return fmt.Errorf("silence_min_duration must be between 0.1 and 60 seconds")
+	}
+	for name, v := range map[string]*float64{"max_black_secs": p.MaxBlackSecs, "max_freeze_secs": p.MaxFreezeSecs, "max_silence_secs": p.MaxSilenceSecs} {
+		if v != nil && *v < 0 {
+			return fmt.Errorf("%s cannot be negative", name)
+		}
+	}
+	if p.MaxClippedSamples != nil && *p.MaxClippedSamples < 0 {
+		return fmt.Errorf("max_clipped_samples cannot be negative")
+	}
+	return nil
+}
+
+// QCRequest drives POST /qc. Exactly one of FileID (an upload) or JobID (a
+// completed job's output) selects the media to check.
+type QCRequest struct {
+	FileID string `json:"file_id"`
+	JobID  string `json:"job_id"`
+	QCParams
+}
+
+func (r *QCRequest) Validate() error {
+	if (r.FileID == "") == (r.JobID == "") {
+		return fmt.Errorf("exactly one of file_id or job_id is required")
+	}
+	return r.QCParams.Validate()
+}
+
 type MergeRequest struct {
 	FileIDs      []string `json:"file_ids"`
 	OutputFormat string   `json:"output_format"`
@@ -212,13 +1335,181 @@ func (r *MergeRequest) Validate() error {
 	if r.OutputFormat == "" {
 		return fmt.Errorf("output_format is required")
 	}
-	if !AllowedOutputFormats[strings.ToLower(r.OutputFormat)] {
-		return fmt.Errorf("output_format not allowed: %s", r.OutputFormat)
+	if err := checkAllowed("output_format", strings.ToLower(r.OutputFormat), AllowedOutputFormats); err != nil {
+		return err
 	}
 	switch strings.ToLower(r.OutputFormat) {
 	case "mp3", "aac", "wav", "flac", "ogg", "m4a":
 		return fmt.Errorf("merge does not support audio-only output format: %s", r.OutputFormat)
 	}
+	return validateCodecs(r.OutputFormat, nil, nil, nil, nil, nil)
+}
+
+// AllowedComposeLayouts mirrors ffmpeg.ComposeLayouts.
+var AllowedComposeLayouts = map[string]bool{"pip": true, "side_by_side": true, "top_bottom": true, "grid": true}
+
+// AllowedPiPCorners mirrors ffmpeg.PiPCorners.
+var AllowedPiPCorners = map[string]bool{"top_left": true, "top_right": true, "bottom_left": true, "bottom_right": true}
+
+// composeSourceCounts is how many sources each layout takes.
+var composeSourceCounts = map[string][2]int{"pip": {2, 2}, "side_by_side": {2, 2}, "top_bottom": {2, 2}, "grid": {2, 4}}
+
+// colorRe matches an ffmpeg colour name or #RRGGBB.
+var colorRe = regexp.MustCompile(`^([a-zA-Z]+|#[0-9a-fA-F]{6})$`)
+
+// ComposeSource is one input of a composition.
+type ComposeSource struct {
+	FileID string  `json:"file_id"`
+	Start  float64 `json:"start"` // in-point in the file
+	// Duration is how much of the file plays; nil plays to its end.
+	Duration *float64 `json:"duration"`
+	// Offset is when the source enters the composition.
+	Offset float64  `json:"offset"`
+	Volume *float64 `json:"volume"` // linear gain of its audio
+}
+
+// PiPParams places the inset of a "pip" composition.
+type PiPParams struct {
+	Corner      *string  `json:"corner"`
+	Scale       *float64 `json:"scale"`  // inset width / canvas width
+	Margin      *int     `json:"margin"` // pixels from the canvas edges
+	Border      *int     `json:"border"` // pixels
+	BorderColor *string  `json:"border_color"`
+}
+
+func (p *PiPParams) Validate() error {
+	if p.Corner != nil {
+		if err := checkAllowed("corner", *p.Corner, AllowedPiPCorners); err != nil {
+			return err
+		}
+	}
+	if p.Scale != nil && (*p.Scale < 0.1 || *p.Scale > 0.5) {
+		return fmt.Errorf("scale must be between 0.1 and 0.5")
+	}
+	if p.Margin != nil && (*p.Margin < 0 || *p.Margin > 500) {
+		return fmt.Errorf("margin must be between 0 and 500 pixels")
+	}
+	if p.Border != nil && (*p.Border < 0 || *p.Border > 50) {
+		return fmt.Errorf("border must be between 0 and 50 pixels")
+	}
+	if p.BorderColor != nil && !colorRe.MatchString(*p.BorderColor) {
+		return fmt.Errorf("border_color must be a colour name or #RRGGBB")
+	}
+	return nil
+}
+
+// ComposeRequest drives POST /compose: several files laid out on one canvas,
+// each entering at its offset.
+type ComposeRequest struct {
+	Sources []ComposeSource `json:"sources"`
+	Layout  string          `json:"layout"`
+	PiP     *PiPParams      `json:"pip"`
+	Width   *int            `json:"width"`
+	Height  *int            `json:"height"`
+	FPS     *FrameRate      `json:"fps"`
+	// Duration is the output length; nil ends with the first source for
+	// pip and with the last source to finish otherwise.
+	Duration *float64 `json:"duration"`
+	// Audio lists the sources whose audio is used, by index; several are
+	// mixed. nil uses the first source with audio, [] writes no audio.
+	Audio        []int   `json:"audio"`
+	OutputFormat string  `json:"output_format"`
+	VideoCodec   *string `json:"video_codec"`
+	AudioCodec   *string `json:"audio_codec"`
+	CRF          *int    `json:"crf"`
+	Preset       *string `json:"preset"`
+	FastStart    bool    `json:"fast_start"`
+}
+
+func (r *ComposeRequest) Validate() error {
+	if err := checkAllowed("layout", r.Layout, AllowedComposeLayouts); err != nil {
+		return err
+	}
+	n := composeSourceCounts[r.Layout]
+	if len(r.Sources) < n[0] || len(r.Sources) > n[1] {
+		if n[0] == n[1] {
+			return fmt.Errorf("%s needs %d sources", r.Layout, n[0])
+		}
+		return fmt.Errorf("%s needs %d to %d sources", r.Layout, n[0], n[1])
+	}
+	for i, src := range r.Sources {
+		if src.FileID == "" {
+			return fmt.Errorf("sources[%d].file_id is required", i)
+		}
+		if src.Start < 0 {
+			return fmt.Errorf("sources[%d].start cannot be negative", i)
+		}
+		if src.Duration != nil && *src.Duration <= 0 {
+			return fmt.Errorf("sources[%d].duration must be positive", i)
+		}
+		if src.Offset < 0 {
+			return fmt.Errorf("sources[%d].offset cannot be negative", i)
+		}
+		if src.Volume != nil && (*src.Volume < 0 || *src.Volume > 4) {
+			return fmt.Errorf("sources[%d].volume must be between 0 and 4", i)
+		}
+	}
+	if r.PiP != nil {
+		if r.Layout != "pip" {
+			return fmt.Errorf("pip applies to the pip layout only")
+		}
+		if err := r.PiP.Validate(); err != nil {
+			return fmt.Errorf("pip: %w", err)
+		}
+	}
+	if r.Width != nil && (*r.Width < 160 || *r.Width > 3840 || *r.Width%2 != 0) {
+		return fmt.Errorf("width must be an even number between 160 and 3840")
+	}
+	if r.Height != nil && (*r.Height < 90 || *r.Height > 2160 || *r.Height%2 != 0) {
+		return fmt.Errorf("height must be an even number between 90 and 2160")
+	}
+	if err := validateFrameRate(r.FPS, nil); err != nil {
+		return err
+	}
+	if r.Duration != nil && (*r.Duration <= 0 || *r.Duration > 6*3600) {
+		return fmt.Errorf("duration must be between 0 and 6 hours")
+	}
+	seen := map[int]bool{}
+	for _, i := range r.Audio {
+		if i < 0 || i >= len(r.Sources) {
+			return fmt.Errorf("audio source %d does not exist", i)
+		}
+		if seen[i] {
+			return fmt.Errorf("audio source %d is listed twice", i)
+		}
+		seen[i] = true
+	}
+	if r.OutputFormat == "" {
+		return fmt.Errorf("output_format is required")
+	}
+	if err := checkAllowed("output_format", strings.ToLower(r.OutputFormat), AllowedOutputFormats); err != nil {
+		return err
+	}
+	if audioOnlyFormats[strings.ToLower(r.OutputFormat)] {
+		return fmt.Errorf("compose needs a video output format, not %s", r.OutputFormat)
+	}
+	if r.VideoCodec != nil {
+		if *r.VideoCodec == "copy" {
+			return fmt.Errorf("compose re-encodes; video_codec cannot be copy")
+		}
+		if err := checkAllowed("video_codec", *r.VideoCodec, AllowedVideoCodecs); err != nil {
+			return err
+		}
+	}
+	if r.AudioCodec != nil {
+		if *r.AudioCodec == "copy" {
+			return fmt.Errorf("compose re-encodes; audio_codec cannot be copy")
+		}
+		if err := checkAllowed("audio_codec", *r.AudioCodec, AllowedAudioCodecs); err != nil {
+			return err
+		}
+	}
+	if err := validateCodecs(r.OutputFormat, r.VideoCodec, nil, nil, r.AudioCodec, r.CRF); err != nil {
+		return err
+	}
+	if r.Preset != nil && !AllowedPresets[*r.Preset] {
+		return fmt.Errorf("preset not allowed: %s", *r.Preset)
+	}
 	return nil
 }
 
@@ -21,8 +21,8 @@ func main() {
 	defer cancel()
 
 	fmt.Println("Starting merge test...")
-	err := ffmpeg.Merge(ctx, opts, func(current, total, outTimeMs float64) {
-		fmt.Printf("Progress: %.2f ms\n", outTimeMs)
+	err := ffmpeg.Merge(ctx, opts, func(ev ffmpeg.ProgressEvent) {
+		fmt.Printf("Progress: %.0f%% (%.2fx, %.1f fps)\n", ev.Progress*100, ev.Speed, ev.FPS)
 	})
 
 	if err != nil {